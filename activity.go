@@ -23,7 +23,15 @@ func (sc *SnowthClient) RebuildActivity(node *SnowthNode,
 func (sc *SnowthClient) RebuildActivityContext(ctx context.Context,
 	node *SnowthNode,
 	rebuildRequest []RebuildActivityRequest,
+	opts ...CallOption,
 ) (*IRONdbPutResponse, error) {
+	ctx, n, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if n != nil {
+		node = n
+	}
+
 	data, err := encodeJSON(rebuildRequest)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,559 @@
+package gosnowth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/gosnowth/fb/noit"
+)
+
+// Default BatchConfig settings.
+const (
+	DefaultBatchSize        = 1000
+	DefaultBatchAge         = time.Second
+	DefaultBatchMaxPending  = 100000
+	DefaultBatchConcurrency = 4
+)
+
+// Errors returned by BatchWriter values.
+var (
+	// ErrBatchWriterClosed is returned when points are added to a
+	// BatchWriter after it has been closed.
+	ErrBatchWriterClosed = errors.New("batch writer is closed")
+
+	// ErrBatchWriterFull is returned when points are dropped because a
+	// BatchWriter configured with DropWhenFull is full.
+	ErrBatchWriterFull = errors.New("batch writer is full")
+
+	// ErrBatchPartialWrite is returned for batches which a node reports as
+	// partially written, with some records misdirected or failed. These
+	// batches are not retried, since the records which were written cannot be
+	// identified.
+	ErrBatchPartialWrite = errors.New("batch partially written")
+)
+
+// BatchConfig values contain the configuration of a BatchWriter. Zero values
+// are replaced with the defaults.
+type BatchConfig struct {
+	// Size is the number of points which causes a batch to be flushed.
+	Size int `json:"size,omitempty"`
+
+	// Age is the maximum time a point waits in a batch before it is flushed.
+	Age time.Duration `json:"age,omitempty"`
+
+	// MaxPending is the maximum number of points held in memory, including
+	// the points being flushed. When it is reached, adding points blocks until
+	// space is available, or fails if DropWhenFull is set.
+	MaxPending int `json:"max_pending,omitempty"`
+
+	// DropWhenFull causes points added when MaxPending is reached to be
+	// dropped, in place of blocking the caller.
+	DropWhenFull bool `json:"drop_when_full,omitempty"`
+
+	// Concurrency is the maximum number of batches flushed at the same time.
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// Retries is the number of times a failed batch is retried, using the
+	// backoff and retry rules of the client retry policy. If zero, the client
+	// Retries() setting is used. A negative value disables retries.
+	Retries int64 `json:"retries,omitempty"`
+
+	// DeadLetter, if set, is called with the points of each batch which could
+	// not be written, and the error which caused the failure. Only one of the
+	// metrics or histograms lists contains points. When a node reports some
+	// records of a batch as misdirected or failed, the whole batch is passed,
+	// and may contain records which were written.
+	DeadLetter func(metrics []*noit.MetricT, histograms []HistogramData,
+		err error) `json:"-"`
+}
+
+// BatchStats values contain the statistics of a BatchWriter.
+type BatchStats struct {
+	// Queued is the number of points waiting to be flushed.
+	Queued int
+
+	// InFlight is the number of points being flushed.
+	InFlight int
+
+	// Flushed is the number of points written.
+	Flushed uint64
+
+	// Dropped is the number of points which were not written, either because
+	// the writer was full, or because their batch failed.
+	Dropped uint64
+
+	// Batches is the number of batches flushed.
+	Batches uint64
+}
+
+// pointBatch values contain the points waiting to be sent to the owners of
+// their metrics.
+type pointBatch struct {
+	key        string
+	owners     []string
+	metrics    []*noit.MetricT
+	histograms []HistogramData
+	created    time.Time
+}
+
+// size returns the number of points in the batch.
+func (b *pointBatch) size() int {
+	return len(b.metrics) + len(b.histograms)
+}
+
+// BatchWriter values accumulate raw metric records and histogram data in
+// memory, and write them in batches grouped by the nodes owning their
+// metrics. Batches are flushed when they reach the configured size or age,
+// or when Flush() is called. Metric records are written using
+// WriteRawMetricListContext and histograms using WriteHistogramContext.
+type BatchWriter struct {
+	sc  *SnowthClient
+	cfg BatchConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu       sync.Mutex
+	batches  map[string]*pointBatch
+	queued   int
+	inFlight int
+	freed    chan struct{}
+	closed   bool
+	flushed  uint64
+	dropped  uint64
+	sent     uint64
+}
+
+// NewBatchWriter creates a new batch writer which writes points using a
+// client. If cfg is nil, the default configuration is used. Close() must be
+// called to flush the remaining points and stop the writer, before the client
+// is closed.
+func NewBatchWriter(sc *SnowthClient, cfg *BatchConfig) *BatchWriter {
+	c := BatchConfig{}
+	if cfg != nil {
+		c = *cfg
+	}
+
+	if c.Size <= 0 {
+		c.Size = DefaultBatchSize
+	}
+
+	if c.Age <= 0 {
+		c.Age = DefaultBatchAge
+	}
+
+	if c.MaxPending <= 0 {
+		c.MaxPending = DefaultBatchMaxPending
+	}
+
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultBatchConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	bw := &BatchWriter{
+		sc:      sc,
+		cfg:     c,
+		ctx:     ctx,
+		cancel:  cancel,
+		sem:     make(chan struct{}, c.Concurrency),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		batches: map[string]*pointBatch{},
+		freed:   make(chan struct{}),
+	}
+
+	go bw.run()
+
+	return bw
+}
+
+// run flushes batches which reach the configured age, until the writer is
+// closed.
+func (bw *BatchWriter) run() {
+	defer close(bw.done)
+
+	interval := bw.cfg.Age / 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-bw.stop:
+			return
+		case now := <-t.C:
+			bw.mu.Lock()
+
+			for _, b := range bw.batches {
+				if now.Sub(b.created) >= bw.cfg.Age {
+					bw.dispatch(b, nil)
+				}
+			}
+
+			bw.mu.Unlock()
+		}
+	}
+}
+
+// AddMetrics adds raw metric records to the writer. If the writer is full,
+// it blocks until space is available or the context is done, unless the
+// writer is configured to drop points when full.
+func (bw *BatchWriter) AddMetrics(ctx context.Context,
+	metrics ...*noit.MetricT,
+) error {
+	owners := make([][]string, len(metrics))
+
+	for i, m := range metrics {
+		owners[i] = bw.owners(m.CheckUuid, rawMetricName(m))
+	}
+
+	if err := bw.reserve(ctx, len(metrics)); err != nil {
+		return err
+	}
+
+	defer bw.mu.Unlock()
+
+	for i, m := range metrics {
+		b := bw.batch("m", owners[i])
+		b.metrics = append(b.metrics, m)
+		bw.full(b)
+	}
+
+	return nil
+}
+
+// AddHistograms adds histogram data to the writer. If the writer is full, it
+// blocks until space is available or the context is done, unless the writer
+// is configured to drop points when full.
+func (bw *BatchWriter) AddHistograms(ctx context.Context,
+	histograms ...HistogramData,
+) error {
+	owners := make([][]string, len(histograms))
+	for i, h := range histograms {
+		owners[i] = bw.owners(h.ID, h.Metric)
+	}
+
+	if err := bw.reserve(ctx, len(histograms)); err != nil {
+		return err
+	}
+
+	defer bw.mu.Unlock()
+
+	for i, h := range histograms {
+		b := bw.batch("h", owners[i])
+		b.histograms = append(b.histograms, h)
+		bw.full(b)
+	}
+
+	return nil
+}
+
+// reserve waits for space for n points, and counts them as queued. On
+// success, the writer is left locked, so that the points are added before it
+// can be closed.
+func (bw *BatchWriter) reserve(ctx context.Context, n int) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		bw.mu.Lock()
+
+		if bw.closed {
+			bw.mu.Unlock()
+
+			return ErrBatchWriterClosed
+		}
+
+		pending := bw.queued + bw.inFlight
+		if pending == 0 || pending+n <= bw.cfg.MaxPending {
+			bw.queued += n
+
+			return nil
+		}
+
+		if bw.cfg.DropWhenFull {
+			bw.dropped += uint64(n)
+			bw.mu.Unlock()
+
+			return ErrBatchWriterFull
+		}
+
+		freed := bw.freed
+		bw.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-freed:
+		}
+	}
+}
+
+// owners returns the node identifiers owning a metric in the cached
+// topology. No topology is retrieved, so that adding points never waits for
+// a request.
+func (bw *BatchWriter) owners(uuid, metric string) []string {
+	e := bw.sc.topology.load()
+	if e == nil {
+		return nil
+	}
+
+	ids, err := e.topo.FindMetricNodeIDs(uuid, metric)
+	if err != nil {
+		return nil
+	}
+
+	return ids
+}
+
+// batch returns the open batch for a list of owners, creating it if needed.
+// The writer must be locked by the caller.
+func (bw *BatchWriter) batch(kind string, owners []string) *pointBatch {
+	key := kind + ":" + strings.Join(owners, ",")
+
+	b := bw.batches[key]
+	if b == nil {
+		b = &pointBatch{key: key, owners: owners, created: time.Now()}
+		bw.batches[key] = b
+	}
+
+	return b
+}
+
+// full dispatches a batch if it has reached the configured size. The writer
+// must be locked by the caller.
+func (bw *BatchWriter) full(b *pointBatch) {
+	if b.size() >= bw.cfg.Size {
+		bw.dispatch(b, nil)
+	}
+}
+
+// dispatch removes a batch from the open batches and sends it in the
+// background. If done is not nil, it is called with the result. The writer
+// must be locked by the caller.
+func (bw *BatchWriter) dispatch(b *pointBatch, done func(error)) {
+	delete(bw.batches, b.key)
+
+	n := b.size()
+	bw.queued -= n
+	bw.inFlight += n
+
+	go func() {
+		bw.sem <- struct{}{}
+		err := bw.send(b)
+		<-bw.sem
+
+		if done != nil {
+			done(err)
+		}
+
+		bw.mu.Lock()
+
+		bw.inFlight -= n
+		bw.sent++
+
+		close(bw.freed)
+		bw.freed = make(chan struct{})
+
+		bw.mu.Unlock()
+	}()
+}
+
+// send writes a batch, retrying it according to the client retry policy, and
+// records the result.
+func (bw *BatchWriter) send(b *pointBatch) error {
+	retries := bw.cfg.Retries
+	if retries == 0 {
+		retries = bw.sc.Retries()
+	}
+
+	policy := bw.sc.RetryPolicy()
+
+	var (
+		written int
+		err     error
+	)
+
+	for attempt := int64(0); ; attempt++ {
+		if written, err = bw.write(b); err == nil {
+			policy.RecordSuccess()
+
+			break
+		}
+
+		var ie *IRONdbError
+
+		status := 0
+		if errors.As(err, &ie) {
+			status = ie.StatusCode
+		}
+
+		if attempt >= retries || errors.Is(err, ErrBatchPartialWrite) ||
+			!policy.Retryable(status, err) ||
+			!policy.AllowRetry() {
+			break
+		}
+
+		bw.sc.LogDebugf("retrying batch of %d points: %s", b.size(),
+			err.Error())
+
+		if waitContext(bw.ctx, policy.Backoff(attempt+1)) != nil {
+			break
+		}
+	}
+
+	bw.mu.Lock()
+	bw.flushed += uint64(written)
+	bw.dropped += uint64(b.size() - written)
+	bw.mu.Unlock()
+
+	if err != nil {
+		bw.sc.LogWarnf("unable to write batch of %d points: %s", b.size(),
+			err.Error())
+
+		if bw.cfg.DeadLetter != nil {
+			bw.cfg.DeadLetter(b.metrics, b.histograms, err)
+		}
+	}
+
+	return err
+}
+
+// write sends a batch to an active owner of its metrics, returning the
+// number of points written.
+func (bw *BatchWriter) write(b *pointBatch) (int, error) {
+	var opts []CallOption
+
+	if node := bw.sc.GetActiveNode(b.owners); node != nil {
+		opts = append(opts, node)
+	}
+
+	if len(b.histograms) > 0 {
+		if err := bw.sc.WriteHistogramContext(bw.ctx, b.histograms,
+			opts...); err != nil {
+			return 0, err
+		}
+
+		return len(b.histograms), nil
+	}
+
+	res, err := bw.sc.WriteRawMetricListContext(bw.ctx,
+		&noit.MetricListT{Metrics: b.metrics}, nil, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := res.Misdirected + res.Errors
+	if failed == 0 {
+		return len(b.metrics), nil
+	}
+
+	if failed > uint64(len(b.metrics)) {
+		failed = uint64(len(b.metrics))
+	}
+
+	return len(b.metrics) - int(failed), fmt.Errorf("%w: failed to write %d "+
+		"records: %d misdirected, %d errors", ErrBatchPartialWrite, failed,
+		res.Misdirected, res.Errors)
+}
+
+// Flush sends all open batches, and waits until all batches being sent have
+// completed, or the context is done. It returns the first error of the
+// batches it sent, which are also passed to the dead letter function.
+func (bw *BatchWriter) Flush(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var (
+		errMu    sync.Mutex
+		firstErr error
+	)
+
+	done := func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	bw.mu.Lock()
+
+	for _, b := range bw.batches {
+		bw.dispatch(b, done)
+	}
+
+	for bw.inFlight > 0 {
+		freed := bw.freed
+		bw.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-freed:
+		}
+
+		bw.mu.Lock()
+	}
+
+	bw.mu.Unlock()
+
+	errMu.Lock()
+	defer errMu.Unlock()
+
+	return firstErr
+}
+
+// Close flushes the writer and stops it. Points added after Close() is called
+// are rejected with ErrBatchWriterClosed. If the context is done before the
+// flush completes, the batches being sent are cancelled.
+func (bw *BatchWriter) Close(ctx context.Context) error {
+	bw.mu.Lock()
+
+	if bw.closed {
+		bw.mu.Unlock()
+
+		return nil
+	}
+
+	bw.closed = true
+	bw.mu.Unlock()
+
+	close(bw.stop)
+	<-bw.done
+
+	err := bw.Flush(ctx)
+
+	bw.cancel()
+
+	return err
+}
+
+// Stats returns the statistics of the writer.
+func (bw *BatchWriter) Stats() BatchStats {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	return BatchStats{
+		Queued:   bw.queued,
+		InFlight: bw.inFlight,
+		Flushed:  bw.flushed,
+		Dropped:  bw.dropped,
+		Batches:  bw.sent,
+	}
+}
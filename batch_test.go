@@ -0,0 +1,373 @@
+package gosnowth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/gosnowth/fb/noit"
+)
+
+// batchTestMetric returns a raw metric record for batch writer tests.
+func batchTestMetric(name string) *noit.MetricT {
+	return &noit.MetricT{
+		Timestamp: 60000,
+		CheckUuid: shardTestUUID,
+		AccountId: 1,
+		Value: &noit.MetricValueT{
+			Name:      name,
+			Timestamp: 60000,
+			Value: &noit.MetricValueUnionT{
+				Type:  noit.MetricValueUnionDoubleValue,
+				Value: &noit.DoubleValueT{Value: 1},
+			},
+		},
+	}
+}
+
+func TestBatchWriter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		records, histograms, requests int64
+		fail                          int32
+	)
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		switch r.RequestURI {
+		case "/stats.json":
+			_, _ = w.Write([]byte(statsTestData))
+		case "/raw":
+			atomic.AddInt64(&requests, 1)
+
+			if atomic.LoadInt32(&fail) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			b, _ := io.ReadAll(r.Body)
+			n := len(noit.GetRootAsMetricList(b, 0).UnPack().Metrics)
+
+			if atomic.LoadInt32(&fail) == 2 {
+				_, _ = fmt.Fprintf(w, `{"records":%d,"updated":0,`+
+					`"misdirected":%d,"errors":0}`, n, n)
+
+				return
+			}
+
+			atomic.AddInt64(&records, int64(n))
+
+			_, _ = fmt.Fprintf(w, `{"records":%d,"updated":%d,`+
+				`"misdirected":0,"errors":0}`, n, n)
+		case "/histogram/write":
+			atomic.AddInt64(&histograms, 1)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer ms.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	sc.SetRetryPolicy(&DefaultRetryPolicy{BaseDelay: time.Millisecond})
+
+	var (
+		dlMu   sync.Mutex
+		dlErr  error
+		dlRecs int
+	)
+
+	bw := NewBatchWriter(sc, &BatchConfig{
+		Size:    5,
+		Age:     time.Hour,
+		Retries: 2,
+		DeadLetter: func(metrics []*noit.MetricT, h []HistogramData,
+			err error,
+		) {
+			dlMu.Lock()
+			defer dlMu.Unlock()
+
+			dlErr, dlRecs = err, len(metrics)
+		},
+	})
+
+	ctx := context.Background()
+
+	// Batches are flushed when they reach the configured size.
+	for i := 0; i < 5; i++ {
+		if err := bw.AddMetrics(ctx,
+			batchTestMetric(fmt.Sprintf("m%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt64(&records) != 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := atomic.LoadInt64(&records); n != 5 {
+		t.Errorf("Expected records: 5, got: %v", n)
+	}
+
+	// Smaller batches are written by Flush().
+	if err := bw.AddMetrics(ctx, batchTestMetric("a"),
+		batchTestMetric("b")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bw.AddHistograms(ctx, HistogramData{
+		ID:     shardTestUUID,
+		Metric: "h",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := bw.Stats(); s.Queued != 3 {
+		t.Errorf("Expected queued: 3, got: %v", s.Queued)
+	}
+
+	if err := bw.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := atomic.LoadInt64(&records); n != 7 {
+		t.Errorf("Expected records: 7, got: %v", n)
+	}
+
+	if n := atomic.LoadInt64(&histograms); n != 1 {
+		t.Errorf("Expected histogram writes: 1, got: %v", n)
+	}
+
+	s := bw.Stats()
+	if s.Flushed != 8 || s.Queued != 0 || s.InFlight != 0 || s.Dropped != 0 {
+		t.Errorf("Expected flushed: 8, got: %+v", s)
+	}
+
+	// Failed batches are retried, then passed to the dead letter function.
+	atomic.StoreInt32(&fail, 1)
+	atomic.StoreInt64(&requests, 0)
+
+	if err := bw.AddMetrics(ctx, batchTestMetric("c")); err != nil {
+		t.Fatal(err)
+	}
+
+	var ie *IRONdbError
+	if err := bw.Flush(ctx); !errors.As(err, &ie) {
+		t.Errorf("Expected IRONdbError, got: %v", err)
+	}
+
+	if n := atomic.LoadInt64(&requests); n != 3 {
+		t.Errorf("Expected requests: 3, got: %v", n)
+	}
+
+	dlMu.Lock()
+	if dlErr == nil || dlRecs != 1 {
+		t.Errorf("Expected dead letter records: 1, got: %v, %v", dlRecs,
+			dlErr)
+	}
+	dlMu.Unlock()
+
+	if s := bw.Stats(); s.Dropped != 1 {
+		t.Errorf("Expected dropped: 1, got: %v", s.Dropped)
+	}
+
+	// Misdirected batches are not retried.
+	atomic.StoreInt32(&fail, 2)
+	atomic.StoreInt64(&requests, 0)
+
+	if err := bw.AddMetrics(ctx, batchTestMetric("c")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bw.Flush(ctx); !errors.Is(err, ErrBatchPartialWrite) {
+		t.Errorf("Expected error: %v, got: %v", ErrBatchPartialWrite, err)
+	}
+
+	if n := atomic.LoadInt64(&requests); n != 1 {
+		t.Errorf("Expected requests: 1, got: %v", n)
+	}
+
+	if s := bw.Stats(); s.Dropped != 2 {
+		t.Errorf("Expected dropped: 2, got: %v", s.Dropped)
+	}
+
+	if err := bw.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bw.AddMetrics(ctx, batchTestMetric("d")); !errors.Is(err,
+		ErrBatchWriterClosed) {
+		t.Errorf("Expected error: %v, got: %v", ErrBatchWriterClosed, err)
+	}
+}
+
+func TestBatchWriterOwners(t *testing.T) {
+	t.Parallel()
+
+	topo, err := TopologyLoadXML(shardTopologyXMLTestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{topo.Nodes[0].ID, topo.Nodes[1].ID}
+
+	var mu sync.Mutex
+
+	received := map[string][]string{}
+
+	ms0 := rerouteTestServer(t, topo, ids[0], &mu, received)
+	defer ms0.Close()
+
+	ms1 := rerouteTestServer(t, topo, ids[1], &mu, received)
+	defer ms1.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms0.URL, ms1.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+
+	for len(sc.ListActiveNodes()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := sc.Topology(); err != nil {
+		t.Fatal(err)
+	}
+
+	ml, owned1, _ := rerouteTestMetrics(t, topo, ids[1])
+
+	// The batch writer groups records by the owners of their metrics in the
+	// cached topology, using their canonical names, so that none are
+	// misdirected.
+	bw := NewBatchWriter(sc, &BatchConfig{Age: time.Hour})
+
+	if err := bw.AddMetrics(context.Background(), ml.Metrics...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bw.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := bw.Stats(); s.Flushed != 20 || s.Dropped != 0 {
+		t.Errorf("Expected flushed: 20, got: %+v", s)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if strings.Join(received[ids[1]], ",") != strings.Join(owned1, ",") {
+		t.Errorf("Expected batched metrics: %v, got: %v", owned1,
+			received[ids[1]])
+	}
+}
+
+func TestBatchWriterLimits(t *testing.T) {
+	t.Parallel()
+
+	var records int64
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		switch r.RequestURI {
+		case "/stats.json":
+			_, _ = w.Write([]byte(statsTestData))
+		case "/raw":
+			b, _ := io.ReadAll(r.Body)
+			n := len(noit.GetRootAsMetricList(b, 0).UnPack().Metrics)
+			atomic.AddInt64(&records, int64(n))
+
+			_, _ = fmt.Fprintf(w, `{"records":%d,"updated":%d,`+
+				`"misdirected":0,"errors":0}`, n, n)
+		}
+	}))
+
+	defer ms.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	ctx := context.Background()
+
+	// Points are dropped when the writer is full.
+	bw := NewBatchWriter(sc, &BatchConfig{
+		Size:         100,
+		Age:          time.Hour,
+		MaxPending:   2,
+		DropWhenFull: true,
+	})
+
+	if err := bw.AddMetrics(ctx, batchTestMetric("a"),
+		batchTestMetric("b")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bw.AddMetrics(ctx, batchTestMetric("c")); !errors.Is(err,
+		ErrBatchWriterFull) {
+		t.Errorf("Expected error: %v, got: %v", ErrBatchWriterFull, err)
+	}
+
+	if s := bw.Stats(); s.Dropped != 1 || s.Queued != 2 {
+		t.Errorf("Expected dropped: 1, queued: 2, got: %+v", s)
+	}
+
+	// Without DropWhenFull, adding points blocks until space is available.
+	bw.cfg.DropWhenFull = false
+
+	tCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	if err := bw.AddMetrics(tCtx, batchTestMetric("c")); !errors.Is(err,
+		context.DeadlineExceeded) {
+		t.Errorf("Expected error: %v, got: %v", context.DeadlineExceeded,
+			err)
+	}
+
+	if err := bw.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Batches are flushed when they reach the configured age.
+	bw = NewBatchWriter(sc, &BatchConfig{Age: 10 * time.Millisecond})
+
+	defer func() {
+		_ = bw.Close(ctx)
+	}()
+
+	if err := bw.AddMetrics(ctx, batchTestMetric("d")); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt64(&records) != 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := atomic.LoadInt64(&records); n != 3 {
+		t.Errorf("Expected records: 3, got: %v", n)
+	}
+}
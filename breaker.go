@@ -0,0 +1,343 @@
+package gosnowth
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState values represent the state of a node circuit breaker.
+type BreakerState int32
+
+// BreakerState values used by node circuit breakers.
+const (
+	// BreakerClosed indicates requests are being sent to the node normally.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen indicates the node has failed and requests are not being
+	// sent to it.
+	BreakerOpen
+
+	// BreakerHalfOpen indicates the node is being tested with trial requests
+	// to determine whether it has recovered.
+	BreakerHalfOpen
+)
+
+// String returns the name of the breaker state.
+func (bs BreakerState) String() string {
+	switch bs {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+
+	return "unknown"
+}
+
+// BreakerConfig values represent the configuration of the circuit breakers
+// used by SnowthClient values to remove failing nodes from rotation.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed requests which
+	// will open the circuit breaker for a node.
+	FailureThreshold int64 `json:"failure_threshold,omitempty"`
+
+	// OpenTimeout is the duration a circuit breaker remains open before
+	// allowing trial requests to the node.
+	OpenTimeout time.Duration `json:"open_timeout,omitempty"`
+
+	// HalfOpenSuccesses is the number of consecutive successful trial
+	// requests needed to close the circuit breaker for a node. It is also
+	// the maximum number of trial requests sent to the node at once.
+	HalfOpenSuccesses int64 `json:"half_open_successes,omitempty"`
+}
+
+// NewBreakerConfig creates and initializes a new circuit breaker configuration
+// value using default values.
+func NewBreakerConfig() *BreakerConfig {
+	return &BreakerConfig{
+		FailureThreshold:  5,
+		OpenTimeout:       5 * time.Second,
+		HalfOpenSuccesses: 2,
+	}
+}
+
+// circuitBreaker values track request outcomes for a single node.
+type circuitBreaker struct {
+	sync.Mutex
+	cfg       BreakerConfig
+	state     BreakerState
+	failures  int64
+	successes int64
+	trials    int64
+	openedAt  time.Time
+}
+
+// newCircuitBreaker creates a new closed circuit breaker.
+func newCircuitBreaker(cfg *BreakerConfig) *circuitBreaker {
+	cb := &circuitBreaker{cfg: *cfg}
+
+	if cb.cfg.FailureThreshold <= 0 {
+		cb.cfg.FailureThreshold = 1
+	}
+
+	if cb.cfg.HalfOpenSuccesses <= 0 {
+		cb.cfg.HalfOpenSuccesses = 1
+	}
+
+	return cb
+}
+
+// current returns the state of the breaker, moving an open breaker into the
+// half-open state once its timeout has passed. The caller must hold the
+// breaker lock.
+func (cb *circuitBreaker) current() BreakerState {
+	if cb.state == BreakerOpen && time.Since(cb.openedAt) >= cb.cfg.OpenTimeout {
+		cb.state = BreakerHalfOpen
+		cb.successes = 0
+	}
+
+	return cb.state
+}
+
+// State returns the current state of the breaker.
+func (cb *circuitBreaker) State() BreakerState {
+	cb.Lock()
+	defer cb.Unlock()
+
+	return cb.current()
+}
+
+// allow reports whether a request may be sent to the node. A half-open
+// breaker only allows requests while fewer than the maximum number of trial
+// requests are in progress.
+func (cb *circuitBreaker) allow() bool {
+	cb.Lock()
+	defer cb.Unlock()
+
+	switch cb.current() {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		return cb.trials < cb.cfg.HalfOpenSuccesses
+	case BreakerClosed:
+	}
+
+	return true
+}
+
+// acquire reports whether a request may be sent to the node, and whether it
+// is a trial request of a half-open breaker. Trial requests must be released
+// when they complete.
+func (cb *circuitBreaker) acquire() (bool, bool) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	switch cb.current() {
+	case BreakerOpen:
+		return false, false
+	case BreakerHalfOpen:
+		if cb.trials >= cb.cfg.HalfOpenSuccesses {
+			return false, false
+		}
+
+		cb.trials++
+
+		return true, true
+	case BreakerClosed:
+	}
+
+	return true, false
+}
+
+// release records the completion of a trial request.
+func (cb *circuitBreaker) release() {
+	cb.Lock()
+	defer cb.Unlock()
+
+	if cb.trials > 0 {
+		cb.trials--
+	}
+}
+
+// success records a successful request. It returns true if this caused the
+// breaker to close.
+func (cb *circuitBreaker) success() bool {
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.failures = 0
+
+	if cb.current() != BreakerHalfOpen {
+		return false
+	}
+
+	cb.successes++
+
+	if cb.successes < cb.cfg.HalfOpenSuccesses {
+		return false
+	}
+
+	cb.state = BreakerClosed
+	cb.successes = 0
+
+	return true
+}
+
+// failure records a failed request. It returns true if this caused the
+// breaker to open.
+func (cb *circuitBreaker) failure() bool {
+	cb.Lock()
+	defer cb.Unlock()
+
+	switch cb.current() {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		cb.trip()
+
+		return true
+	case BreakerClosed:
+	}
+
+	cb.failures++
+
+	if cb.failures < cb.cfg.FailureThreshold {
+		return false
+	}
+
+	cb.trip()
+
+	return true
+}
+
+// reset closes the breaker and clears its counters.
+func (cb *circuitBreaker) reset() {
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.state = BreakerClosed
+	cb.failures = 0
+	cb.successes = 0
+}
+
+// trip opens the breaker. The caller must hold the breaker lock.
+func (cb *circuitBreaker) trip() {
+	cb.state = BreakerOpen
+	cb.openedAt = time.Now()
+	cb.failures = 0
+	cb.successes = 0
+}
+
+// nodeBreaker returns the circuit breaker for a node, creating it if needed.
+// It returns nil if circuit breakers are not enabled for the client.
+func (sc *SnowthClient) nodeBreaker(node *SnowthNode) *circuitBreaker {
+	if node == nil || node.GetURL() == nil {
+		return nil
+	}
+
+	key := node.GetURL().String()
+
+	sc.RLock()
+	cfg := sc.breakerConfig
+	cb := sc.breakers[key]
+	sc.RUnlock()
+
+	if cfg == nil || cb != nil {
+		return cb
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	if cb = sc.breakers[key]; cb == nil {
+		cb = newCircuitBreaker(cfg)
+
+		if sc.breakers == nil {
+			sc.breakers = map[string]*circuitBreaker{}
+		}
+
+		sc.breakers[key] = cb
+	}
+
+	return cb
+}
+
+// NodeBreakerState returns the state of the circuit breaker for a node. If
+// circuit breakers are not enabled for the client, BreakerClosed is always
+// returned.
+func (sc *SnowthClient) NodeBreakerState(node *SnowthNode) BreakerState {
+	cb := sc.nodeBreaker(node)
+	if cb == nil {
+		return BreakerClosed
+	}
+
+	return cb.State()
+}
+
+// breakerAllow reports whether the circuit breaker for a node allows requests
+// to be sent to it.
+func (sc *SnowthClient) breakerAllow(node *SnowthNode) bool {
+	cb := sc.nodeBreaker(node)
+	if cb == nil {
+		return true
+	}
+
+	return cb.allow()
+}
+
+// breakerAcquire reports whether the circuit breaker for a node allows a
+// request to be sent to it. The returned function must be called when the
+// request completes, so that a half-open breaker can allow further trials.
+func (sc *SnowthClient) breakerAcquire(node *SnowthNode) (bool, func()) {
+	cb := sc.nodeBreaker(node)
+	if cb == nil {
+		return true, func() {}
+	}
+
+	ok, trial := cb.acquire()
+	if !trial {
+		return ok, func() {}
+	}
+
+	return true, cb.release
+}
+
+// recordNodeOutcome updates the circuit breaker for a node with the result of
+// a request. A node whose breaker is open remains active, but requests are not
+// sent to it until the breaker allows trial requests.
+func (sc *SnowthClient) recordNodeOutcome(node *SnowthNode, ok bool) {
+	cb := sc.nodeBreaker(node)
+	if cb == nil {
+		return
+	}
+
+	if ok {
+		if cb.success() {
+			sc.LogInfof("circuit breaker closed for snowth node: %s",
+				node.GetURL().Host)
+		}
+
+		return
+	}
+
+	if cb.failure() {
+		sc.LogWarnf("circuit breaker opened for snowth node: %s",
+			node.GetURL().Host)
+	}
+}
+
+// breakerAllowed returns the nodes whose circuit breakers allow requests to
+// be sent to them.
+func (sc *SnowthClient) breakerAllowed(nodes []*SnowthNode) []*SnowthNode {
+	allowed := make([]*SnowthNode, 0, len(nodes))
+
+	for _, node := range nodes {
+		if sc.breakerAllow(node) {
+			allowed = append(allowed, node)
+		}
+	}
+
+	return allowed
+}
@@ -0,0 +1,312 @@
+package gosnowth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	cb := newCircuitBreaker(&BreakerConfig{
+		FailureThreshold:  2,
+		OpenTimeout:       20 * time.Millisecond,
+		HalfOpenSuccesses: 2,
+	})
+
+	if cb.State() != BreakerClosed {
+		t.Fatalf("Expected state: closed, got: %v", cb.State())
+	}
+
+	if cb.failure() {
+		t.Error("Expected breaker to remain closed after one failure")
+	}
+
+	cb.success()
+
+	if cb.failure() {
+		t.Error("Expected success to reset failure count")
+	}
+
+	if !cb.failure() {
+		t.Error("Expected breaker to open")
+	}
+
+	if cb.State() != BreakerOpen || cb.allow() {
+		t.Fatalf("Expected state: open, got: %v", cb.State())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if cb.State() != BreakerHalfOpen || !cb.allow() {
+		t.Fatalf("Expected state: half-open, got: %v", cb.State())
+	}
+
+	if !cb.failure() {
+		t.Error("Expected half-open failure to reopen breaker")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if cb.success() {
+		t.Error("Expected breaker to remain half-open after one success")
+	}
+
+	if !cb.success() {
+		t.Error("Expected breaker to close")
+	}
+
+	if cb.State() != BreakerClosed {
+		t.Errorf("Expected state: closed, got: %v", cb.State())
+	}
+
+	cb.failure()
+	cb.failure()
+	cb.reset()
+
+	if cb.State() != BreakerClosed {
+		t.Errorf("Expected state: closed, got: %v", cb.State())
+	}
+
+	if BreakerHalfOpen.String() != "half-open" {
+		t.Errorf("Expected state name: half-open, got: %v", BreakerHalfOpen)
+	}
+}
+
+func TestNodeBreakerRecovery(t *testing.T) {
+	t.Parallel()
+
+	var failing int32 = 1
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		if atomic.LoadInt32(&failing) != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(stateTestData))
+	}))
+
+	defer bad.Close()
+
+	var goodCount int32
+
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		atomic.AddInt32(&goodCount, 1)
+
+		_, _ = w.Write([]byte(stateTestData))
+	}))
+
+	defer good.Close()
+
+	cfg := NewConfig(bad.URL, good.URL)
+	cfg.Breaker = &BreakerConfig{
+		FailureThreshold:  2,
+		OpenTimeout:       50 * time.Millisecond,
+		HalfOpenSuccesses: 1,
+	}
+
+	sc, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	u, err := url.Parse(bad.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	node := &SnowthNode{url: u}
+
+	sc.SetConnectRetries(0)
+
+	for i := 0; i < 2; i++ {
+		if _, err := sc.GetNodeState(node); err == nil {
+			t.Fatal("Expected error from failing node")
+		}
+	}
+
+	if s := sc.NodeBreakerState(node); s != BreakerOpen {
+		t.Fatalf("Expected breaker state: open, got: %v", s)
+	}
+
+	// The node remains active, but requests are sent to the other node
+	// while its breaker is open.
+	if len(sc.ListActiveNodes()) != 2 {
+		t.Errorf("Expected active nodes: 2, got: %v",
+			len(sc.ListActiveNodes()))
+	}
+
+	for i := 0; i < 5; i++ {
+		if n := sc.GetActiveNode(); n.GetURL().String() != good.URL {
+			t.Fatalf("Expected active node: %v, got: %v", good.URL,
+				n.GetURL())
+		}
+	}
+
+	if _, err := sc.GetNodeState(node); err != nil {
+		t.Fatal(err)
+	}
+
+	if atomic.LoadInt32(&goodCount) != 1 {
+		t.Errorf("Expected request to be sent to the healthy node")
+	}
+
+	// A failed trial request opens the breaker again.
+	time.Sleep(60 * time.Millisecond)
+
+	if s := sc.NodeBreakerState(node); s != BreakerHalfOpen {
+		t.Fatalf("Expected breaker state: half-open, got: %v", s)
+	}
+
+	if _, err := sc.GetNodeState(node); err == nil {
+		t.Fatal("Expected error from failing node")
+	}
+
+	if s := sc.NodeBreakerState(node); s != BreakerOpen {
+		t.Fatalf("Expected breaker state: open, got: %v", s)
+	}
+
+	atomic.StoreInt32(&failing, 0)
+	time.Sleep(60 * time.Millisecond)
+
+	if s := sc.NodeBreakerState(node); s != BreakerHalfOpen {
+		t.Fatalf("Expected breaker state: half-open, got: %v", s)
+	}
+
+	if _, err := sc.GetNodeState(node); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := sc.NodeBreakerState(node); s != BreakerClosed {
+		t.Fatalf("Expected breaker state: closed, got: %v", s)
+	}
+
+	if atomic.LoadInt32(&goodCount) != 1 {
+		t.Errorf("Expected trial request to be sent to the recovered node")
+	}
+}
+
+func TestNodeBreakerTrials(t *testing.T) {
+	t.Parallel()
+
+	var count int32
+
+	release := make(chan struct{})
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		atomic.AddInt32(&count, 1)
+		<-release
+
+		_, _ = w.Write([]byte(stateTestData))
+	}))
+
+	defer ms.Close()
+
+	cfg := NewConfig(ms.URL)
+	cfg.Breaker = &BreakerConfig{
+		FailureThreshold:  1,
+		OpenTimeout:       20 * time.Millisecond,
+		HalfOpenSuccesses: 2,
+	}
+
+	sc, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	node := sc.ListActiveNodes()[0]
+
+	sc.nodeBreaker(node).failure()
+	time.Sleep(30 * time.Millisecond)
+
+	if s := sc.NodeBreakerState(node); s != BreakerHalfOpen {
+		t.Fatalf("Expected breaker state: half-open, got: %v", s)
+	}
+
+	// Only the allowed number of trial requests are sent to a half-open
+	// node at once.
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 10)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := sc.GetNodeState(node)
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < 8; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrNoActiveNode) {
+				t.Errorf("Expected error: %v, got: %v", ErrNoActiveNode, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected requests to be rejected")
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+
+	for atomic.LoadInt32(&count) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	if n := atomic.LoadInt32(&count); n != 2 {
+		t.Errorf("Expected trial requests: 2, got: %v", n)
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected trial request success, got: %v", err)
+		}
+	}
+
+	if s := sc.NodeBreakerState(node); s != BreakerClosed {
+		t.Errorf("Expected breaker state: closed, got: %v", s)
+	}
+}
@@ -38,6 +38,9 @@ type CAQLError struct {
 	Status    string                 `json:"status"`
 	Arguments map[string]interface{} `json:"arguments"`
 	Success   bool                   `json:"success"`
+
+	// err is the error response from IRONdb which contained the CAQL error.
+	err error
 }
 
 // Message returns the user_error.message of a CAQL error, if it exists.
@@ -77,32 +80,49 @@ func (ce *CAQLError) Error() string {
 	return ce.String()
 }
 
+// Is reports whether the error matches a target error. A CAQL error
+// containing a user error message matches ErrInvalidQuery.
+func (ce *CAQLError) Is(target error) bool {
+	return target == ErrInvalidQuery && ce.Message() != ""
+}
+
+// Unwrap returns the IRONdbError response which contained the CAQL error.
+func (ce *CAQLError) Unwrap() error {
+	return ce.err
+}
+
 // GetCAQLQuery retrieves data values for metrics matching a CAQL format.
 func (sc *SnowthClient) GetCAQLQuery(q *CAQLQuery,
 	nodes ...*SnowthNode,
 ) (*DF4Response, error) {
-	return sc.GetCAQLQueryContext(context.Background(), q, nodes...)
+	return sc.GetCAQLQueryContext(context.Background(), q, nodeOptions(nodes)...)
 }
 
 // GetCAQLQueryContext is the context aware version of GetCAQLQuery.
 func (sc *SnowthClient) GetCAQLQueryContext(ctx context.Context, q *CAQLQuery,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*DF4Response, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	u := "/extension/lua/public/caql_v1"
 
 	if q == nil {
-		return nil, fmt.Errorf("invalid CAQL query: null")
+		return nil, invalidQueryf("invalid CAQL query: null")
+	}
+
+	if id, ok := accountFromContext(ctx); ok && q.AccountID == 0 {
+		qc := *q
+		qc.AccountID = id
+		q = &qc
 	}
 
 	q.Format = "DF4"
@@ -129,7 +149,9 @@ func (sc *SnowthClient) GetCAQLQueryContext(ctx context.Context, q *CAQLQuery,
 	if err != nil {
 		if body != nil {
 			cErr := &CAQLError{}
-			if err := decodeJSON(body, &cErr); err == nil {
+			if dErr := decodeJSON(body, &cErr); dErr == nil {
+				cErr.err = err
+
 				return nil, cErr
 			}
 		}
@@ -2,6 +2,7 @@ package gosnowth
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -155,6 +156,15 @@ func TestGetCAQLQuery(t *testing.T) {
 		t.Fatalf("Expected error type: CAQLError, got: %T: %v", err, err)
 	}
 
+	if !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected CAQL user error to match ErrInvalidQuery")
+	}
+
+	var ie *IRONdbError
+	if !errors.As(err, &ie) || ie.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected IRONdb error status: 502, got: %v", ie)
+	}
+
 	exp := "Function not found: histograms"
 	if vErr.Message() != exp {
 		t.Errorf("Expected user error: %v, got: %v", exp,
@@ -12,7 +12,6 @@ import (
 	"net/textproto"
 	"net/url"
 	"os"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -33,7 +32,11 @@ type Logger interface {
 // the cluster, and the topology is the current topology that the node falls
 // within.  A topology is a set of nodes that distribute data amongst each other.
 type SnowthNode struct {
-	url             *url.URL
+	url *url.URL
+
+	// mu guards the identifier, topology and version of the node, which are
+	// updated by health checks and responses while requests read them.
+	mu              sync.RWMutex
 	identifier      string
 	currentTopology string
 	semVer          string
@@ -49,15 +52,49 @@ func (sn *SnowthNode) GetURL() *url.URL {
 // SemVer returns a string containing the semantic version of IRONdb the node
 // is currently running.
 func (sn *SnowthNode) SemVer() string {
+	sn.mu.RLock()
+	defer sn.mu.RUnlock()
+
 	return sn.semVer
 }
 
 // GetCurrentTopology return the hash string representation of the
 // node's current topology.
 func (sn *SnowthNode) GetCurrentTopology() string {
+	sn.mu.RLock()
+	defer sn.mu.RUnlock()
+
 	return sn.currentTopology
 }
 
+// id returns the identifier of the node within the cluster.
+func (sn *SnowthNode) id() string {
+	sn.mu.RLock()
+	defer sn.mu.RUnlock()
+
+	return sn.identifier
+}
+
+// setIdentity sets the identifier and version of the node, returning the
+// previous version.
+func (sn *SnowthNode) setIdentity(id, semVer string) string {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+
+	old := sn.semVer
+	sn.identifier, sn.semVer = id, semVer
+
+	return old
+}
+
+// setCurrentTopology sets the hash of the current topology of the node.
+func (sn *SnowthNode) setCurrentTopology(hash string) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+
+	sn.currentTopology = hash
+}
+
 // httpClient values are used to define the behavior needed from HTTP client
 // values.
 type httpClient interface {
@@ -66,14 +103,71 @@ type httpClient interface {
 
 // Config values represent configuration information SnowthClient values.
 type Config struct {
-	DialTimeout    time.Duration `json:"dial_timeout,omitempty"`
-	Timeout        time.Duration `json:"timeout,omitempty"`
-	WatchInterval  time.Duration `json:"watch_interval,omitempty"`
-	Retries        int64         `json:"retries,omitempty"`
-	ConnectRetries int64         `json:"connect_retries,omitempty"`
-	Servers        []string      `json:"servers,omitempty"`
-	DenyHosts      []string      `json:"deny_hosts,omitempty"`
-	CtxKeyTraceID  interface{}   `json:"-"`
+	DialTimeout    time.Duration  `json:"dial_timeout,omitempty"`
+	Timeout        time.Duration  `json:"timeout,omitempty"`
+	WatchInterval  time.Duration  `json:"watch_interval,omitempty"`
+	Retries        int64          `json:"retries,omitempty"`
+	ConnectRetries int64          `json:"connect_retries,omitempty"`
+	Servers        []string       `json:"servers,omitempty"`
+	DenyHosts      []string       `json:"deny_hosts,omitempty"`
+	TLS            *TLSConfig     `json:"tls,omitempty"`
+	Breaker        *BreakerConfig `json:"breaker,omitempty"`
+	Hedge          *HedgeConfig   `json:"hedge,omitempty"`
+	Health         *HealthPolicy  `json:"health,omitempty"`
+	CtxKeyTraceID  interface{}    `json:"-"`
+
+	// Transport, if set, is used to send all requests to IRONdb nodes in
+	// place of the default transport. When a transport is provided, the
+	// DialTimeout, DialContext and TLS settings are not applied to it, and
+	// connections are not forced closed after each request.
+	Transport http.RoundTripper `json:"-"`
+
+	// RetryPolicy, if set, determines how failed requests are retried. If
+	// nil, a DefaultRetryPolicy is used.
+	RetryPolicy RetryPolicy `json:"-"`
+
+	// TraceContextFunc, if set, is used to retrieve the W3C trace context
+	// propagated to IRONdb requests from the contexts passed to client
+	// methods, when one has not been added by ContextWithTraceContext().
+	TraceContextFunc func(ctx context.Context) (TraceContext, bool) `json:"-"`
+
+	// RequestObserver, if set, receives events describing every request
+	// sent to IRONdb nodes.
+	RequestObserver RequestObserver `json:"-"`
+
+	// NodeSelector, if set, determines which node receives each request
+	// when several nodes are able to serve it. If nil, a node is chosen at
+	// random.
+	NodeSelector NodeSelector `json:"-"`
+
+	// Snapshot, if set, contains the nodes and topology used to start the
+	// client. SnapshotFile, if set, names a file containing a snapshot
+	// written by SaveSnapshotFile(), which is used when Snapshot is nil.
+	// When a snapshot is used, NewClient() returns without waiting for any
+	// of the servers to respond, and the servers are contacted in the
+	// background to update the snapshot state.
+	Snapshot     *TopologySnapshot `json:"-"`
+	SnapshotFile string            `json:"snapshot_file,omitempty"`
+
+	// Resolver, if set, is used to resolve the DNS names found in Servers.
+	// Servers entries may use the srv+http or srv+https scheme to find nodes
+	// using DNS SRV records, and host names resolving to several addresses
+	// are used as one node per address. These names are resolved again on
+	// each watch interval, and new nodes are added to the client. If nil,
+	// the default resolver is used.
+	Resolver Resolver `json:"-"`
+
+	// DialContext, if set, is used by the default transport to create
+	// network connections to IRONdb nodes. This can be used to connect
+	// through Unix sockets or proxy tunnels.
+	DialContext func(ctx context.Context,
+		network, addr string) (net.Conn, error) `json:"-"`
+
+	// Spool, if set, configures a durable on-disk spool for flatbuffer raw
+	// and NNTBS writes. Writes which cannot be sent because no node is
+	// available are added to the spool, and are replayed in order in the
+	// background when nodes are activated, and at the spool replay interval.
+	Spool *SpoolConfig `json:"spool,omitempty"`
 }
 
 // NewConfig creates and initializes a new SnowthClient configuration value
@@ -86,6 +180,8 @@ func NewConfig(servers ...string) *Config {
 		WatchInterval:  30 * time.Second,
 		Retries:        0,
 		ConnectRetries: -1,
+		Breaker:        NewBreakerConfig(),
+		Health:         NewHealthPolicy(),
 	}
 }
 
@@ -94,6 +190,11 @@ type SnowthClient struct {
 	sync.RWMutex
 	c httpClient
 
+	// closeConns determines whether connections are closed after each
+	// request. This is only done when using the default transport, so that
+	// caller-supplied transports can manage their own connection pools.
+	closeConns bool
+
 	// timeout is the maximum duration that a snowth request is allowed to run.
 	timeout time.Duration
 
@@ -105,6 +206,10 @@ type SnowthClient struct {
 	// fail to snowth nodes due to connection problems.
 	connRetries int64
 
+	// retryPolicy determines the backoff, budget and idempotency rules used
+	// when retrying failed requests.
+	retryPolicy RetryPolicy
+
 	// in order to keep track of healthy nodes within the cluster,
 	// we have two lists of SnowthNode types, active and inactive.
 	activeNodes   []*SnowthNode
@@ -129,7 +234,7 @@ type SnowthClient struct {
 	// dumpRequests and traceRequests are settings from the environment
 	// GOSNOWTH_DUMP_REQUESTS and GOSNOWTH_TRACE_REQUESTS respectively.
 	// Set to a path `/data/fetch` or `*` for all paths.
-	// Dump: full request w/payload is emitted to stdout
+	// Dump: full request w/payload is emitted to the debug log
 	// Trace: httptrace of request
 	dumpRequests  string
 	traceRequests string
@@ -138,9 +243,75 @@ type SnowthClient struct {
 	// contexts passed to gosnowth functions.
 	ctxKeyTraceID interface{}
 
-	// current topology
-	currentTopology         string
-	currentTopologyCompiled *Topology
+	// scheme is the URL scheme used for nodes discovered through the
+	// cluster topology. If forceScheme is set, it is also used for nodes
+	// found using the servers the client was created with.
+	scheme      string
+	forceScheme bool
+
+	// servers contains the server addresses the client was created with,
+	// which are resolved using resolver on each watch interval. The
+	// discovered map contains the node URLs found by the last resolution.
+	servers    []string
+	resolver   Resolver
+	discovered map[string]bool
+
+	// breakerConfig is the configuration used for node circuit breakers. If
+	// nil, circuit breakers are disabled. The breakers map contains the
+	// circuit breaker for each node, keyed by node URL.
+	breakerConfig *BreakerConfig
+	breakers      map[string]*circuitBreaker
+
+	// nodeSelector chooses which node receives each request. The nodeStats
+	// map contains request measurements for each node, keyed by node URL.
+	nodeSelector NodeSelector
+	nodeStats    map[string]*nodeStats
+
+	// healthPolicy determines how WatchAndUpdate() checks node health. If
+	// nil, the default policy is used. The health map contains the
+	// consecutive health check results for each node, keyed by node URL.
+	healthPolicy *HealthPolicy
+	health       map[string]*nodeHealth
+
+	// hedger determines the delay used for hedged reads. If nil, hedged
+	// reads are disabled.
+	hedger *hedger
+
+	// observer receives events for requests sent to IRONdb nodes, if set.
+	observer RequestObserver
+
+	// traceContextFunc retrieves the trace context propagated to IRONdb
+	// requests from request contexts, if set.
+	traceContextFunc func(ctx context.Context) (TraceContext, bool)
+
+	// closed is set, and closeCh is closed, when the client is closed. The
+	// background wait group tracks goroutines started by the client, and the
+	// requests wait group tracks requests in progress.
+	closed     bool
+	closeCh    chan struct{}
+	background sync.WaitGroup
+	requests   sync.WaitGroup
+
+	// subscriptions contains the subscribers receiving cluster change
+	// events, and droppedEvents counts the events dropped because a
+	// subscriber queue was full.
+	subscriptions []*subscription
+	droppedEvents uint64
+
+	// currentTopology is the hash of the current cluster topology, and the
+	// topology cache contains the last good compiled topology, which may
+	// differ from it while a new topology is being retrieved. nextTopology
+	// is the hash of the next topology last reported by a node, if any, and
+	// snapshotTopology is set while the current topology was restored from
+	// a snapshot and has not been reported by a node.
+	currentTopology  string
+	nextTopology     string
+	snapshotTopology bool
+	topology         topologyCache
+
+	// spool contains the writes which could not be sent to any node, if
+	// the client has a spool.
+	spool *Spool
 }
 
 // NewClient creates and performs initial setup of a new SnowthClient.
@@ -155,15 +326,35 @@ func NewClient(ctx context.Context, cfg *Config,
 		cfg.DialTimeout = time.Millisecond * 500
 	}
 
+	tlsConfig, err := cfg.TLS.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load TLS configuration: %w", err)
+	}
+
+	scheme, err := cfg.TLS.nodeScheme(cfg.Servers)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load TLS configuration: %w", err)
+	}
+
 	client := &http.Client{
-		Timeout: cfg.Timeout,
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
+		Timeout:   cfg.Timeout,
+		Transport: cfg.Transport,
+	}
+
+	if client.Transport == nil {
+		dial := cfg.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{
 				Timeout:   cfg.DialTimeout,
 				KeepAlive: cfg.Timeout,
 				DualStack: true,
-			}).DialContext,
+			}).DialContext
+		}
+
+		client.Transport = &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dial,
+			TLSClientConfig:       tlsConfig,
 			ForceAttemptHTTP2:     true,
 			DisableKeepAlives:     true,
 			MaxConnsPerHost:       0,
@@ -172,65 +363,98 @@ func NewClient(ctx context.Context, cfg *Config,
 			IdleConnTimeout:       cfg.Timeout,
 			TLSHandshakeTimeout:   cfg.DialTimeout,
 			ExpectContinueTimeout: cfg.DialTimeout,
-		},
+		}
 	}
 
 	sc := &SnowthClient{
-		c:             client,
-		activeNodes:   []*SnowthNode{},
-		inactiveNodes: []*SnowthNode{},
-		watchInterval: cfg.WatchInterval,
-		timeout:       cfg.Timeout,
-		retries:       cfg.Retries,
-		connRetries:   cfg.ConnectRetries,
-		dumpRequests:  os.Getenv("GOSNOWTH_DUMP_REQUESTS"),
-		traceRequests: os.Getenv("GOSNOWTH_TRACE_REQUESTS"),
-		denyHosts:     cfg.DenyHosts,
-		ctxKeyTraceID: cfg.CtxKeyTraceID,
+		c:                client,
+		closeConns:       cfg.Transport == nil,
+		activeNodes:      []*SnowthNode{},
+		inactiveNodes:    []*SnowthNode{},
+		watchInterval:    cfg.WatchInterval,
+		timeout:          cfg.Timeout,
+		retries:          cfg.Retries,
+		connRetries:      cfg.ConnectRetries,
+		retryPolicy:      cfg.RetryPolicy,
+		dumpRequests:     os.Getenv("GOSNOWTH_DUMP_REQUESTS"),
+		traceRequests:    os.Getenv("GOSNOWTH_TRACE_REQUESTS"),
+		denyHosts:        cfg.DenyHosts,
+		ctxKeyTraceID:    cfg.CtxKeyTraceID,
+		scheme:           scheme,
+		forceScheme:      cfg.TLS.forcesScheme(),
+		servers:          cfg.Servers,
+		resolver:         cfg.Resolver,
+		breakerConfig:    cfg.Breaker,
+		nodeSelector:     cfg.NodeSelector,
+		hedger:           newHedger(cfg.Hedge),
+		healthPolicy:     cfg.Health,
+		observer:         cfg.RequestObserver,
+		traceContextFunc: cfg.TraceContextFunc,
 	}
 
 	if len(logs) > 0 {
 		sc.log = logs[0]
 	}
 
+	snap, err := configSnapshot(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if snap != nil {
+		if err := sc.restoreSnapshot(snap); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Spool != nil {
+		if sc.spool, err = OpenSpool(cfg.Spool); err != nil {
+			return nil, err
+		}
+	}
+
 	// Initial setup of the client may continue in the background after
-	// the client has been returned to the caller.
-	cCtx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	// the client has been returned to the caller, until it is closed.
+	bCtx, bCancel := sc.backgroundContext(context.Background())
+	cCtx, cancel := context.WithTimeout(bCtx, cfg.Timeout)
 
 	doneCh := make(chan struct{}, 1)
 
-	defer close(doneCh)
+	sc.background.Add(1)
 
 	go func(ctx context.Context, sc *SnowthClient, cfg *Config) {
-		remaining := len(cfg.Servers)
+		defer sc.endBackground()
+		defer bCancel()
+		defer cancel()
 
-		nodeCh := make(chan *SnowthNode, remaining)
+		urls, errs := sc.resolveServers(ctx, cfg.Servers)
+		for _, err := range errs {
+			sc.LogErrorf(err.Error())
+		}
 
-		defer close(nodeCh)
+		sc.Lock()
 
-		errCh := make(chan error, remaining)
+		sc.discovered = make(map[string]bool, len(urls))
 
-		defer close(errCh)
+		for _, u := range urls {
+			sc.discovered[u.String()] = true
+		}
 
-		for _, addr := range cfg.Servers {
-			go func(addr string) {
-				url, err := url.Parse(addr)
-				if err != nil {
-					errCh <- fmt.Errorf("unable to parse server url %s: %w",
-						addr, err)
+		sc.Unlock()
 
-					return
-				}
+		remaining := len(urls)
 
-				for _, dh := range cfg.DenyHosts {
-					if url.Host == dh {
-						errCh <- fmt.Errorf("deny host found in servers: %s",
-							url.Host)
+		nodeCh := make(chan *SnowthNode, remaining)
 
-						return
-					}
-				}
+		defer close(nodeCh)
+
+		errCh := make(chan error, remaining)
+
+		defer close(errCh)
 
+		for _, u := range urls {
+			go func(url *url.URL) {
+				addr := url.String()
 				node := &SnowthNode{url: url}
 
 				stats, err := sc.GetStatsNodeContext(ctx, node)
@@ -244,9 +468,8 @@ func NewClient(ctx context.Context, cfg *Config,
 					return
 				}
 
-				node.identifier = stats.Identity()
-				node.currentTopology = stats.CurrentTopology()
-				node.semVer = stats.SemVer()
+				node.setIdentity(stats.Identity(), stats.SemVer())
+				node.setCurrentTopology(stats.CurrentTopology())
 
 				select {
 				case <-ctx.Done():
@@ -255,7 +478,7 @@ func NewClient(ctx context.Context, cfg *Config,
 				default:
 					nodeCh <- node
 				}
-			}(addr)
+			}(u)
 		}
 
 		first := true
@@ -269,11 +492,7 @@ func NewClient(ctx context.Context, cfg *Config,
 				sc.ActivateNodes(node)
 
 				if first {
-					sc.Lock()
-
-					sc.currentTopology = node.currentTopology
-
-					sc.Unlock()
+					sc.updateTopology(node, node.GetCurrentTopology())
 
 					doneCh <- struct{}{}
 
@@ -285,13 +504,25 @@ func NewClient(ctx context.Context, cfg *Config,
 		}
 	}(cCtx, sc, cfg)
 
+	if snap != nil {
+		sc.watchSpool()
+
+		return sc, nil
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			cancel()
 
+			if sc.spool != nil {
+				_ = sc.spool.Close()
+			}
+
 			return nil, fmt.Errorf("no snowth nodes could be activated")
 		case <-doneCh:
+			sc.watchSpool()
+
 			return sc, nil
 		}
 	}
@@ -299,8 +530,8 @@ func NewClient(ctx context.Context, cfg *Config,
 
 // Retries gets the number of retries a SnowthClient will attempt when
 // errors other than connection errors occur with a snowth node.
-// Retires will repeat the request with exponential backoff until this number
-// of retries is reached.
+// Retries will repeat the request with the backoff determined by the client
+// retry policy until this number of retries is reached.
 func (sc *SnowthClient) Retries() int64 {
 	sc.RLock()
 	defer sc.RUnlock()
@@ -310,8 +541,8 @@ func (sc *SnowthClient) Retries() int64 {
 
 // SetRetries sets the number of retries a SnowthClient will attempt when
 // errors other than connection errors occur with a snowth node.
-// Retires will repeat the request with exponential backoff until this number
-// of retries is reached.
+// Retries will repeat the request with the backoff determined by the client
+// retry policy until this number of retries is reached.
 func (sc *SnowthClient) SetRetries(num int64) {
 	sc.Lock()
 	defer sc.Unlock()
@@ -343,6 +574,37 @@ func (sc *SnowthClient) SetConnectRetries(num int64) {
 	sc.connRetries = num
 }
 
+// RetryPolicy gets the policy a SnowthClient uses to determine the backoff,
+// retry budget and idempotency rules applied when retrying failed requests.
+func (sc *SnowthClient) RetryPolicy() RetryPolicy {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.retryPolicy == nil {
+		sc.retryPolicy = NewDefaultRetryPolicy()
+	}
+
+	return sc.retryPolicy
+}
+
+// SetRetryPolicy sets the policy a SnowthClient uses to determine the backoff,
+// retry budget and idempotency rules applied when retrying failed requests.
+// A nil value restores the default policy.
+func (sc *SnowthClient) SetRetryPolicy(rp RetryPolicy) {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.retryPolicy = rp
+}
+
+// SetHedgeConfig enables hedged reads of metric data using the provided
+// configuration. A nil value, or one which sets neither a delay nor a
+// percentile, disables hedged reads.
+func (sc *SnowthClient) SetHedgeConfig(cfg *HedgeConfig) {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.hedger = newHedger(cfg)
+}
+
 // SetRequestFunc sets an optional middleware function that is used to modify
 // the HTTP request before it is used by SnowthClient to connect with IRONdb.
 // Tracing headers or other context information provided by the user of this
@@ -397,18 +659,27 @@ func (sc *SnowthClient) LogDebugf(format string, args ...interface{}) {
 
 // Topology returns the currently active topology.
 func (sc *SnowthClient) Topology() (*Topology, error) {
-	if sc.currentTopologyCompiled != nil {
-		return sc.currentTopologyCompiled, nil
+	return sc.topologyContext(context.Background())
+}
+
+// topologyContext returns the currently active topology, retrieving it from
+// an active node using the provided context if it is not cached.
+func (sc *SnowthClient) topologyContext(ctx context.Context) (*Topology,
+	error,
+) {
+	if e := sc.topology.load(); e != nil {
+		return e.topo, nil
 	}
 
-	var lasterr error = nil
+	lasterr := ErrNoActiveNode
 
 	for _, node := range sc.ListActiveNodes() {
-		if topology, lasterr := sc.GetTopologyInfo(node); lasterr == nil {
-			sc.currentTopologyCompiled = topology
-
+		topology, err := sc.GetTopologyInfoContext(ctx, node)
+		if err == nil {
 			return topology, nil
 		}
+
+		lasterr = err
 	}
 
 	return nil, lasterr
@@ -430,10 +701,11 @@ func (sc *SnowthClient) FindMetricNodeIDs(uuid, metric string) []string {
 	return results
 }
 
-// isNodeActive checks to see if a given node is active or not taking into
+// isNodeActive checks to see if a given node is active or not using the
+// checks required by the client health policy. By default, this takes into
 // account the ability to get the node state, gossip information and the gossip
-// age of the node. If the age is larger than 10 the node is considered
-// inactive.
+// age of the node. If the age is larger than the policy MaxGossipAge the node
+// is considered inactive.
 func (sc *SnowthClient) isNodeActive(ctx context.Context,
 	node *SnowthNode,
 ) bool {
@@ -450,7 +722,19 @@ func (sc *SnowthClient) isNodeActive(ctx context.Context,
 		}
 	}
 
-	if node.identifier == "" || node.semVer == "" {
+	// Probes are only sent to the node being checked, even if its circuit
+	// breaker is open.
+	ctx, _, cancel := sc.callContext(ctx, []CallOption{pinNode()})
+	defer cancel()
+
+	hp := sc.HealthPolicy()
+	statsChecked := false
+
+	// The state of inactive nodes is refreshed, since a node may have been
+	// upgraded while it was unavailable.
+	if node.id() == "" || node.SemVer() == "" || !sc.nodeIsActive(node) {
+		statsChecked = true
+
 		// go get state to figure out identity
 		stats, err := sc.GetStatsNodeContext(ctx, node)
 		if err != nil {
@@ -460,13 +744,43 @@ func (sc *SnowthClient) isNodeActive(ctx context.Context,
 			return false
 		}
 
-		node.identifier = stats.Identity()
-		node.semVer = stats.SemVer()
+		semVer := stats.SemVer()
+		oldVersion := node.setIdentity(stats.Identity(), semVer)
+
 		sc.LogDebugf("retrieved state of node: %s -> %s",
-			node.GetURL().Host, node.identifier)
+			node.GetURL().Host, stats.Identity())
+
+		if oldVersion != "" && oldVersion != semVer {
+			sc.emit(Event{
+				Type:       EventNodeVersionChanged,
+				Node:       node,
+				OldVersion: oldVersion,
+				NewVersion: semVer,
+			})
+		}
+	}
+
+	if hp.requires(HealthCheckStats) && !statsChecked {
+		if _, err := sc.GetStatsNodeContext(ctx, node); err != nil {
+			sc.LogWarnf("unable to get the stats of the node: %s", err.Error())
+
+			return false
+		}
+	}
+
+	if hp.requires(HealthCheckState) {
+		if _, err := sc.GetNodeStateContext(ctx, node); err != nil {
+			sc.LogWarnf("unable to get the state of the node: %s", err.Error())
+
+			return false
+		}
 	}
 
-	gossip, err := sc.GetGossipInfo(node)
+	if !hp.requires(HealthCheckGossip) {
+		return true
+	}
+
+	gossip, err := sc.GetGossipInfoContext(ctx, node)
 	if err != nil {
 		sc.LogWarnf("unable to get the gossip info of the node: %s",
 			err.Error())
@@ -474,18 +788,25 @@ func (sc *SnowthClient) isNodeActive(ctx context.Context,
 		return false
 	}
 
+	sc.updateGossipLatency(gossip)
+
 	age := float64(100)
 
 	for _, entry := range []GossipDetail(*gossip) {
-		if entry.ID == node.identifier {
+		if entry.ID == node.id() {
 			age = entry.Age
 
 			break
 		}
 	}
 
-	if age > 10.0 {
-		sc.LogWarnf("gossip age expired: %s -> %d", node.GetURL().Host, age)
+	maxAge := hp.MaxGossipAge
+	if maxAge <= 0 {
+		maxAge = 10.0
+	}
+
+	if age > maxAge {
+		sc.LogWarnf("gossip age expired: %s -> %f", node.GetURL().Host, age)
 
 		return false
 	}
@@ -508,7 +829,21 @@ func (sc *SnowthClient) WatchAndUpdate(ctx context.Context) {
 		return
 	}
 
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if !sc.startBackground() {
+		return
+	}
+
+	// The watch is stopped if the client is closed.
+	ctx, cancel := sc.backgroundContext(ctx)
+
 	go func(wi time.Duration) {
+		defer sc.endBackground()
+		defer cancel()
+
 		tick := time.NewTimer(wi)
 
 		for {
@@ -545,31 +880,13 @@ func (sc *SnowthClient) WatchAndUpdate(ctx context.Context) {
 					}
 				}
 
-				for _, node := range sc.ListInactiveNodes() {
-					icCtx, icCancel := context.WithTimeout(ctx, to)
-
-					if sc.isNodeActive(icCtx, node) {
-						sc.LogDebugf("moving snowth node to active list: %s",
-							node.GetURL().Host)
-
-						sc.ActivateNodes(node)
-					}
-
-					icCancel()
-				}
-
-				for _, node := range sc.ListActiveNodes() {
-					acCtx, acCancel := context.WithTimeout(ctx, to)
+				rcCtx, rcCancel := context.WithTimeout(ctx, to)
 
-					if !sc.isNodeActive(acCtx, node) {
-						sc.LogWarnf("moving snowth node to inactive list: %s",
-							node.GetURL().Host)
+				sc.resolveNodes(rcCtx)
 
-						sc.DeactivateNodes(node)
-					}
+				rcCancel()
 
-					acCancel()
-				}
+				sc.updateNodeHealth(ctx, to)
 
 				tick = time.NewTimer(wi)
 			}
@@ -587,57 +904,34 @@ func (sc *SnowthClient) populateNodeInfo(ctx context.Context, hash string,
 	found := false
 
 	for i := 0; i < len(sc.activeNodes); i++ {
-		if sc.activeNodes[i].identifier == topology.ID {
+		if sc.activeNodes[i].id() == topology.ID {
 			found = true
-
-			url := url.URL{
-				Scheme: "http",
-				Host: fmt.Sprintf("%s:%d", topology.Address,
-					topology.APIPort),
-			}
-
-			sc.activeNodes[i].url = &url
-			sc.activeNodes[i].currentTopology = hash
+			sc.activeNodes[i].url = sc.topologyNodeURL(topology)
 
 			break
 		}
 	}
 
 	for i := 0; i < len(sc.inactiveNodes); i++ {
-		if sc.inactiveNodes[i].identifier == topology.ID {
+		if sc.inactiveNodes[i].id() == topology.ID {
 			found = true
-
-			url := url.URL{
-				Scheme: "http",
-				Host: fmt.Sprintf("%s:%d", topology.Address,
-					topology.APIPort),
-			}
-
-			sc.inactiveNodes[i].url = &url
-			sc.inactiveNodes[i].currentTopology = hash
+			sc.inactiveNodes[i].url = sc.topologyNodeURL(topology)
 
 			break
 		}
 	}
 
-	if sc.currentTopology != hash {
-		sc.currentTopology = hash
-		sc.currentTopologyCompiled = nil
-	}
-
 	dhosts := sc.denyHosts
+	nodeURL := sc.topologyNodeURL(topology)
 
 	sc.Unlock()
 
+	sc.updateTopology(nil, hash)
+
 	if !found {
 		newNode := &SnowthNode{
 			identifier: topology.ID,
-			url: &url.URL{
-				Scheme: "http",
-				Host: fmt.Sprintf("%s:%d", topology.Address,
-					topology.APIPort),
-			},
-			currentTopology: hash,
+			url:        nodeURL,
 		}
 
 		for _, dh := range dhosts {
@@ -657,19 +951,127 @@ func (sc *SnowthClient) populateNodeInfo(ctx context.Context, hash string,
 			return
 		}
 
-		newNode.identifier = stats.Identity()
-		newNode.semVer = stats.SemVer()
+		newNode.setIdentity(stats.Identity(), stats.SemVer())
 
 		sc.AddNodes(newNode)
 		sc.ActivateNodes(newNode)
+		sc.updateTopology(newNode, stats.CurrentTopology())
 	}
 }
 
-// ActivateNodes makes provided nodes active.
-func (sc *SnowthClient) ActivateNodes(nodes ...*SnowthNode) {
+// updateTopology records the topology hash reported by a node, or by the
+// cluster if node is nil. The current cluster topology only moves to the hash
+// if no topology is known or the known one was restored from a snapshot, if a
+// node has reported it as the next topology, or if it is reported by a
+// majority of the active nodes. This prevents nodes
+// which have not yet moved to a new topology during a rebalance from moving
+// the client back to the old one. The current topology is retrieved in the
+// background if it is not cached.
+func (sc *SnowthClient) updateTopology(node *SnowthNode, hash string) {
+	if hash == "" {
+		return
+	}
+
+	if node != nil {
+		node.setCurrentTopology(hash)
+	}
+
+	sc.Lock()
+
+	oldTopo := sc.currentTopology
+
+	if oldTopo != hash && (oldTopo == "" || sc.snapshotTopology ||
+		hash == sc.nextTopology || sc.topologyMajority(node, hash)) {
+		sc.currentTopology = hash
+
+		if sc.nextTopology == hash {
+			sc.nextTopology = ""
+		}
+	}
+
+	current := sc.currentTopology
+
+	if current == hash {
+		sc.snapshotTopology = false
+	}
+
+	sc.Unlock()
+
+	if current != hash {
+		return
+	}
+
+	sc.emit(topologyEvent(node, oldTopo, hash)...)
+
+	// A new topology is retrieved in the background, while lookups continue
+	// to use the last good topology.
+	if sc.topology.load() != nil {
+		sc.refreshTopology(node, hash)
+	}
+}
+
+// topologyMajority reports whether a majority of the active nodes report the
+// provided topology hash. The hash reported by node is used for the active
+// node with the same URL. The caller must hold the client lock.
+func (sc *SnowthClient) topologyMajority(node *SnowthNode, hash string) bool {
+	count := 0
+
+	for _, n := range sc.activeNodes {
+		h := n.GetCurrentTopology()
+		if node != nil && n.GetURL().String() == node.GetURL().String() {
+			h = node.GetCurrentTopology()
+		}
+
+		if h == hash {
+			count++
+		}
+	}
+
+	return count*2 > len(sc.activeNodes)
+}
+
+// setNextTopology records the next topology hash reported by a node, if it
+// differs from the current topology.
+func (sc *SnowthClient) setNextTopology(hash string) {
+	if hash == "" || hash == "-" {
+		return
+	}
+
 	sc.Lock()
 	defer sc.Unlock()
 
+	if hash != sc.currentTopology {
+		sc.nextTopology = hash
+	}
+}
+
+// topologyNodeURL returns the API URL of a node discovered through the
+// cluster topology, using the scheme determined by the client TLS settings.
+// The caller must hold the client lock.
+func (sc *SnowthClient) topologyNodeURL(topology TopologyNode) *url.URL {
+	scheme := sc.scheme
+	if scheme == "" {
+		scheme = SchemePolicyHTTP
+	}
+
+	return &url.URL{
+		Scheme: scheme,
+		Host:   fmt.Sprintf("%s:%d", topology.Address, topology.APIPort),
+	}
+}
+
+// ActivateNodes makes provided nodes active. The circuit breakers of the
+// nodes are reset to the closed state. If any nodes were activated, the
+// writes in the client spool are replayed in the background.
+func (sc *SnowthClient) ActivateNodes(nodes ...*SnowthNode) {
+	for _, v := range nodes {
+		if cb := sc.nodeBreaker(v); cb != nil {
+			cb.reset()
+		}
+	}
+
+	sc.Lock()
+
 	in := []*SnowthNode{}
 	match := false
 
@@ -709,14 +1111,30 @@ func (sc *SnowthClient) ActivateNodes(nodes ...*SnowthNode) {
 	}
 
 	sc.activeNodes = append(sc.activeNodes, an...)
+
+	sc.Unlock()
+
+	sc.emit(nodeEvents(EventNodeActivated, "", an)...)
+
+	if len(an) > 0 {
+		sc.replaySpool()
+	}
 }
 
 // DeactivateNodes makes provided nodes inactive.
 func (sc *SnowthClient) DeactivateNodes(nodes ...*SnowthNode) {
+	sc.deactivateNodes(ReasonRequested, nodes...)
+}
+
+// deactivateNodes makes provided nodes inactive, emitting a node deactivated
+// event with the provided reason for each node which was active.
+func (sc *SnowthClient) deactivateNodes(reason DeactivationReason,
+	nodes ...*SnowthNode,
+) {
 	sc.Lock()
-	defer sc.Unlock()
 
 	an := []*SnowthNode{}
+	removed := []*SnowthNode{}
 	match := false
 
 	for _, av := range sc.activeNodes {
@@ -732,6 +1150,8 @@ func (sc *SnowthClient) DeactivateNodes(nodes ...*SnowthNode) {
 
 		if !match {
 			an = append(an, av)
+		} else {
+			removed = append(removed, av)
 		}
 	}
 
@@ -755,12 +1175,15 @@ func (sc *SnowthClient) DeactivateNodes(nodes ...*SnowthNode) {
 	}
 
 	sc.inactiveNodes = append(sc.inactiveNodes, in...)
+
+	sc.Unlock()
+
+	sc.emit(nodeEvents(EventNodeDeactivated, reason, removed)...)
 }
 
 // AddNodes adds node values to the inactive node list.
 func (sc *SnowthClient) AddNodes(nodes ...*SnowthNode) {
 	sc.Lock()
-	defer sc.Unlock()
 
 	in := []*SnowthNode{}
 	match := false
@@ -782,6 +1205,47 @@ func (sc *SnowthClient) AddNodes(nodes ...*SnowthNode) {
 	}
 
 	sc.inactiveNodes = append(sc.inactiveNodes, in...)
+
+	sc.Unlock()
+
+	sc.emit(nodeEvents(EventNodeAdded, "", in)...)
+}
+
+// removeNodes removes nodes from the active and inactive node lists.
+func (sc *SnowthClient) removeNodes(nodes ...*SnowthNode) {
+	rm := make(map[string]bool, len(nodes))
+	for _, v := range nodes {
+		rm[v.GetURL().String()] = true
+	}
+
+	sc.Lock()
+
+	removed := []*SnowthNode{}
+	an := []*SnowthNode{}
+
+	for _, av := range sc.activeNodes {
+		if rm[av.GetURL().String()] {
+			removed = append(removed, av)
+		} else {
+			an = append(an, av)
+		}
+	}
+
+	in := []*SnowthNode{}
+
+	for _, iv := range sc.inactiveNodes {
+		if rm[iv.GetURL().String()] {
+			removed = append(removed, iv)
+		} else {
+			in = append(in, iv)
+		}
+	}
+
+	sc.activeNodes, sc.inactiveNodes = an, in
+
+	sc.Unlock()
+
+	sc.emit(nodeEvents(EventNodeRemoved, "", removed)...)
 }
 
 // ListInactiveNodes lists all of the currently inactive nodes.
@@ -795,6 +1259,20 @@ func (sc *SnowthClient) ListInactiveNodes() []*SnowthNode {
 	return result
 }
 
+// nodeIsActive reports whether a node is in the active node list.
+func (sc *SnowthClient) nodeIsActive(node *SnowthNode) bool {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	for _, av := range sc.activeNodes {
+		if av.GetURL().String() == node.GetURL().String() {
+			return true
+		}
+	}
+
+	return false
+}
+
 // ListActiveNodes lists all of the currently active nodes.
 func (sc *SnowthClient) ListActiveNodes() []*SnowthNode {
 	sc.RLock()
@@ -806,26 +1284,47 @@ func (sc *SnowthClient) ListActiveNodes() []*SnowthNode {
 	return result
 }
 
-// GetActiveNode returns a random active node in the cluster.
+// GetActiveNode returns an active node in the cluster, chosen by the client
+// node selector. If sets of node identifiers are provided, such as the owners
+// of a metric returned by FindMetricNodeIDs(), the node is chosen from the
+// active nodes in the first set containing any active nodes. Nodes whose
+// circuit breakers are open are only chosen if no other active node is
+// available.
 func (sc *SnowthClient) GetActiveNode(idsets ...[]string) *SnowthNode {
+	for _, ids := range idsets {
+		candidates := sc.breakerAllowed(sc.activeOwners(ids))
+		if len(candidates) > 0 {
+			return sc.selectNode(candidates)
+		}
+	}
+
+	active := sc.ListActiveNodes()
+	if candidates := sc.breakerAllowed(active); len(candidates) > 0 {
+		return sc.selectNode(candidates)
+	}
+
+	return sc.selectNode(active)
+}
+
+// activeOwners returns the active nodes having one of the provided node
+// identifiers.
+func (sc *SnowthClient) activeOwners(ids []string) []*SnowthNode {
 	sc.RLock()
 	defer sc.RUnlock()
 
-	if len(sc.activeNodes) == 0 {
-		return nil
-	}
+	result := []*SnowthNode{}
 
-	for _, ids := range idsets {
+	for _, node := range sc.activeNodes {
 		for _, id := range ids {
-			for _, node := range sc.activeNodes {
-				if node.identifier == id {
-					return node
-				}
+			if node.id() == id {
+				result = append(result, node)
+
+				break
 			}
 		}
 	}
 
-	return sc.activeNodes[time.Now().UnixNano()%int64(len(sc.activeNodes))]
+	return result
 }
 
 // DoRequest sends a request to IRONdb.
@@ -844,9 +1343,28 @@ func (sc *SnowthClient) DoRequest(node *SnowthNode,
 // will perform those retries.
 func (sc *SnowthClient) DoRequestContext(ctx context.Context, node *SnowthNode,
 	method string, url string, body io.Reader,
-	headers http.Header,
+	headers http.Header, opts ...CallOption,
 ) (io.Reader, http.Header, error) {
+	if err := sc.beginRequest(); err != nil {
+		return nil, nil, err
+	}
+
+	defer sc.endRequest()
+
+	ctx, n, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if n != nil {
+		node = n
+	}
+
+	co := callOptionsFromContext(ctx)
+
 	retries := sc.Retries()
+	if co.hasRetries {
+		retries = co.retries
+	}
+
 	if retries < 0 {
 		retries = 0
 	}
@@ -866,7 +1384,7 @@ func (sc *SnowthClient) DoRequestContext(ctx context.Context, node *SnowthNode,
 	nodes := []*SnowthNode{node}
 
 	for _, n := range sc.ListActiveNodes() {
-		if n.GetURL().String() != node.GetURL().String() {
+		if !co.pinned && n.GetURL().String() != node.GetURL().String() {
 			nodes = append(nodes, n)
 		}
 	}
@@ -877,14 +1395,37 @@ func (sc *SnowthClient) DoRequestContext(ctx context.Context, node *SnowthNode,
 
 	var status int
 
+	// Each request attempt is sent as a child span of the trace context,
+	// which is also used to identify the request in log output unless a
+	// trace ID is provided through the CtxKeyTraceID context value.
+	tc := sc.requestTraceContext(ctx)
+
 	traceID, ok := ctx.Value(sc.ctxKeyTraceID).(string)
 	if !ok {
-		traceID = strconv.FormatInt(time.Now().UnixNano(), 10)
+		traceID = tc.TraceID
 	}
 
+	policy := sc.RetryPolicy()
+	idempotent := policy.Idempotent(method, requestPath(url))
+	endpoint := endpointTemplate(url)
+
 	for r := int64(0); r < retries+1; r++ {
+		if r > 0 {
+			if !policy.AllowRetry() {
+				sc.LogWarnf("gosnowth retry budget exhausted "+
+					"[traceID: %s, retry: %d]: %s %s", traceID, r, method, url)
+
+				break
+			}
+
+			if werr := waitContext(ctx, policy.Backoff(r)); werr != nil {
+				return bdy, hdr, werr
+			}
+		}
+
 		connRetries := cr
-		sns := nodes
+		sns := append([]*SnowthNode{}, nodes...)
+		attempted := false
 
 		for len(sns) > 0 {
 			n := int64(0)
@@ -904,30 +1445,61 @@ func (sc *SnowthClient) DoRequestContext(ctx context.Context, node *SnowthNode,
 				continue
 			}
 
+			release := func() {}
+
+			if !co.pinned {
+				var ok bool
+
+				if ok, release = sc.breakerAcquire(sn); !ok {
+					sc.LogDebugf("gosnowth skipping node with open circuit "+
+						"breaker [traceID: %s]: %s", traceID,
+						sn.GetURL().Host)
+
+					continue
+				}
+			}
+
+			attempted = true
 			surl := sc.getURL(sn, url)
+			spanID := newTraceID(8)
 
 			sc.LogDebugf("gosnowth %s request "+
-				"[traceID: %s, retry: %d, connRetry: %d]: %s %s %s",
-				reqMsg, traceID, r, (cr - connRetries), method, surl,
-				string(bBody))
+				"[traceID: %s, spanID: %s, retry: %d, connRetry: %d]: "+
+				"%s %s %s", reqMsg, traceID, spanID, r, (cr - connRetries),
+				method, surl, string(bBody))
 
 			start := time.Now()
 
-			bdy, hdr, status, err = sc.do(ctx, sn, method, surl,
-				bytes.NewBuffer(bBody), headers, traceID)
+			bdy, hdr, status, err = sc.observedDo(ctx, &RequestEvent{
+				TraceID:   traceID,
+				SpanID:    spanID,
+				Node:      sn,
+				Method:    method,
+				Endpoint:  endpoint,
+				URL:       surl,
+				BytesOut:  int64(len(bBody)),
+				Retry:     r,
+				ConnRetry: cr - connRetries,
+				Start:     start,
+			}, bBody, headers, tc)
+
+			release()
 
 			sc.LogDebugf("gosnowth request complete "+
-				"[traceID: %s, retry: %d, connRetry: %d]: %s %s latency: %+v",
-				traceID, r, (cr - connRetries), method, surl,
-				time.Since(start))
+				"[traceID: %s, spanID: %s, retry: %d, connRetry: %d]: "+
+				"%s %s latency: %+v", traceID, spanID, r, (cr - connRetries),
+				method, surl, time.Since(start))
 
 			if err == nil {
+				policy.RecordSuccess()
+
 				return bdy, hdr, nil
 			}
 
 			sc.LogWarnf("gosnowth request error "+
-				"[retry: %d, connRetry: %d]: %s %s traceID: %s %+v",
-				r, (cr - connRetries), method, surl, traceID, err)
+				"[retry: %d, connRetry: %d]: %s %s traceID: %s spanID: %s "+
+				"%+v", r, (cr - connRetries), method, surl, traceID, spanID,
+				err)
 
 			// Stop retrying other nodes if he context deadline was reached
 			// or the context has been canceled.
@@ -937,15 +1509,13 @@ func (sc *SnowthClient) DoRequestContext(ctx context.Context, node *SnowthNode,
 			default:
 			}
 
-			// Do not retry 4xx status errors since these indicate a problem
-			// with the request.
-			if status >= http.StatusBadRequest &&
-				status < http.StatusInternalServerError {
+			if !policy.Retryable(status, err) {
 				return bdy, hdr, err
 			}
 
-			if strings.Contains(err.Error(), "cannot parse") ||
-				strings.Contains(err.Error(), "User facing error") {
+			// Requests which are not idempotent are only retried if they
+			// could not have been received by the node.
+			if !idempotent && !isConnectError(err) {
 				return bdy, hdr, err
 			}
 
@@ -956,8 +1526,9 @@ func (sc *SnowthClient) DoRequestContext(ctx context.Context, node *SnowthNode,
 			connRetries--
 		}
 
-		if r < retries {
-			time.Sleep(time.Millisecond * time.Duration(100*2^r))
+		if !attempted && err == nil {
+			return nil, nil, fmt.Errorf("%w: circuit breakers open for all "+
+				"nodes", ErrNoActiveNode)
 		}
 	}
 
@@ -986,9 +1557,21 @@ func (sc *SnowthClient) do(ctx context.Context,
 		strings.HasPrefix(r.URL.Path, sc.traceRequests))
 	dumpReq := sc.dumpRequests != "" && (sc.dumpRequests == "*" ||
 		strings.HasPrefix(r.URL.Path, sc.dumpRequests))
+	closeConns := sc.closeConns
+	timeout := sc.timeout
 	sc.RUnlock()
 
-	r.Close = true
+	co := callOptionsFromContext(ctx)
+
+	if co.trace != nil {
+		traceReq = *co.trace
+	}
+
+	if co.dump != nil {
+		dumpReq = *co.dump
+	}
+
+	r.Close = closeConns
 
 	for key, values := range headers {
 		for _, value := range values {
@@ -996,14 +1579,28 @@ func (sc *SnowthClient) do(ctx context.Context,
 		}
 	}
 
+	for key, values := range co.headers {
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+
+	// The time remaining before the context deadline is used in place of
+	// the client timeout, if it is shorter.
+	if deadline, ok := ctx.Deadline(); ok {
+		if d := time.Until(deadline); timeout <= 0 || d < timeout {
+			timeout = d.Truncate(time.Millisecond)
+		}
+	}
+
 	// Send a header telling snowth to use the gosnowth timeout - 1 second.
-	if sc.timeout > 0 {
-		if (sc.timeout - time.Second) > 0 {
-			to := sc.timeout - time.Second
+	if timeout > 0 {
+		if (timeout - time.Second) > 0 {
+			to := timeout - time.Second
 
 			r.Header.Set("X-Snowth-Timeout", to.String())
 		} else {
-			r.Header.Set("X-Snowth-Timeout", sc.timeout.String())
+			r.Header.Set("X-Snowth-Timeout", timeout.String())
 		}
 	}
 
@@ -1102,8 +1699,28 @@ func (sc *SnowthClient) do(ctx context.Context,
 
 	sc.RUnlock()
 
+	ns := sc.getNodeStats(node)
+	ns.begin()
+
+	start := time.Now()
+	ok := false
+
+	defer func() {
+		if ctx.Err() != nil {
+			ns.abort()
+
+			return
+		}
+
+		ns.end(time.Since(start), ok)
+	}()
+
 	resp, err := cli.Do(r)
 	if err != nil {
+		if ctx.Err() == nil {
+			sc.recordNodeOutcome(node, false)
+		}
+
 		return nil, nil, http.StatusInternalServerError,
 			fmt.Errorf("failed to perform request: %w", err)
 	}
@@ -1114,30 +1731,15 @@ func (sc *SnowthClient) do(ctx context.Context,
 
 	res, err := io.ReadAll(resp.Body)
 	if err != nil {
+		if ctx.Err() == nil {
+			sc.recordNodeOutcome(node, false)
+		}
+
 		return nil, nil, resp.StatusCode,
 			fmt.Errorf("unable to read response body: %w", err)
 	}
 
-	newTopo := resp.Header.Get("X-Topo-0")
-
-	if newTopo != "" {
-		sc.RLock()
-
-		if newTopo != sc.currentTopology || newTopo != node.currentTopology {
-			sc.RUnlock()
-
-			sc.Lock()
-
-			sc.currentTopology = newTopo
-			sc.currentTopologyCompiled = nil
-
-			node.currentTopology = newTopo
-
-			sc.Unlock()
-		} else {
-			sc.RUnlock()
-		}
-	}
+	sc.updateTopology(node, resp.Header.Get("X-Topo-0"))
 
 	if traceReq {
 		msg := string(res[0:64]) + "..."
@@ -1150,11 +1752,30 @@ func (sc *SnowthClient) do(ctx context.Context,
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return bytes.NewBuffer(res), resp.Header, resp.StatusCode,
-			fmt.Errorf("error returned from IRONdb (%s): [%d] %s",
-				r.URL.Host, resp.StatusCode, string(res))
+		ie := &IRONdbError{
+			StatusCode: resp.StatusCode,
+			Node:       node,
+			Endpoint:   endpointTemplate(url),
+			Body:       res,
+			TraceID:    traceID,
+		}
+
+		ie.Retryable = sc.RetryPolicy().Retryable(resp.StatusCode, ie)
+
+		// Server errors caused by the request itself, such as query parsing
+		// errors, do not indicate a problem with the node.
+		ok = resp.StatusCode < http.StatusInternalServerError ||
+			!ie.Retryable
+
+		sc.recordNodeOutcome(node, ok)
+
+		return bytes.NewBuffer(res), resp.Header, resp.StatusCode, ie
 	}
 
+	ok = true
+
+	sc.recordNodeOutcome(node, true)
+
 	return bytes.NewBuffer(res), resp.Header, resp.StatusCode, nil
 }
 
@@ -0,0 +1,289 @@
+package gosnowth
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/circonus-labs/gosnowth/fb/fetch"
+	"github.com/circonus-labs/gosnowth/fb/nntbs"
+	"github.com/circonus-labs/gosnowth/fb/noit"
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// Client values provide the public API of an IRONdb client. Code which uses
+// a Client, rather than a *SnowthClient, can be tested using a fake client,
+// such as the one provided by the snowthfake package, without making any HTTP
+// requests. Client configuration and lifecycle methods, such as SetRetries()
+// and WatchAndUpdate(), are only provided by *SnowthClient.
+type Client interface {
+	DataReader
+	DataWriter
+	TagClient
+	TopologyClient
+	PromQLClient
+	CAQLClient
+	LuaClient
+}
+
+// Ensure that SnowthClient implements Client.
+var _ Client = (*SnowthClient)(nil)
+
+// DataReader values read metric data from IRONdb.
+type DataReader interface {
+	FetchValues(q *FetchQuery, nodes ...*SnowthNode) (*DF4Response, error)
+	FetchValuesContext(ctx context.Context, q *FetchQuery, opts ...CallOption,
+	) (*DF4Response, error)
+	FetchValuesFb(node *SnowthNode, q *fetch.FetchT) (*fetch.DF4T, error)
+	FetchValuesFbContext(ctx context.Context, node *SnowthNode,
+		q *fetch.FetchT, opts ...CallOption,
+	) (*fetch.DF4T, error)
+	ReadNumericValues(start, end time.Time, period int64, t, id, metric string,
+		nodes ...*SnowthNode,
+	) ([]NumericValue, error)
+	ReadNumericValuesContext(ctx context.Context, start, end time.Time,
+		period int64, t, id, metric string, opts ...CallOption,
+	) ([]NumericValue, error)
+	ReadNumericAllValues(start, end time.Time, period int64, id, metric string,
+		nodes ...*SnowthNode,
+	) ([]NumericAllValue, error)
+	ReadNumericAllValuesContext(ctx context.Context, start, end time.Time,
+		period int64, id, metric string, opts ...CallOption,
+	) ([]NumericAllValue, error)
+	ReadNNTValues(start, end time.Time, period int64, t, id, metric string,
+		nodes ...*SnowthNode,
+	) ([]NNTValue, error)
+	ReadNNTValuesContext(ctx context.Context, start, end time.Time,
+		period int64, t, id, metric string, opts ...CallOption,
+	) ([]NNTValue, error)
+	ReadNNTAllValues(start, end time.Time, period int64, id, metric string,
+		nodes ...*SnowthNode,
+	) ([]NNTAllValue, error)
+	ReadNNTAllValuesContext(ctx context.Context, start, end time.Time,
+		period int64, id, metric string, opts ...CallOption,
+	) ([]NNTAllValue, error)
+	ReadHistogramValues(uuid, metric string, period time.Duration,
+		start, end time.Time, nodes ...*SnowthNode,
+	) ([]HistogramValue, error)
+	ReadHistogramValuesContext(ctx context.Context, uuid, metric string,
+		period time.Duration, start, end time.Time, opts ...CallOption,
+	) ([]HistogramValue, error)
+	ReadRawNumericValues(start, end time.Time, uuid, metric string,
+		nodes ...*SnowthNode,
+	) ([]RawNumericValue, error)
+	ReadRawNumericValuesContext(ctx context.Context, start, end time.Time,
+		uuid, metric string, opts ...CallOption,
+	) ([]RawNumericValue, error)
+	ReadRollupValues(uuid, metric string, period time.Duration,
+		start, end time.Time, dataType string, nodes ...*SnowthNode,
+	) ([]RollupValue, error)
+	ReadRollupValuesContext(ctx context.Context, uuid, metric string,
+		period time.Duration, start, end time.Time, dataType string,
+		opts ...CallOption,
+	) ([]RollupValue, error)
+	ReadRollupAllValues(uuid, metric string, period time.Duration,
+		start, end time.Time, nodes ...*SnowthNode,
+	) ([]RollupAllValue, error)
+	ReadRollupAllValuesContext(ctx context.Context, uuid, metric string,
+		period time.Duration, start, end time.Time, opts ...CallOption,
+	) ([]RollupAllValue, error)
+	ReadTextValues(uuid, metric string, start, end time.Time,
+		nodes ...*SnowthNode,
+	) ([]TextValue, error)
+	ReadTextValuesContext(ctx context.Context, uuid, metric string,
+		start, end time.Time, opts ...CallOption,
+	) ([]TextValue, error)
+	GraphiteGetDatapoints(accountID int64, prefix string,
+		lookup *GraphiteLookup, options *GraphiteOptions, nodes ...*SnowthNode,
+	) (*GraphiteDatapoints, error)
+	GraphiteGetDatapointsContext(ctx context.Context, accountID int64,
+		prefix string, lookup *GraphiteLookup, options *GraphiteOptions,
+		opts ...CallOption,
+	) (*GraphiteDatapoints, error)
+}
+
+// DataWriter values write metric data to IRONdb.
+type DataWriter interface {
+	WriteNumeric(data []NumericWrite, nodes ...*SnowthNode) error
+	WriteNumericContext(ctx context.Context, data []NumericWrite,
+		opts ...CallOption,
+	) error
+	WriteNNT(data []NNTData, nodes ...*SnowthNode) error
+	WriteNNTContext(ctx context.Context, data []NNTData, opts ...CallOption,
+	) error
+	WriteText(data []TextData, nodes ...*SnowthNode) error
+	WriteTextContext(ctx context.Context, data []TextData, opts ...CallOption,
+	) error
+	WriteHistogram(data []HistogramData, nodes ...*SnowthNode) error
+	WriteHistogramContext(ctx context.Context, data []HistogramData,
+		opts ...CallOption,
+	) error
+	WriteRaw(data io.Reader, fb bool, dataPoints uint64, nodes ...*SnowthNode,
+	) (*IRONdbPutResponse, error)
+	WriteRawContext(ctx context.Context, data io.Reader, fb bool,
+		dataPoints uint64, opts ...CallOption,
+	) (*IRONdbPutResponse, error)
+	WriteRawMetricList(metricList *noit.MetricListT,
+		builder *flatbuffers.Builder, nodes ...*SnowthNode,
+	) (*IRONdbPutResponse, error)
+	WriteRawMetricListContext(ctx context.Context,
+		metricList *noit.MetricListT, builder *flatbuffers.Builder,
+		opts ...CallOption,
+	) (*IRONdbPutResponse, error)
+	WriteNNTBSFlatbuffer(merge *nntbs.NNTMergeT, builder *flatbuffers.Builder,
+		nodes ...*SnowthNode,
+	) error
+	WriteNNTBSFlatbufferContext(ctx context.Context, merge *nntbs.NNTMergeT,
+		builder *flatbuffers.Builder, opts ...CallOption,
+	) error
+	RebuildActivity(node *SnowthNode, rebuildRequest []RebuildActivityRequest,
+	) (*IRONdbPutResponse, error)
+	RebuildActivityContext(ctx context.Context, node *SnowthNode,
+		rebuildRequest []RebuildActivityRequest, opts ...CallOption,
+	) (*IRONdbPutResponse, error)
+}
+
+// TagClient values find metrics by tag and manage check tags.
+type TagClient interface {
+	FindTags(accountID int64, query string, options *FindTagsOptions,
+		nodes ...*SnowthNode,
+	) (*FindTagsResult, error)
+	FindTagsContext(ctx context.Context, accountID int64, query string,
+		options *FindTagsOptions, opts ...CallOption,
+	) (*FindTagsResult, error)
+	FindTagCats(accountID int64, query string, nodes ...*SnowthNode,
+	) ([]string, error)
+	FindTagCatsContext(ctx context.Context, accountID int64, query string,
+		opts ...CallOption,
+	) ([]string, error)
+	FindTagVals(accountID int64, query, category string, nodes ...*SnowthNode,
+	) ([]string, error)
+	FindTagValsContext(ctx context.Context, accountID int64,
+		query, category string, opts ...CallOption,
+	) ([]string, error)
+	GetCheckTags(checkUUID string, nodes ...*SnowthNode) (CheckTags, error)
+	GetCheckTagsContext(ctx context.Context, checkUUID string,
+		opts ...CallOption,
+	) (CheckTags, error)
+	UpdateCheckTags(checkUUID string, tags []string, nodes ...*SnowthNode,
+	) (int64, error)
+	UpdateCheckTagsContext(ctx context.Context, checkUUID string,
+		tags []string, opts ...CallOption,
+	) (int64, error)
+	DeleteCheckTags(checkUUID string, nodes ...*SnowthNode) error
+	DeleteCheckTagsContext(ctx context.Context, checkUUID string,
+		opts ...CallOption,
+	) error
+	GraphiteFindMetrics(accountID int64, prefix, query string,
+		options *GraphiteOptions, nodes ...*SnowthNode,
+	) ([]GraphiteMetric, error)
+	GraphiteFindMetricsContext(ctx context.Context, accountID int64,
+		prefix, query string, options *GraphiteOptions, opts ...CallOption,
+	) ([]GraphiteMetric, error)
+	GraphiteFindTags(accountID int64, prefix, query string,
+		options *GraphiteOptions, nodes ...*SnowthNode,
+	) ([]GraphiteMetric, error)
+	GraphiteFindTagsContext(ctx context.Context, accountID int64,
+		prefix, query string, options *GraphiteOptions, opts ...CallOption,
+	) ([]GraphiteMetric, error)
+}
+
+// TopologyClient values retrieve and manage the cluster topology and the
+// nodes known by a client.
+type TopologyClient interface {
+	Topology() (*Topology, error)
+	TopologyCacheStatus() TopologyCacheStatus
+	FindMetricNodeIDs(uuid, metric string) []string
+	GetTopologyInfo(nodes ...*SnowthNode) (*Topology, error)
+	GetTopologyInfoContext(ctx context.Context, opts ...CallOption,
+	) (*Topology, error)
+	LoadTopology(hash string, t *Topology, nodes ...*SnowthNode) error
+	LoadTopologyContext(ctx context.Context, hash string, t *Topology,
+		node *SnowthNode, opts ...CallOption,
+	) error
+	ActivateTopology(hash string, node *SnowthNode) error
+	ActivateTopologyContext(ctx context.Context, hash string, node *SnowthNode,
+		opts ...CallOption,
+	) error
+	LocateMetric(uuid, metric string, nodes ...*SnowthNode,
+	) ([]TopologyNode, error)
+	LocateMetricContext(ctx context.Context, uuid, metric string,
+		opts ...CallOption,
+	) ([]TopologyNode, error)
+	LocateMetricRemote(uuid, metric string, node *SnowthNode,
+	) ([]TopologyNode, error)
+	LocateMetricRemoteContext(ctx context.Context, uuid, metric string,
+		node *SnowthNode, opts ...CallOption,
+	) ([]TopologyNode, error)
+	GetStats(nodes ...*SnowthNode) (*Stats, error)
+	GetStatsContext(ctx context.Context, opts ...CallOption) (*Stats, error)
+	GetStatsNodeContext(ctx context.Context, node *SnowthNode,
+		opts ...CallOption,
+	) (*Stats, error)
+	GetNodeState(nodes ...*SnowthNode) (*NodeState, error)
+	GetNodeStateContext(ctx context.Context, opts ...CallOption,
+	) (*NodeState, error)
+	GetGossipInfo(nodes ...*SnowthNode) (*Gossip, error)
+	GetGossipInfoContext(ctx context.Context, opts ...CallOption,
+	) (*Gossip, error)
+	ListActiveNodes() []*SnowthNode
+	ListInactiveNodes() []*SnowthNode
+	GetActiveNode(idsets ...[]string) *SnowthNode
+	AddNodes(nodes ...*SnowthNode)
+	ActivateNodes(nodes ...*SnowthNode)
+	DeactivateNodes(nodes ...*SnowthNode)
+}
+
+// PromQLClient values execute PromQL queries.
+type PromQLClient interface {
+	PromQLInstantQuery(query *PromQLInstantQuery, nodes ...*SnowthNode,
+	) (*PromQLResponse, error)
+	PromQLInstantQueryContext(ctx context.Context, query *PromQLInstantQuery,
+		opts ...CallOption,
+	) (*PromQLResponse, error)
+	PromQLRangeQuery(query *PromQLRangeQuery, nodes ...*SnowthNode,
+	) (*PromQLResponse, error)
+	PromQLRangeQueryContext(ctx context.Context, query *PromQLRangeQuery,
+		opts ...CallOption,
+	) (*PromQLResponse, error)
+	PromQLSeriesQuery(query *PromQLSeriesQuery, nodes ...*SnowthNode,
+	) (*PromQLResponse, error)
+	PromQLSeriesQueryContext(ctx context.Context, query *PromQLSeriesQuery,
+		opts ...CallOption,
+	) (*PromQLResponse, error)
+	PromQLLabelQuery(query *PromQLLabelQuery, nodes ...*SnowthNode,
+	) (*PromQLResponse, error)
+	PromQLLabelQueryContext(ctx context.Context, query *PromQLLabelQuery,
+		opts ...CallOption,
+	) (*PromQLResponse, error)
+	PromQLLabelValuesQuery(label string, query *PromQLLabelQuery,
+		nodes ...*SnowthNode,
+	) (*PromQLResponse, error)
+	PromQLLabelValuesQueryContext(ctx context.Context, label string,
+		query *PromQLLabelQuery, opts ...CallOption,
+	) (*PromQLResponse, error)
+	PromQLMetadataQuery(query *PromQLMetadataQuery, nodes ...*SnowthNode,
+	) (*PromQLResponse, error)
+	PromQLMetadataQueryContext(ctx context.Context, query *PromQLMetadataQuery,
+		opts ...CallOption,
+	) (*PromQLResponse, error)
+}
+
+// CAQLClient values execute CAQL queries.
+type CAQLClient interface {
+	GetCAQLQuery(q *CAQLQuery, nodes ...*SnowthNode) (*DF4Response, error)
+	GetCAQLQueryContext(ctx context.Context, q *CAQLQuery, opts ...CallOption,
+	) (*DF4Response, error)
+}
+
+// LuaClient values list and execute IRONdb Lua extensions.
+type LuaClient interface {
+	GetLuaExtensions(nodes ...*SnowthNode) (LuaExtensions, error)
+	GetLuaExtensionsContext(ctx context.Context, opts ...CallOption,
+	) (LuaExtensions, error)
+	ExecLuaExtension(name string, params []ExtParam, nodes ...*SnowthNode,
+	) (map[string]interface{}, error)
+	ExecLuaExtensionContext(ctx context.Context, name string,
+		params []ExtParam, opts ...CallOption,
+	) (map[string]interface{}, error)
+}
@@ -3,10 +3,13 @@ package gosnowth
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -350,3 +353,243 @@ func TestSnowthClientLog(t *testing.T) {
 		t.Errorf("Expected log entry: %v, got: %v", exp, ml.last)
 	}
 }
+
+type countingTransport struct {
+	sync.Mutex
+	rt    http.RoundTripper
+	count int
+}
+
+func (ct *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ct.Lock()
+	ct.count++
+	ct.Unlock()
+
+	return ct.rt.RoundTrip(r)
+}
+
+func TestNewClientTransport(t *testing.T) {
+	t.Parallel()
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			w.Header().Set("X-Topo-0", "test")
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+	}))
+
+	defer ms.Close()
+
+	ct := &countingTransport{rt: http.DefaultTransport}
+
+	cfg := NewConfig(ms.URL)
+	cfg.Transport = ct
+
+	sc, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	if _, err = sc.GetStats(); err != nil {
+		t.Fatal(err)
+	}
+
+	ct.Lock()
+	count := ct.count
+	ct.Unlock()
+
+	if count != 2 {
+		t.Errorf("Expected transport requests: 2, got: %v", count)
+	}
+
+	sc.RLock()
+	topo := sc.currentTopology
+	sc.RUnlock()
+
+	if topo != "test" {
+		t.Errorf("Expected current topology: test, got: %v", topo)
+	}
+}
+
+func TestNewClientDialContext(t *testing.T) {
+	t.Parallel()
+
+	sock := filepath.Join(t.TempDir(), "irondb.sock")
+
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skip("unix sockets not available:", err)
+	}
+
+	ms := httptest.NewUnstartedServer(http.HandlerFunc(func(
+		w http.ResponseWriter, r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+	}))
+
+	ms.Listener = l
+	ms.Start()
+
+	defer ms.Close()
+
+	cfg := NewConfig("http://irondb:8112")
+	cfg.DialContext = func(ctx context.Context,
+		network, addr string,
+	) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+	}
+
+	sc, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	stats, err := sc.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Identity() != "bb6f7162-4828-11df-bab8-6bac200dcc2a" {
+		t.Errorf("Unexpected identity: %v", stats.Identity())
+	}
+}
+
+func TestTopologyRebalance(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+
+	topos := map[string]string{"a": "old", "b": "old"}
+
+	server := func(id string) *httptest.Server {
+		stats := `{"identity":{"_type":"s","_value":"` + id + `"},` +
+			`"semver":{"_type":"s","_value":"1.0.0"},` +
+			`"topology":{"current":{"_type":"s","_value":"old"},` +
+			`"next":{"_type":"s","_value":"new"}}}`
+
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+			r *http.Request,
+		) {
+			switch r.RequestURI {
+			case "/stats.json":
+				_, _ = w.Write([]byte(stats))
+			case "/state":
+				mu.Lock()
+				w.Header().Set("X-Topo-0", topos[id])
+				mu.Unlock()
+
+				_, _ = w.Write([]byte(stateTestData))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	}
+
+	msa, msb := server("a"), server("b")
+
+	defer msa.Close()
+	defer msb.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{msa.URL, msb.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	defer func() {
+		_ = sc.Close(context.Background())
+	}()
+
+	nodes := map[string]*SnowthNode{}
+	deadline := time.Now().Add(time.Second)
+
+	for len(nodes) < 2 && time.Now().Before(deadline) {
+		for _, n := range sc.ListActiveNodes() {
+			nodes[n.id()] = n
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if len(nodes) != 2 {
+		t.Fatalf("Expected active nodes: 2, got: %v", len(nodes))
+	}
+
+	current := func() string {
+		sc.RLock()
+		defer sc.RUnlock()
+
+		return sc.currentTopology
+	}
+
+	if topo := current(); topo != "old" {
+		t.Fatalf("Expected current topology: old, got: %v", topo)
+	}
+
+	ch := make(chan Event, 10)
+	sc.SubscribeChan(ch)
+
+	mu.Lock()
+	topos["a"] = "new"
+	mu.Unlock()
+
+	// Responses from the node still on the old topology do not move the
+	// client back to it.
+	for i := 0; i < 3; i++ {
+		for _, id := range []string{"a", "b"} {
+			if _, err := sc.GetNodeState(nodes[id]); err != nil {
+				t.Fatal(err)
+			}
+
+			if topo := current(); topo != "new" {
+				t.Fatalf("Expected current topology: new, got: %v", topo)
+			}
+		}
+	}
+
+	ev := receiveEvent(t, ch)
+	if ev.Type != EventTopologyChanged || ev.OldTopology != "old" ||
+		ev.NewTopology != "new" || ev.Node != nodes["a"] {
+		t.Errorf("Expected topology change from old to new, got: %+v", ev)
+	}
+
+	select {
+	case ev := <-ch:
+		t.Errorf("Expected no more events, got: %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if topo := nodes["b"].GetCurrentTopology(); topo != "old" {
+		t.Errorf("Expected node topology: old, got: %v", topo)
+	}
+
+	// A hash which is neither the next topology nor reported by a majority
+	// of the nodes does not become current until it is.
+	mu.Lock()
+	topos["a"], topos["b"] = "other", "other"
+	mu.Unlock()
+
+	if _, err := sc.GetNodeState(nodes["a"]); err != nil {
+		t.Fatal(err)
+	}
+
+	if topo := current(); topo != "new" {
+		t.Errorf("Expected current topology: new, got: %v", topo)
+	}
+
+	if _, err := sc.GetNodeState(nodes["b"]); err != nil {
+		t.Fatal(err)
+	}
+
+	if topo := current(); topo != "other" {
+		t.Errorf("Expected current topology: other, got: %v", topo)
+	}
+}
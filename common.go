@@ -18,6 +18,16 @@ type IRONdbPutResponse struct {
 	Misdirected uint64 `json:"misdirected"`
 	Records     uint64 `json:"records"`
 	Updated     uint64 `json:"updated"`
+
+	// Rerouted is the number of misdirected records which the client re-sent
+	// to, and had accepted by, the nodes owning them. It is not part of the
+	// IRONdb response, and the other counts include the re-sent records.
+	Rerouted uint64 `json:"-"`
+
+	// Spooled is set when the data could not be sent, and was added to the
+	// client spool to be sent when nodes become active. The other counts are
+	// zero in this case.
+	Spooled bool `json:"-"`
 }
 
 // resolveURL resolves the address of a URL plus a string reference.
@@ -0,0 +1,276 @@
+package gosnowth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemePrefixSRV is the prefix of Servers entries which are resolved using
+// DNS SRV records, such as srv+http://_irondb._tcp.example.
+const SchemePrefixSRV = "srv+"
+
+// errDenyHost is returned for resolved server addresses which are listed in
+// the client deny hosts.
+var errDenyHost = errors.New("deny host found in servers")
+
+// Resolver values are used by SnowthClient values to resolve the DNS names
+// found in Config.Servers. The *net.Resolver type implements this interface.
+type Resolver interface {
+	// LookupSRV returns the SRV records for a name. When service and proto
+	// are empty, name is looked up directly.
+	LookupSRV(ctx context.Context, service, proto,
+		name string) (string, []*net.SRV, error)
+
+	// LookupHost returns the addresses of a host name.
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// SetResolver sets the resolver used to resolve the DNS names found in the
+// servers the client was created with. A nil value restores the default
+// resolver.
+func (sc *SnowthClient) SetResolver(r Resolver) {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.resolver = r
+}
+
+// getResolver returns the resolver used by the client.
+func (sc *SnowthClient) getResolver() Resolver {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	if sc.resolver == nil {
+		return net.DefaultResolver
+	}
+
+	return sc.resolver
+}
+
+// resolveServers converts server addresses into node URLs. Addresses using
+// the srv+ scheme prefix are replaced by a URL for each SRV record target.
+// Host names resolving to more than one address are replaced by a URL for
+// each address, so that each node is contacted at its own address. When
+// these nodes are contacted using HTTPS, TLSConfig ServerName must be set to
+// the name in their certificates. Other addresses, including host names which
+// resolve to a single address or cannot be resolved, are used as provided. An
+// error is returned for each address which could not be converted.
+func (sc *SnowthClient) resolveServers(ctx context.Context,
+	servers []string,
+) ([]*url.URL, []error) {
+	sc.RLock()
+	dhosts := sc.denyHosts
+	scheme := sc.scheme
+	force := sc.forceScheme
+	sc.RUnlock()
+
+	res := resolver{sc.getResolver()}
+	urls := []*url.URL{}
+	errs := []error{}
+	seen := map[string]bool{}
+
+	for _, addr := range servers {
+		resolved, err := res.resolve(ctx, addr)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+	urlLoop:
+		for _, u := range resolved {
+			if force {
+				u.Scheme = scheme
+			}
+
+			for _, dh := range dhosts {
+				if u.Host == dh {
+					errs = append(errs, fmt.Errorf("%w: %s", errDenyHost,
+						u.Host))
+
+					continue urlLoop
+				}
+			}
+
+			if !seen[u.String()] {
+				seen[u.String()] = true
+
+				urls = append(urls, u)
+			}
+		}
+	}
+
+	return urls, errs
+}
+
+// resolveNodes resolves the servers the client was created with again, and
+// adds any nodes not already known to the client to the inactive list, where
+// they are checked by WatchAndUpdate(). Nodes previously found in DNS which
+// are no longer found are removed, unless some servers could not be resolved.
+func (sc *SnowthClient) resolveNodes(ctx context.Context) {
+	sc.RLock()
+	servers := sc.servers
+	sc.RUnlock()
+
+	if len(servers) == 0 {
+		return
+	}
+
+	urls, errs := sc.resolveServers(ctx, servers)
+	failed := false
+
+	for _, err := range errs {
+		sc.LogWarnf("unable to resolve snowth servers: %s", err.Error())
+
+		if !errors.Is(err, errDenyHost) {
+			failed = true
+		}
+	}
+
+	known := map[string]*SnowthNode{}
+
+	for _, node := range append(sc.ListActiveNodes(),
+		sc.ListInactiveNodes()...) {
+		known[node.GetURL().String()] = node
+	}
+
+	sc.Lock()
+
+	discovered := make(map[string]bool, len(urls))
+	add := []*SnowthNode{}
+
+	for _, u := range urls {
+		key := u.String()
+		discovered[key] = true
+
+		// Nodes previously added from DNS may since have been given their
+		// topology address by populateNodeInfo(), so they are not added
+		// again while they remain in DNS.
+		if known[key] != nil || sc.discovered[key] {
+			continue
+		}
+
+		add = append(add, &SnowthNode{url: u})
+	}
+
+	if failed {
+		for key := range sc.discovered {
+			discovered[key] = true
+		}
+	}
+
+	rm := []*SnowthNode{}
+
+	for key := range sc.discovered {
+		if !discovered[key] && known[key] != nil {
+			rm = append(rm, known[key])
+		}
+	}
+
+	sc.discovered = discovered
+
+	sc.Unlock()
+
+	if len(add) > 0 {
+		sc.LogDebugf("adding %d snowth nodes found in DNS", len(add))
+		sc.AddNodes(add...)
+	}
+
+	if len(rm) > 0 {
+		sc.LogDebugf("removing %d snowth nodes no longer found in DNS",
+			len(rm))
+		sc.removeNodes(rm...)
+	}
+}
+
+// resolver values wrap a Resolver to convert server addresses into URLs.
+type resolver struct {
+	Resolver
+}
+
+// resolve converts a server address into one or more node URLs.
+func (r resolver) resolve(ctx context.Context,
+	addr string,
+) ([]*url.URL, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse server url %s: %w", addr, err)
+	}
+
+	if strings.HasPrefix(strings.ToLower(u.Scheme), SchemePrefixSRV) {
+		return r.resolveSRV(ctx, u)
+	}
+
+	host := u.Hostname()
+	if host == "" || net.ParseIP(host) != nil {
+		return []*url.URL{u}, nil
+	}
+
+	addrs, err := r.LookupHost(ctx, host)
+	if err != nil || len(addrs) < 2 {
+		return []*url.URL{u}, nil //nolint:nilerr
+	}
+
+	sort.Strings(addrs)
+
+	urls := make([]*url.URL, 0, len(addrs))
+
+	for _, a := range addrs {
+		nu := *u
+		nu.Host = joinHostPort(a, u.Port())
+		urls = append(urls, &nu)
+	}
+
+	return urls, nil
+}
+
+// resolveSRV converts a server address using the srv+ scheme prefix into a
+// node URL for each SRV record target.
+func (r resolver) resolveSRV(ctx context.Context,
+	u *url.URL,
+) ([]*url.URL, error) {
+	_, srvs, err := r.LookupSRV(ctx, "", "", u.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("unable to look up SRV records for %s: %w",
+			u.Hostname(), err)
+	}
+
+	if len(srvs) == 0 {
+		return nil, fmt.Errorf("no SRV records found for %s", u.Hostname())
+	}
+
+	scheme := strings.ToLower(u.Scheme)[len(SchemePrefixSRV):]
+	urls := make([]*url.URL, 0, len(srvs))
+
+	for _, srv := range srvs {
+		nu := *u
+		nu.Scheme = scheme
+		nu.Host = joinHostPort(strings.TrimSuffix(srv.Target, "."),
+			strconv.Itoa(int(srv.Port)))
+		urls = append(urls, &nu)
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].Host < urls[j].Host
+	})
+
+	return urls, nil
+}
+
+// joinHostPort combines a host and an optional port into a URL host.
+func joinHostPort(host, port string) string {
+	if port != "" {
+		return net.JoinHostPort(host, port)
+	}
+
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+
+	return host
+}
@@ -0,0 +1,234 @@
+package gosnowth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type fakeResolver struct {
+	sync.Mutex
+	srv   map[string][]*net.SRV
+	hosts map[string][]string
+}
+
+func (fr *fakeResolver) LookupSRV(ctx context.Context, service, proto,
+	name string,
+) (string, []*net.SRV, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	srvs, ok := fr.srv[name]
+	if !ok {
+		return "", nil, fmt.Errorf("no such host: %s", name)
+	}
+
+	return name, srvs, nil
+}
+
+func (fr *fakeResolver) LookupHost(ctx context.Context,
+	host string,
+) ([]string, error) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	addrs, ok := fr.hosts[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host: %s", host)
+	}
+
+	return addrs, nil
+}
+
+func (fr *fakeResolver) setSRV(name string, srvs ...*net.SRV) {
+	fr.Lock()
+	defer fr.Unlock()
+
+	fr.srv[name] = srvs
+}
+
+func TestResolveServers(t *testing.T) {
+	t.Parallel()
+
+	sc := &SnowthClient{
+		denyHosts: []string{"10.0.0.3:8112"},
+		resolver: &fakeResolver{
+			srv: map[string][]*net.SRV{
+				"_irondb._tcp.example": {
+					{Target: "b.example.", Port: 8112},
+					{Target: "a.example.", Port: 8112},
+				},
+			},
+			hosts: map[string][]string{
+				"irondb.example": {"10.0.0.2", "10.0.0.1", "10.0.0.3", "::1"},
+				"single.example": {"10.0.0.4"},
+			},
+		},
+	}
+
+	urls, errs := sc.resolveServers(context.Background(), []string{
+		"srv+http://_irondb._tcp.example",
+		"http://irondb.example:8112",
+		"http://single.example:8112",
+		"http://unknown.example:8112",
+		"http://10.0.0.5:8112",
+		"http://a.example:8112",
+		"srv+https://_missing._tcp.example",
+		":",
+	})
+
+	exp := []string{
+		"http://a.example:8112",
+		"http://b.example:8112",
+		"http://10.0.0.1:8112",
+		"http://10.0.0.2:8112",
+		"http://[::1]:8112",
+		"http://single.example:8112",
+		"http://unknown.example:8112",
+		"http://10.0.0.5:8112",
+	}
+
+	if len(urls) != len(exp) {
+		t.Fatalf("Expected urls: %v, got: %v", exp, urls)
+	}
+
+	for i, u := range urls {
+		if u.String() != exp[i] {
+			t.Errorf("Expected url: %v, got: %v", exp[i], u)
+		}
+	}
+
+	if len(errs) != 3 {
+		t.Errorf("Expected errors: 3, got: %v", errs)
+	}
+
+	sc.scheme, sc.forceScheme = SchemePolicyHTTPS, true
+
+	urls, _ = sc.resolveServers(context.Background(),
+		[]string{"srv+http://_irondb._tcp.example"})
+	if len(urls) != 2 || urls[0].Scheme != SchemePolicyHTTPS {
+		t.Errorf("Expected https urls, got: %v", urls)
+	}
+}
+
+func TestResolveNodes(t *testing.T) {
+	t.Parallel()
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	defer ms.Close()
+
+	u, err := url.Parse(ms.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal("Invalid test URL port")
+	}
+
+	fr := &fakeResolver{
+		srv: map[string][]*net.SRV{
+			"_irondb._tcp.example": {
+				{Target: u.Hostname() + ".", Port: uint16(port)},
+			},
+		},
+	}
+
+	sc, err := NewClient(context.Background(), &Config{
+		Servers:  []string{"srv+http://_irondb._tcp.example"},
+		Resolver: fr,
+	})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	if len(sc.ListActiveNodes()) != 1 ||
+		sc.ListActiveNodes()[0].GetURL().String() != ms.URL {
+		t.Fatalf("Expected active node: %v, got: %v", ms.URL,
+			sc.ListActiveNodes())
+	}
+
+	fr.setSRV("_irondb._tcp.example",
+		&net.SRV{Target: u.Hostname() + ".", Port: uint16(port)},
+		&net.SRV{Target: "10.0.0.1.", Port: 8112})
+
+	sc.resolveNodes(context.Background())
+	sc.resolveNodes(context.Background())
+
+	if len(sc.ListInactiveNodes()) != 1 ||
+		sc.ListInactiveNodes()[0].GetURL().String() != "http://10.0.0.1:8112" {
+		t.Fatalf("Expected inactive node: http://10.0.0.1:8112, got: %v",
+			sc.ListInactiveNodes())
+	}
+
+	// A node previously found in DNS is not added again when its address is
+	// changed to its topology address.
+	sc.ListInactiveNodes()[0].url = &url.URL{
+		Scheme: "http",
+		Host:   "10.0.1.1:8112",
+	}
+
+	sc.resolveNodes(context.Background())
+
+	if len(sc.ListInactiveNodes()) != 1 {
+		t.Errorf("Expected inactive nodes: 1, got: %v",
+			len(sc.ListInactiveNodes()))
+	}
+
+	fr.setSRV("_irondb._tcp.example",
+		&net.SRV{Target: u.Hostname() + ".", Port: uint16(port)},
+		&net.SRV{Target: "10.0.0.2.", Port: 8112})
+
+	sc.resolveNodes(context.Background())
+
+	if len(sc.ListInactiveNodes()) != 2 {
+		t.Fatalf("Expected inactive nodes: 2, got: %v",
+			sc.ListInactiveNodes())
+	}
+
+	// Nodes are not removed when the servers cannot be resolved.
+	fr.Lock()
+	delete(fr.srv, "_irondb._tcp.example")
+	fr.Unlock()
+
+	sc.resolveNodes(context.Background())
+
+	if len(sc.ListInactiveNodes()) != 2 || len(sc.ListActiveNodes()) != 1 {
+		t.Fatalf("Expected nodes: 1 active, 2 inactive, got: %v, %v",
+			sc.ListActiveNodes(), sc.ListInactiveNodes())
+	}
+
+	// Nodes which are no longer found in DNS are removed.
+	fr.setSRV("_irondb._tcp.example",
+		&net.SRV{Target: u.Hostname() + ".", Port: uint16(port)})
+
+	sc.resolveNodes(context.Background())
+
+	if len(sc.ListInactiveNodes()) != 1 ||
+		sc.ListInactiveNodes()[0].GetURL().Host != "10.0.1.1:8112" {
+		t.Errorf("Expected inactive node: 10.0.1.1:8112, got: %v",
+			sc.ListInactiveNodes())
+	}
+
+	if len(sc.ListActiveNodes()) != 1 {
+		t.Errorf("Expected active nodes: 1, got: %v", sc.ListActiveNodes())
+	}
+}
@@ -0,0 +1,123 @@
+package gosnowth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors returned by SnowthClient methods. These can be detected
+// using errors.Is().
+var (
+	// ErrNoActiveNode is returned when no active node is available to
+	// receive a request.
+	ErrNoActiveNode = errors.New("unable to get active node")
+
+	// ErrInvalidQuery is returned when a query is rejected, either before
+	// being sent because it is invalid, or by IRONdb because it could not be
+	// parsed or evaluated.
+	ErrInvalidQuery = errors.New("invalid query")
+
+	// ErrClientClosed is returned by requests made with a SnowthClient after
+	// it has been closed.
+	ErrClientClosed = errors.New("snowth client is closed")
+)
+
+// IRONdbError values represent an error response returned by an IRONdb node.
+// Errors returned by SnowthClient methods can be converted to IRONdbError
+// values using errors.As().
+type IRONdbError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Node is the node which returned the response.
+	Node *SnowthNode
+
+	// Endpoint is the endpoint template of the request, such as
+	// /read/{start_ts}/{end_ts}/{period}/{uuid}/{type}/{metric}.
+	Endpoint string
+
+	// Body is the body of the response.
+	Body []byte
+
+	// TraceID is the trace ID used in log output for the request.
+	TraceID string
+
+	// Retryable indicates whether the request may succeed if retried,
+	// according to the client retry policy.
+	Retryable bool
+}
+
+// Error returns the error message, which includes the node host, status code
+// and response body.
+func (ie *IRONdbError) Error() string {
+	host := ""
+	if ie.Node != nil && ie.Node.GetURL() != nil {
+		host = ie.Node.GetURL().Host
+	}
+
+	return fmt.Sprintf("error returned from IRONdb (%s): [%d] %s",
+		host, ie.StatusCode, string(ie.Body))
+}
+
+// Is reports whether the error matches a target error. An IRONdbError matches
+// ErrInvalidQuery if IRONdb rejected the request as invalid.
+func (ie *IRONdbError) Is(target error) bool {
+	if target != ErrInvalidQuery {
+		return false
+	}
+
+	return ie.StatusCode == http.StatusBadRequest ||
+		(ie.StatusCode >= http.StatusInternalServerError && ie.requestError())
+}
+
+// requestError reports whether IRONdb rejected the request itself, rather
+// than failing to process it. This is the case for 4xx responses, and for
+// server errors reporting a query which could not be parsed or evaluated.
+func (ie *IRONdbError) requestError() bool {
+	if ie.StatusCode >= http.StatusBadRequest &&
+		ie.StatusCode < http.StatusInternalServerError {
+		return true
+	}
+
+	return bytes.Contains(ie.Body, []byte("cannot parse")) ||
+		bytes.Contains(ie.Body, []byte("User facing error"))
+}
+
+// Temporary reports whether the error is likely to be resolved by retrying
+// the request.
+func (ie *IRONdbError) Temporary() bool {
+	return ie.Retryable
+}
+
+// invalidQueryError values represent queries found to be invalid before they
+// are sent to IRONdb.
+type invalidQueryError struct {
+	msg string
+	err error
+}
+
+// invalidQueryf returns a formatted error which matches ErrInvalidQuery. The
+// format supports the %w directive in the same way as fmt.Errorf().
+func invalidQueryf(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+
+	return &invalidQueryError{msg: err.Error(), err: errors.Unwrap(err)}
+}
+
+// Error returns the error message.
+func (iqe *invalidQueryError) Error() string {
+	return iqe.msg
+}
+
+// Is reports whether the error matches a target error. It matches
+// ErrInvalidQuery.
+func (iqe *invalidQueryError) Is(target error) bool {
+	return target == ErrInvalidQuery
+}
+
+// Unwrap returns the error which caused the query to be invalid, if any.
+func (iqe *invalidQueryError) Unwrap() error {
+	return iqe.err
+}
@@ -0,0 +1,154 @@
+package gosnowth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestIRONdbError(t *testing.T) {
+	t.Parallel()
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		status, err := strconv.Atoi(r.URL.Query().Get("status"))
+		if err != nil {
+			status = http.StatusInternalServerError
+		}
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("test error"))
+	}))
+
+	defer ms.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	u, err := url.Parse(ms.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	node := &SnowthNode{url: u}
+
+	tests := []struct {
+		status    int
+		retryable bool
+		invalid   bool
+	}{
+		{http.StatusBadRequest, false, true},
+		{http.StatusNotFound, false, false},
+		{http.StatusServiceUnavailable, true, false},
+	}
+
+	for _, tt := range tests {
+		_, _, err := sc.DoRequest(node, "GET",
+			"/read/1/2/3aa57ac2-28de-4ec4-aa3d-ed0ddd48fa4d/test?status="+
+				strconv.Itoa(tt.status), nil, nil)
+		if err == nil {
+			t.Fatalf("Expected error for status: %v", tt.status)
+		}
+
+		var ie *IRONdbError
+		if !errors.As(err, &ie) {
+			t.Fatalf("Expected error type: IRONdbError, got: %T", err)
+		}
+
+		if ie.StatusCode != tt.status {
+			t.Errorf("Expected status: %v, got: %v", tt.status,
+				ie.StatusCode)
+		}
+
+		if ie.Node != node || string(ie.Body) != "test error" ||
+			ie.TraceID == "" {
+			t.Errorf("Expected node, body and trace ID, got: %+v", ie)
+		}
+
+		if ie.Endpoint != "/read/{start_ts}/{end_ts}/{uuid}/{metric}" {
+			t.Errorf("Expected endpoint template, got: %v", ie.Endpoint)
+		}
+
+		if ie.Retryable != tt.retryable || ie.Temporary() != tt.retryable {
+			t.Errorf("Expected retryable for %v: %v, got: %v", tt.status,
+				tt.retryable, ie.Retryable)
+		}
+
+		if errors.Is(err, ErrInvalidQuery) != tt.invalid {
+			t.Errorf("Expected invalid query for %v: %v", tt.status,
+				tt.invalid)
+		}
+
+		exp := fmt.Sprintf("error returned from IRONdb (%s): [%d] test error",
+			u.Host, tt.status)
+		if err.Error() != exp {
+			t.Errorf("Expected error: %v, got: %v", exp, err)
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	t.Parallel()
+
+	sc := &SnowthClient{}
+
+	if _, err := sc.GetStats(); !errors.Is(err, ErrNoActiveNode) {
+		t.Errorf("Expected error: %v, got: %v", ErrNoActiveNode, err)
+	}
+
+	if _, err := sc.LocateMetricRemote("", "", nil); !errors.Is(err,
+		ErrNoActiveNode) {
+		t.Errorf("Expected error: %v, got: %v", ErrNoActiveNode, err)
+	}
+
+	u, err := url.Parse("http://localhost:8112")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &SnowthNode{url: u}
+
+	if _, err := sc.GetCAQLQuery(nil, node); !errors.Is(err,
+		ErrInvalidQuery) {
+		t.Errorf("Expected error: %v, got: %v", ErrInvalidQuery, err)
+	}
+
+	_, err = sc.PromQLInstantQuery(&PromQLInstantQuery{
+		Query:     "test",
+		AccountID: "x",
+	}, node)
+	if !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected error: %v, got: %v", ErrInvalidQuery, err)
+	}
+
+	cause := errors.New("cause")
+
+	err = invalidQueryf("invalid test query: %w", cause)
+	if !errors.Is(err, ErrInvalidQuery) || !errors.Is(err, cause) {
+		t.Errorf("Expected error to match ErrInvalidQuery and cause")
+	}
+
+	if err.Error() != "invalid test query: cause" {
+		t.Errorf("Expected error message: invalid test query: cause, got: %v",
+			err)
+	}
+
+	if errors.Is(&PromQLError{ErrorType: "database"}, ErrInvalidQuery) {
+		t.Error("Expected database error not to match ErrInvalidQuery")
+	}
+}
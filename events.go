@@ -0,0 +1,326 @@
+package gosnowth
+
+import (
+	"sync"
+	"time"
+)
+
+// eventQueueSize is the maximum number of events queued for a subscriber.
+// When the queue is full, the oldest queued event is dropped.
+const eventQueueSize = 1024
+
+// EventType values identify the kind of cluster change described by an Event.
+type EventType int
+
+// EventType values emitted by SnowthClient values.
+const (
+	// EventNodeAdded indicates a node has become known to the client.
+	EventNodeAdded EventType = iota + 1
+
+	// EventNodeActivated indicates a node has been moved to the active list.
+	EventNodeActivated
+
+	// EventNodeDeactivated indicates a node has been moved to the inactive
+	// list. The Reason field of the event explains why.
+	EventNodeDeactivated
+
+	// EventTopologyChanged indicates the current cluster topology hash has
+	// changed. The OldTopology and NewTopology fields contain the hashes.
+	EventTopologyChanged
+
+	// EventNodeVersionChanged indicates the IRONdb version of a node has
+	// changed. The OldVersion and NewVersion fields contain the versions.
+	EventNodeVersionChanged
+
+	// EventNodeRemoved indicates a node is no longer known to the client,
+	// because it was found in DNS and is no longer returned.
+	EventNodeRemoved
+)
+
+// String returns the name of the event type.
+func (et EventType) String() string {
+	switch et {
+	case EventNodeAdded:
+		return "NodeAdded"
+	case EventNodeActivated:
+		return "NodeActivated"
+	case EventNodeDeactivated:
+		return "NodeDeactivated"
+	case EventTopologyChanged:
+		return "TopologyChanged"
+	case EventNodeVersionChanged:
+		return "NodeVersionChanged"
+	case EventNodeRemoved:
+		return "NodeRemoved"
+	}
+
+	return "unknown"
+}
+
+// DeactivationReason values explain why a node was deactivated.
+type DeactivationReason string
+
+// DeactivationReason values used in EventNodeDeactivated events.
+const (
+	// ReasonRequested indicates DeactivateNodes was called by the user of
+	// the client.
+	ReasonRequested DeactivationReason = "requested"
+
+	// ReasonHealthCheck indicates the node failed a periodic health check.
+	ReasonHealthCheck DeactivationReason = "health check failed"
+)
+
+// Event values describe a change to the cluster state known by a SnowthClient.
+type Event struct {
+	// Type is the kind of change.
+	Type EventType
+
+	// Time is the time the change occurred.
+	Time time.Time
+
+	// Node is the node which changed. It is nil for EventTopologyChanged
+	// events not caused by a specific node.
+	Node *SnowthNode
+
+	// Reason explains why a node was deactivated. It is only set for
+	// EventNodeDeactivated events.
+	Reason DeactivationReason
+
+	// OldTopology and NewTopology contain the previous and new topology
+	// hashes. They are only set for EventTopologyChanged events.
+	OldTopology string
+	NewTopology string
+
+	// OldVersion and NewVersion contain the previous and new IRONdb
+	// versions of the node. They are only set for EventNodeVersionChanged
+	// events.
+	OldVersion string
+	NewVersion string
+}
+
+// subscription values deliver events to a single subscriber in order, using
+// a bounded queue so that emitting events never blocks.
+type subscription struct {
+	sync.Mutex
+	queue   []Event
+	notify  chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	deliver func(ev Event, done <-chan struct{})
+}
+
+// push adds an event to the subscription queue. It reports whether the
+// oldest queued event was dropped to make room for it.
+func (s *subscription) push(ev Event) bool {
+	s.Lock()
+
+	dropped := len(s.queue) >= eventQueueSize
+	if dropped {
+		s.queue = s.queue[1:]
+	}
+
+	s.queue = append(s.queue, ev)
+	s.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	return dropped
+}
+
+// cancel stops delivery of events to the subscriber.
+func (s *subscription) cancel() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// run delivers queued events until the subscription is cancelled or the
+// client is closed.
+func (s *subscription) run(closeCh <-chan struct{}) {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-closeCh:
+			return
+		case <-s.notify:
+		}
+
+		for {
+			s.Lock()
+
+			if len(s.queue) == 0 {
+				s.Unlock()
+
+				break
+			}
+
+			ev := s.queue[0]
+			s.queue = s.queue[1:]
+			s.Unlock()
+
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+
+			s.deliver(ev, s.done)
+		}
+	}
+}
+
+// Subscribe registers a callback function which will be called with an Event
+// for each change to the cluster state known by the client. Events are
+// delivered in order from a separate goroutine, so a slow callback delays
+// later events but never blocks requests. If more events are queued than the
+// subscriber can keep up with, the oldest are dropped and counted by
+// DroppedEvents. Subscribe returns a function which cancels the subscription.
+// Subscriptions end when the client is closed.
+func (sc *SnowthClient) Subscribe(f func(ev Event)) func() {
+	return sc.subscribe(func(ev Event, done <-chan struct{}) {
+		f(ev)
+	})
+}
+
+// SubscribeChan registers a channel which will receive an Event for each
+// change to the cluster state known by the client. Events are delivered in
+// order, and are queued while the channel is not ready to receive them, up to
+// a limit after which the oldest are dropped and counted by DroppedEvents.
+// SubscribeChan returns a function which cancels the subscription. The
+// channel is not closed when the subscription ends.
+func (sc *SnowthClient) SubscribeChan(ch chan<- Event) func() {
+	return sc.subscribe(func(ev Event, done <-chan struct{}) {
+		select {
+		case ch <- ev:
+		case <-done:
+		}
+	})
+}
+
+// subscribe registers a subscription which delivers events using the
+// provided function.
+func (sc *SnowthClient) subscribe(
+	deliver func(ev Event, done <-chan struct{}),
+) func() {
+	s := &subscription{
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		deliver: deliver,
+	}
+
+	closeCh := sc.closing()
+
+	if !sc.startBackground() {
+		s.cancel()
+
+		return s.cancel
+	}
+
+	sc.Lock()
+	sc.subscriptions = append(sc.subscriptions, s)
+	sc.Unlock()
+
+	go func() {
+		defer sc.endBackground()
+		defer sc.unsubscribe(s)
+
+		s.run(closeCh)
+	}()
+
+	return s.cancel
+}
+
+// unsubscribe removes a subscription from the client.
+func (sc *SnowthClient) unsubscribe(s *subscription) {
+	s.cancel()
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	subs := make([]*subscription, 0, len(sc.subscriptions))
+
+	for _, v := range sc.subscriptions {
+		if v != s {
+			subs = append(subs, v)
+		}
+	}
+
+	sc.subscriptions = subs
+}
+
+// emit sends events to all subscribers. It must not be called while holding
+// the client lock.
+func (sc *SnowthClient) emit(events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	sc.RLock()
+	subs := append([]*subscription{}, sc.subscriptions...)
+	sc.RUnlock()
+
+	now := time.Now()
+	dropped := uint64(0)
+
+	for _, ev := range events {
+		if ev.Time.IsZero() {
+			ev.Time = now
+		}
+
+		for _, s := range subs {
+			if s.push(ev) {
+				dropped++
+			}
+		}
+	}
+
+	if dropped > 0 {
+		sc.Lock()
+		sc.droppedEvents += dropped
+		sc.Unlock()
+
+		sc.LogWarnf("gosnowth dropped %d events queued for slow subscribers",
+			dropped)
+	}
+}
+
+// DroppedEvents returns the number of events which were dropped because a
+// subscriber did not receive them quickly enough.
+func (sc *SnowthClient) DroppedEvents() uint64 {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	return sc.droppedEvents
+}
+
+// nodeEvents returns events of a type for each of the provided nodes.
+func nodeEvents(et EventType, reason DeactivationReason,
+	nodes []*SnowthNode,
+) []Event {
+	events := make([]Event, 0, len(nodes))
+
+	for _, n := range nodes {
+		events = append(events, Event{Type: et, Node: n, Reason: reason})
+	}
+
+	return events
+}
+
+// topologyEvent returns a topology changed event, or nil if the topology has
+// not changed.
+func topologyEvent(node *SnowthNode, oldHash, newHash string) []Event {
+	if oldHash == newHash || newHash == "" {
+		return nil
+	}
+
+	return []Event{{
+		Type:        EventTopologyChanged,
+		Node:        node,
+		OldTopology: oldHash,
+		NewTopology: newHash,
+	}}
+}
@@ -0,0 +1,241 @@
+package gosnowth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func receiveEvent(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+
+	select {
+	case ev := <-ch:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("Expected event")
+	}
+
+	return Event{}
+}
+
+func TestSubscribeNodeEvents(t *testing.T) {
+	t.Parallel()
+
+	sc := &SnowthClient{}
+	nodes := testSelectorNodes(t, 2)
+
+	ch := make(chan Event)
+	cancel := sc.SubscribeChan(ch)
+
+	var mu sync.Mutex
+
+	received := []EventType{}
+
+	cancelFunc := sc.Subscribe(func(ev Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		received = append(received, ev.Type)
+	})
+
+	sc.AddNodes(nodes...)
+	sc.ActivateNodes(nodes[0])
+	sc.ActivateNodes(nodes[0])
+	sc.DeactivateNodes(nodes...)
+	sc.ActivateNodes(nodes[1])
+	sc.recordNodeOutcome(nodes[1], false)
+
+	for i, exp := range []Event{
+		{Type: EventNodeAdded, Node: nodes[0]},
+		{Type: EventNodeAdded, Node: nodes[1]},
+		{Type: EventNodeActivated, Node: nodes[0]},
+		{Type: EventNodeDeactivated, Node: nodes[0], Reason: ReasonRequested},
+		{Type: EventNodeActivated, Node: nodes[1]},
+	} {
+		ev := receiveEvent(t, ch)
+
+		if ev.Type != exp.Type || ev.Node != exp.Node ||
+			ev.Reason != exp.Reason {
+			t.Errorf("Expected event %d: %v %v %v, got: %v %v %v", i,
+				exp.Type, exp.Node.identifier, exp.Reason, ev.Type,
+				ev.Node.identifier, ev.Reason)
+		}
+
+		if ev.Time.IsZero() {
+			t.Errorf("Expected event time")
+		}
+	}
+
+	cancel()
+
+	sc.deactivateNodes(ReasonHealthCheck, nodes[1])
+
+	select {
+	case ev := <-ch:
+		t.Errorf("Expected no event after cancel, got: %v", ev.Type)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+
+	if len(received) != 6 || received[5] != EventNodeDeactivated {
+		t.Errorf("Expected callback events: 6, got: %v", received)
+	}
+
+	mu.Unlock()
+
+	cancelFunc()
+
+	if err := sc.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sc.subscriptions) != 0 {
+		t.Errorf("Expected no subscriptions after close, got: %v",
+			len(sc.subscriptions))
+	}
+
+	if EventNodeVersionChanged.String() != "NodeVersionChanged" {
+		t.Errorf("Expected event name: NodeVersionChanged, got: %v",
+			EventNodeVersionChanged)
+	}
+
+	if EventNodeRemoved.String() != "NodeRemoved" {
+		t.Errorf("Expected event name: NodeRemoved, got: %v",
+			EventNodeRemoved)
+	}
+}
+
+func TestSubscribeClusterEvents(t *testing.T) {
+	t.Parallel()
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		switch r.RequestURI {
+		case "/stats.json":
+			_, _ = w.Write([]byte(statsTestData))
+		case "/gossip/json":
+			_, _ = w.Write([]byte(gossipTestData))
+		default:
+			w.Header().Set("X-Topo-0", "abc123")
+			_, _ = w.Write([]byte(stateTestData))
+		}
+	}))
+
+	defer ms.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	defer func() {
+		_ = sc.Close(context.Background())
+	}()
+
+	ch := make(chan Event, 10)
+	sc.SubscribeChan(ch)
+
+	oldTopo := sc.currentTopology
+
+	u, err := url.Parse(ms.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	node := &SnowthNode{url: u}
+
+	if _, err := sc.GetNodeState(node); err != nil {
+		t.Fatal(err)
+	}
+
+	ev := receiveEvent(t, ch)
+	if ev.Type != EventTopologyChanged || ev.OldTopology != oldTopo ||
+		ev.NewTopology != "abc123" || ev.Node != node {
+		t.Errorf("Expected topology change from %v to abc123, got: %+v",
+			oldTopo, ev)
+	}
+
+	if _, err := sc.GetNodeState(node); err != nil {
+		t.Fatal(err)
+	}
+
+	node = &SnowthNode{
+		url:        u,
+		identifier: "bb6f7162-4828-11df-bab8-6bac200dcc2a",
+		semVer:     "0.1.1500000000",
+	}
+
+	sc.DeactivateNodes(node)
+
+	if ev := receiveEvent(t, ch); ev.Type != EventNodeDeactivated {
+		t.Errorf("Expected event: NodeDeactivated, got: %v", ev.Type)
+	}
+
+	sc.isNodeActive(context.Background(), node)
+
+	ev = receiveEvent(t, ch)
+	if ev.Type != EventNodeVersionChanged || ev.OldVersion !=
+		"0.1.1500000000" || ev.NewVersion != "0.1.1570000000" {
+		t.Errorf("Expected version change, got: %+v", ev)
+	}
+}
+
+func TestSubscribeDroppedEvents(t *testing.T) {
+	t.Parallel()
+
+	sc := &SnowthClient{}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ch := make(chan Event, eventQueueSize+1)
+
+	cancel := sc.Subscribe(func(ev Event) {
+		if ev.NewTopology == "first" {
+			close(started)
+			<-release
+		}
+
+		ch <- ev
+	})
+
+	defer cancel()
+
+	sc.emit(Event{Type: EventTopologyChanged, NewTopology: "first"})
+
+	<-started
+
+	// The oldest events are dropped while the subscriber is blocked.
+	for i := 0; i < eventQueueSize+5; i++ {
+		sc.emit(Event{
+			Type:        EventTopologyChanged,
+			NewTopology: strconv.Itoa(i),
+		})
+	}
+
+	if n := sc.DroppedEvents(); n != 5 {
+		t.Errorf("Expected dropped events: 5, got: %v", n)
+	}
+
+	close(release)
+
+	if ev := receiveEvent(t, ch); ev.NewTopology != "first" {
+		t.Errorf("Expected event: first, got: %v", ev.NewTopology)
+	}
+
+	for i := 5; i < eventQueueSize+5; i++ {
+		if ev := receiveEvent(t, ch); ev.NewTopology != strconv.Itoa(i) {
+			t.Fatalf("Expected event: %d, got: %v", i, ev.NewTopology)
+		}
+	}
+}
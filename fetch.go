@@ -54,7 +54,7 @@ func (fq *FetchQuery) MarshalJSON() ([]byte, error) {
 
 	fv, err := strconv.ParseFloat(formatTimestamp(fq.Start), 64)
 	if err != nil {
-		return nil, fmt.Errorf("invalid fetch start value: " +
+		return nil, invalidQueryf("invalid fetch start value: %s",
 			formatTimestamp(fq.Start))
 	}
 
@@ -134,26 +134,29 @@ func (fq *FetchQuery) Timestamp() string {
 
 // FetchValues retrieves data values using the IRONdb fetch API.
 func (sc *SnowthClient) FetchValues(q *FetchQuery, nodes ...*SnowthNode) (*DF4Response, error) {
-	return sc.FetchValuesContext(context.Background(), q, nodes...)
+	return sc.FetchValuesContext(context.Background(), q, nodeOptions(nodes)...)
 }
 
 // FetchValuesContext is the context aware version of FetchValues.
 func (sc *SnowthClient) FetchValuesContext(ctx context.Context,
-	q *FetchQuery, nodes ...*SnowthNode,
+	q *FetchQuery, opts ...CallOption,
 ) (*DF4Response, error) {
-	var node *SnowthNode
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	var owners []string
 
 	switch {
-	case len(nodes) > 0 && nodes[0] != nil:
-		node = nodes[0]
+	case node != nil:
 	case len(q.Streams) > 0:
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(q.Streams[0].UUID, q.Streams[0].Name))
+		owners = sc.FindMetricNodeIDs(q.Streams[0].UUID, q.Streams[0].Name)
+		node = sc.GetActiveNode(owners)
 	default:
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	buf := &bytes.Buffer{}
@@ -163,7 +166,8 @@ func (sc *SnowthClient) FetchValuesContext(ctx context.Context,
 
 	hdrs := http.Header{"Content-Type": {"application/json"}}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "POST", "/fetch", buf, hdrs)
+	body, _, err := sc.hedgedRequestContext(ctx, node, owners, "POST",
+		"/fetch", buf, hdrs)
 	if err != nil {
 		return nil, err
 	}
@@ -198,8 +202,15 @@ func (sc *SnowthClient) FetchValuesFb(node *SnowthNode,
 
 // FetchValuesFbContext is the context aware version of FetchValuesFb.
 func (sc *SnowthClient) FetchValuesFbContext(ctx context.Context,
-	node *SnowthNode, q *fetch.FetchT,
+	node *SnowthNode, q *fetch.FetchT, opts ...CallOption,
 ) (*fetch.DF4T, error) {
+	ctx, n, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if n != nil {
+		node = n
+	}
+
 	builder := flatbuffers.NewBuilder(8192)
 	qOffset := fetch.FetchPack(builder, q)
 	builder.Finish(qOffset)
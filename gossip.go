@@ -29,22 +29,22 @@ type GossipLatency map[string]string
 // the identifier of the node, the node's gossip_time, gossip_age, as well
 // as topology state, current and next topology.
 func (sc *SnowthClient) GetGossipInfo(nodes ...*SnowthNode) (*Gossip, error) {
-	return sc.GetGossipInfoContext(context.Background(), nodes...)
+	return sc.GetGossipInfoContext(context.Background(), nodeOptions(nodes)...)
 }
 
 // GetGossipInfoContext is the context aware version of GetGossipInfo.
 func (sc *SnowthClient) GetGossipInfoContext(ctx context.Context,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*Gossip, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	r := &Gossip{}
@@ -47,24 +47,24 @@ func (sc *SnowthClient) GraphiteFindMetrics(accountID int64,
 	nodes ...*SnowthNode,
 ) ([]GraphiteMetric, error) {
 	return sc.GraphiteFindMetricsContext(context.Background(), accountID,
-		prefix, query, options, nodes...)
+		prefix, query, options, nodeOptions(nodes)...)
 }
 
 // GraphiteFindMetricsContext is the context aware version of
 // GraphiteFindMetrics.
 func (sc *SnowthClient) GraphiteFindMetricsContext(ctx context.Context,
 	accountID int64, prefix, query string, options *GraphiteOptions,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) ([]GraphiteMetric, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	u := fmt.Sprintf("%s?query=%s",
@@ -97,24 +97,24 @@ func (sc *SnowthClient) GraphiteFindTags(accountID int64,
 	nodes ...*SnowthNode,
 ) ([]GraphiteMetric, error) {
 	return sc.GraphiteFindTagsContext(context.Background(), accountID,
-		prefix, query, options, nodes...)
+		prefix, query, options, nodeOptions(nodes)...)
 }
 
 // GraphiteFindTagsContext is the context aware version of
 // GraphiteFindTags.
 func (sc *SnowthClient) GraphiteFindTagsContext(ctx context.Context,
 	accountID int64, prefix, query string, options *GraphiteOptions,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) ([]GraphiteMetric, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	u := fmt.Sprintf("%s?query=%s",
@@ -147,7 +147,7 @@ func (sc *SnowthClient) GraphiteGetDatapoints(accountID int64,
 	nodes ...*SnowthNode,
 ) (*GraphiteDatapoints, error) {
 	return sc.GraphiteGetDatapointsContext(context.Background(), accountID,
-		prefix, lookup, options, nodes...)
+		prefix, lookup, options, nodeOptions(nodes)...)
 }
 
 // GraphiteGetDatapointsContext is the context aware version of
@@ -155,17 +155,17 @@ func (sc *SnowthClient) GraphiteGetDatapoints(accountID int64,
 func (sc *SnowthClient) GraphiteGetDatapointsContext(ctx context.Context,
 	accountID int64, prefix string, lookup *GraphiteLookup,
 	options *GraphiteOptions,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*GraphiteDatapoints, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	u := fmt.Sprintf("/graphite/%d/%s/series_multi",
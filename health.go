@@ -0,0 +1,233 @@
+package gosnowth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// HealthCheck values identify the requests used to determine whether a node
+// is healthy.
+type HealthCheck string
+
+// HealthCheck values used in health policies.
+const (
+	// HealthCheckStats requires the node to respond to a stats request.
+	HealthCheckStats HealthCheck = "stats"
+
+	// HealthCheckGossip requires the node to respond to a gossip request,
+	// and to report its own gossip age within the MaxGossipAge limit.
+	HealthCheckGossip HealthCheck = "gossip"
+
+	// HealthCheckState requires the node to respond to a state request.
+	HealthCheckState HealthCheck = "state"
+)
+
+// HealthPolicy values represent the rules used by WatchAndUpdate() to decide
+// when nodes are moved between the active and inactive lists.
+type HealthPolicy struct {
+	// MaxGossipAge is the maximum gossip age, in seconds, a node may report
+	// for itself before it is considered unhealthy. If zero or less, a
+	// maximum age of 10 seconds is used.
+	MaxGossipAge float64 `json:"max_gossip_age,omitempty"`
+
+	// Checks contains the checks a node must pass to be considered healthy.
+	// If empty, only the gossip check is used. The stats of a node are
+	// always retrieved when its identity or version is unknown, or when it
+	// is inactive, and failing to retrieve them fails the health check.
+	Checks []HealthCheck `json:"checks,omitempty"`
+
+	// FailureThreshold is the number of consecutive failed health checks
+	// needed to move an active node to the inactive list.
+	FailureThreshold int64 `json:"failure_threshold,omitempty"`
+
+	// SuccessThreshold is the number of consecutive successful health
+	// checks needed to move an inactive node to the active list.
+	SuccessThreshold int64 `json:"success_threshold,omitempty"`
+
+	// Concurrency is the maximum number of nodes checked at the same time.
+	// If zero or less, all nodes are checked at the same time.
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// NewHealthPolicy creates and initializes a new health policy value using
+// default values.
+func NewHealthPolicy() *HealthPolicy {
+	return &HealthPolicy{
+		MaxGossipAge:     10.0,
+		Checks:           []HealthCheck{HealthCheckGossip},
+		FailureThreshold: 1,
+		SuccessThreshold: 1,
+		Concurrency:      32,
+	}
+}
+
+// requires reports whether the policy requires a check.
+func (hp *HealthPolicy) requires(check HealthCheck) bool {
+	if len(hp.Checks) == 0 {
+		return check == HealthCheckGossip
+	}
+
+	for _, c := range hp.Checks {
+		if c == check {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nodeHealth values contain the consecutive health check results for a node.
+type nodeHealth struct {
+	failures  int64
+	successes int64
+}
+
+// HealthPolicy returns the health policy used by the client. If no policy
+// has been set, the default policy is returned.
+func (sc *SnowthClient) HealthPolicy() *HealthPolicy {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	if sc.healthPolicy == nil {
+		return NewHealthPolicy()
+	}
+
+	return sc.healthPolicy
+}
+
+// SetHealthPolicy sets the health policy used by the client. A nil value
+// restores the default policy.
+func (sc *SnowthClient) SetHealthPolicy(hp *HealthPolicy) {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.healthPolicy = hp
+}
+
+// recordNodeHealth records the result of a health check for a node and
+// reports whether the node has reached the threshold needed to change state.
+// Active nodes change state after consecutive failures, and inactive nodes
+// change state after consecutive successes.
+func (sc *SnowthClient) recordNodeHealth(node *SnowthNode, active,
+	healthy bool,
+) bool {
+	hp := sc.HealthPolicy()
+	key := node.GetURL().String()
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.health == nil {
+		sc.health = map[string]*nodeHealth{}
+	}
+
+	nh := sc.health[key]
+	if nh == nil {
+		nh = &nodeHealth{}
+		sc.health[key] = nh
+	}
+
+	if healthy {
+		nh.failures = 0
+		nh.successes++
+	} else {
+		nh.successes = 0
+		nh.failures++
+	}
+
+	if active {
+		if healthy || nh.failures < maxInt64(hp.FailureThreshold, 1) {
+			return false
+		}
+	} else if !healthy || nh.successes < maxInt64(hp.SuccessThreshold, 1) {
+		return false
+	}
+
+	nh.failures, nh.successes = 0, 0
+
+	return true
+}
+
+// updateNodeHealth checks the health of all known nodes, using up to the
+// number of concurrent checks permitted by the health policy, and moves nodes
+// between the active and inactive lists as required. Each check is limited
+// by the provided timeout.
+func (sc *SnowthClient) updateNodeHealth(ctx context.Context,
+	timeout time.Duration,
+) {
+	type probe struct {
+		node   *SnowthNode
+		active bool
+	}
+
+	probes := []probe{}
+
+	for _, node := range sc.ListInactiveNodes() {
+		probes = append(probes, probe{node: node})
+	}
+
+	for _, node := range sc.ListActiveNodes() {
+		probes = append(probes, probe{node: node, active: true})
+	}
+
+	limit := sc.HealthPolicy().Concurrency
+	if limit <= 0 || limit > len(probes) {
+		limit = len(probes)
+	}
+
+	sem := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+
+	for _, p := range probes {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+
+			return
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+
+		go func(p probe) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			pCtx, pCancel := context.WithTimeout(ctx, timeout)
+			healthy := sc.isNodeActive(pCtx, p.node)
+
+			pCancel()
+
+			if ctx.Err() != nil ||
+				!sc.recordNodeHealth(p.node, p.active, healthy) {
+				return
+			}
+
+			if p.active {
+				sc.LogWarnf("moving snowth node to inactive list: %s",
+					p.node.GetURL().Host)
+
+				sc.deactivateNodes(ReasonHealthCheck, p.node)
+
+				return
+			}
+
+			sc.LogDebugf("moving snowth node to active list: %s",
+				p.node.GetURL().Host)
+
+			sc.ActivateNodes(p.node)
+		}(p)
+	}
+
+	wg.Wait()
+}
+
+// maxInt64 returns the larger of two int64 values.
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,214 @@
+package gosnowth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthPolicy(t *testing.T) {
+	t.Parallel()
+
+	var failState, stateCount int32
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		switch r.RequestURI {
+		case "/stats.json":
+			_, _ = w.Write([]byte(statsTestData))
+		case "/gossip/json":
+			_, _ = w.Write([]byte(gossipTestData))
+		case "/state":
+			atomic.AddInt32(&stateCount, 1)
+
+			if atomic.LoadInt32(&failState) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+
+				return
+			}
+
+			_, _ = w.Write([]byte(stateTestData))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer ms.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	if sc.HealthPolicy().MaxGossipAge != 10.0 {
+		t.Errorf("Expected default max gossip age: 10, got: %v",
+			sc.HealthPolicy().MaxGossipAge)
+	}
+
+	sc.SetHealthPolicy(&HealthPolicy{
+		Checks:           []HealthCheck{HealthCheckStats, HealthCheckState},
+		FailureThreshold: 2,
+		SuccessThreshold: 2,
+	})
+
+	u, err := url.Parse(ms.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	node := &SnowthNode{url: u}
+
+	sc.AddNodes(node)
+	sc.ActivateNodes(node)
+
+	ctx := context.Background()
+
+	if !sc.isNodeActive(ctx, node) {
+		t.Fatal("Expected node to be healthy")
+	}
+
+	if atomic.LoadInt32(&stateCount) != 1 {
+		t.Errorf("Expected state requests: 1, got: %v", stateCount)
+	}
+
+	atomic.StoreInt32(&failState, 1)
+
+	sc.updateNodeHealth(ctx, time.Second)
+
+	if len(sc.ListActiveNodes()) != 1 {
+		t.Fatal("Expected node to remain active after one failure")
+	}
+
+	sc.updateNodeHealth(ctx, time.Second)
+
+	if len(sc.ListInactiveNodes()) != 1 {
+		t.Fatal("Expected node to be inactive after two failures")
+	}
+
+	atomic.StoreInt32(&failState, 0)
+
+	sc.updateNodeHealth(ctx, time.Second)
+
+	if len(sc.ListInactiveNodes()) != 1 {
+		t.Fatal("Expected node to remain inactive after one success")
+	}
+
+	sc.updateNodeHealth(ctx, time.Second)
+
+	if len(sc.ListActiveNodes()) != 1 {
+		t.Fatal("Expected node to be active after two successes")
+	}
+
+	sc.SetHealthPolicy(&HealthPolicy{MaxGossipAge: -1})
+
+	if !sc.isNodeActive(ctx, node) {
+		t.Error("Expected node to be healthy using default gossip age")
+	}
+}
+
+func TestHealthParallel(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, maxInFlight int32
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		switch r.RequestURI {
+		case "/stats.json":
+			_, _ = w.Write([]byte(statsTestData))
+		case "/gossip/json":
+			n := atomic.AddInt32(&inFlight, 1)
+
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+
+			time.Sleep(100 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+
+			_, _ = w.Write([]byte(gossipTestData))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer ms.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	sc.SetHealthPolicy(&HealthPolicy{Concurrency: 4})
+
+	u, err := url.Parse(ms.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	// Distinct node URLs for the same server are created using user names.
+	// The node versions are unknown, so that the checks update the nodes.
+	for i := 0; i < 8; i++ {
+		node := &SnowthNode{
+			url: &url.URL{
+				Scheme: u.Scheme,
+				Host:   u.Host,
+				User:   url.User(string(rune('a' + i))),
+			},
+			identifier: "bb6f7162-4828-11df-bab8-6bac200dcc2a",
+		}
+
+		sc.AddNodes(node)
+		sc.ActivateNodes(node)
+	}
+
+	// Requests choose nodes while the checks update them.
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ids := []string{"bb6f7162-4828-11df-bab8-6bac200dcc2a"}
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+				_ = sc.GetActiveNode(ids).SemVer()
+			}
+		}
+	}()
+
+	start := time.Now()
+
+	sc.updateNodeHealth(context.Background(), time.Second)
+
+	close(done)
+	<-stopped
+
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("Expected parallel health checks, took: %v", d)
+	}
+
+	if m := atomic.LoadInt32(&maxInFlight); m > 4 || m < 2 {
+		t.Errorf("Expected concurrent health checks: 4, got: %v", m)
+	}
+
+	if len(sc.ListActiveNodes()) != 9 {
+		t.Errorf("Expected active nodes: 9, got: %v",
+			len(sc.ListActiveNodes()))
+	}
+}
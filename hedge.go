@@ -0,0 +1,216 @@
+package gosnowth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// hedgeSampleSize is the number of request latency samples kept to compute
+// the hedging delay percentile, and hedgeMinSamples is the number of samples
+// needed before the percentile is used.
+const (
+	hedgeSampleSize = 128
+	hedgeMinSamples = 16
+)
+
+// HedgeConfig values represent the configuration of hedged reads. When
+// hedging is enabled, a read of metric data which has not completed after the
+// hedging delay is also sent to a second node owning a copy of the metric.
+// The first successful response is used and the other request is cancelled.
+// Hedging is disabled if neither Delay nor Percentile is greater than zero.
+type HedgeConfig struct {
+	// Delay is the time to wait for a response before sending the hedged
+	// request. It is also used when Percentile is set, until enough
+	// requests have been measured. If it is not greater than zero, reads
+	// are not hedged until then.
+	Delay time.Duration `json:"delay,omitempty"`
+
+	// Percentile, if greater than zero, sets the hedging delay to this
+	// percentile, from 0 to 1, of recently measured read latencies.
+	Percentile float64 `json:"percentile,omitempty"`
+}
+
+// hedger values track read latencies and determine the hedging delay.
+type hedger struct {
+	sync.Mutex
+	cfg     HedgeConfig
+	samples []time.Duration
+	next    int
+}
+
+// newHedger creates a new hedger from a hedging configuration. It returns nil
+// if the configuration does not enable hedging.
+func newHedger(cfg *HedgeConfig) *hedger {
+	if cfg == nil || (cfg.Delay <= 0 && cfg.Percentile <= 0) {
+		return nil
+	}
+
+	return &hedger{cfg: *cfg}
+}
+
+// delay returns the time to wait before sending a hedged request, and
+// whether a hedged request should be sent.
+func (h *hedger) delay() (time.Duration, bool) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.cfg.Percentile <= 0 || len(h.samples) < hedgeMinSamples {
+		return h.cfg.Delay, h.cfg.Delay > 0
+	}
+
+	p := h.cfg.Percentile
+	if p > 1 {
+		p = 1
+	}
+
+	s := append([]time.Duration{}, h.samples...)
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+
+	idx := int(p*float64(len(s))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+
+	if idx >= len(s) {
+		idx = len(s) - 1
+	}
+
+	return s[idx], true
+}
+
+// observe records the latency of a completed read.
+func (h *hedger) observe(d time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+
+	if len(h.samples) < hedgeSampleSize {
+		h.samples = append(h.samples, d)
+
+		return
+	}
+
+	h.samples[h.next] = d
+	h.next = (h.next + 1) % hedgeSampleSize
+}
+
+// hedgeResult values contain the result of a request sent by a hedged read.
+type hedgeResult struct {
+	body io.Reader
+	hdr  http.Header
+	err  error
+}
+
+// hedgedRequestContext sends a read request to a node. If hedging is enabled
+// and the metric being read has other active owners, the request is also sent
+// to another owner if no response is received within the hedging delay. The
+// owners argument should be nil when the caller requested a specific node.
+func (sc *SnowthClient) hedgedRequestContext(ctx context.Context,
+	node *SnowthNode, owners []string, method, url string, body io.Reader,
+	headers http.Header,
+) (io.Reader, http.Header, error) {
+	sc.RLock()
+	h := sc.hedger
+	sc.RUnlock()
+
+	if h == nil || len(owners) == 0 {
+		return sc.DoRequestContext(ctx, node, method, url, body, headers)
+	}
+
+	alts := []*SnowthNode{}
+
+	for _, n := range sc.activeOwners(owners) {
+		if n.GetURL().String() != node.GetURL().String() {
+			alts = append(alts, n)
+		}
+	}
+
+	alt := sc.selectNode(alts)
+	if alt == nil {
+		return sc.DoRequestContext(ctx, node, method, url, body, headers)
+	}
+
+	start := time.Now()
+
+	delay, ok := h.delay()
+	if !ok {
+		// Latencies are still measured until the hedging delay is known.
+		b, hdr, err := sc.DoRequestContext(ctx, node, method, url, body,
+			headers)
+		if err == nil {
+			h.observe(time.Since(start))
+		}
+
+		return b, hdr, err
+	}
+
+	bBody := []byte{}
+
+	if body != nil {
+		var err error
+
+		if bBody, err = io.ReadAll(body); err != nil {
+			return nil, nil, fmt.Errorf("unable to read request body: %w", err)
+		}
+	}
+
+	hCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resCh := make(chan hedgeResult, 2)
+
+	send := func(n *SnowthNode) {
+		go func() {
+			b, hdr, err := sc.DoRequestContext(hCtx, n, method, url,
+				bytes.NewReader(bBody), headers)
+
+			resCh <- hedgeResult{body: b, hdr: hdr, err: err}
+		}()
+	}
+
+	send(node)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	timerCh := timer.C
+	pending := 1
+
+	var last hedgeResult
+
+	for pending > 0 {
+		select {
+		case <-timerCh:
+			sc.LogDebugf("gosnowth sending hedged request to %s: %s %s",
+				alt.GetURL().Host, method, url)
+
+			send(alt)
+
+			timerCh = nil
+			pending++
+		case res := <-resCh:
+			pending--
+
+			if res.err == nil {
+				h.observe(time.Since(start))
+
+				return res.body, res.hdr, nil
+			}
+
+			last = res
+
+			// The request has already been retried on other nodes if the
+			// failure allowed it, so a hedged request is not sent.
+			if timerCh != nil {
+				return last.body, last.hdr, last.err
+			}
+		}
+	}
+
+	return last.body, last.hdr, last.err
+}
@@ -0,0 +1,208 @@
+package gosnowth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgerDelay(t *testing.T) {
+	t.Parallel()
+
+	if h := newHedger(nil); h != nil {
+		t.Fatal("Expected nil hedger for nil configuration")
+	}
+
+	if h := newHedger(&HedgeConfig{}); h != nil {
+		t.Fatal("Expected nil hedger for empty configuration")
+	}
+
+	h := newHedger(&HedgeConfig{Percentile: 0.5})
+
+	if d, ok := h.delay(); ok {
+		t.Errorf("Expected no hedging before samples, got delay: %v", d)
+	}
+
+	for i := 1; i <= hedgeMinSamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+
+	if d, ok := h.delay(); !ok || d != 8*time.Millisecond {
+		t.Errorf("Expected delay: 8ms, got: %v, %v", d, ok)
+	}
+
+	h = newHedger(&HedgeConfig{Delay: 50 * time.Millisecond, Percentile: 0.9})
+
+	for i := 1; i < hedgeMinSamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+
+	if d, _ := h.delay(); d != 50*time.Millisecond {
+		t.Errorf("Expected delay: 50ms, got: %v", d)
+	}
+
+	for i := hedgeMinSamples; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+
+	if d, _ := h.delay(); d != 90*time.Millisecond {
+		t.Errorf("Expected delay: 90ms, got: %v", d)
+	}
+
+	for i := 0; i < hedgeSampleSize; i++ {
+		h.observe(time.Millisecond)
+	}
+
+	if d, _ := h.delay(); d != time.Millisecond {
+		t.Errorf("Expected delay: 1ms, got: %v", d)
+	}
+
+	if len(h.samples) != hedgeSampleSize {
+		t.Errorf("Expected samples: %v, got: %v", hedgeSampleSize,
+			len(h.samples))
+	}
+}
+
+func TestHedgedRequest(t *testing.T) {
+	t.Parallel()
+
+	var slowCount, fastCount int32
+
+	slowDone := make(chan struct{}, 1)
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		atomic.AddInt32(&slowCount, 1)
+
+		_, _ = io.ReadAll(r.Body)
+
+		select {
+		case <-r.Context().Done():
+			slowDone <- struct{}{}
+		case <-time.After(2 * time.Second):
+			_, _ = w.Write([]byte("slow"))
+		}
+	}))
+
+	defer slow.Close()
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		atomic.AddInt32(&fastCount, 1)
+
+		b, _ := io.ReadAll(r.Body)
+		if string(b) != "query" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("fast"))
+	}))
+
+	defer fast.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{fast.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	su, err := url.Parse(slow.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	slowNode := &SnowthNode{url: su, identifier: "slow"}
+	fastNode := sc.ListActiveNodes()[0]
+	fastNode.identifier = "fast"
+
+	sc.AddNodes(slowNode)
+	sc.ActivateNodes(slowNode)
+
+	owners := []string{"slow", "fast"}
+
+	sc.SetHedgeConfig(&HedgeConfig{Delay: 20 * time.Millisecond})
+
+	start := time.Now()
+
+	body, _, err := sc.hedgedRequestContext(context.Background(), slowNode,
+		owners, "POST", "/fetch", bytes.NewBufferString("query"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "fast" {
+		t.Errorf("Expected response: fast, got: %s", b)
+	}
+
+	if time.Since(start) > time.Second {
+		t.Error("Expected hedged request to complete before the slow node")
+	}
+
+	select {
+	case <-slowDone:
+	case <-time.After(time.Second):
+		t.Error("Expected slow request to be cancelled")
+	}
+
+	// The cancelled request does not penalize the latency of the slow node.
+	deadline := time.Now().Add(time.Second)
+
+	for sc.getNodeStats(slowNode).load(slowNode).Outstanding != 0 &&
+		time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if nl := sc.getNodeStats(slowNode).load(slowNode); nl.Outstanding != 0 ||
+		nl.Latency >= latencyFailurePenalty {
+		t.Errorf("Expected unpenalized slow node latency, got: %+v", nl)
+	}
+
+	if atomic.LoadInt32(&fastCount) != 1 {
+		t.Errorf("Expected fast node requests: 1, got: %v",
+			atomic.LoadInt32(&fastCount))
+	}
+
+	sc.SetHedgeConfig(nil)
+
+	body, _, err = sc.hedgedRequestContext(context.Background(), fastNode,
+		nil, "POST", "/fetch", bytes.NewBufferString("query"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b, _ = io.ReadAll(body); string(b) != "fast" {
+		t.Errorf("Expected response: fast, got: %s", b)
+	}
+
+	if atomic.LoadInt32(&slowCount) != 1 {
+		t.Errorf("Expected slow node requests: 1, got: %v",
+			atomic.LoadInt32(&slowCount))
+	}
+}
@@ -89,24 +89,27 @@ func (sc *SnowthClient) ReadHistogramValues(
 	start, end time.Time, nodes ...*SnowthNode,
 ) ([]HistogramValue, error) {
 	return sc.ReadHistogramValuesContext(context.Background(), uuid,
-		metric, period, start, end, nodes...)
+		metric, period, start, end, nodeOptions(nodes)...)
 }
 
 // ReadHistogramValuesContext is the context aware version of
 // ReadHistogramValues.
 func (sc *SnowthClient) ReadHistogramValuesContext(ctx context.Context,
 	uuid, metric string, period time.Duration,
-	start, end time.Time, nodes ...*SnowthNode,
+	start, end time.Time, opts ...CallOption,
 ) ([]HistogramValue, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(uuid, metric))
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	var owners []string
+
+	if node == nil {
+		owners = sc.FindMetricNodeIDs(uuid, metric)
+		node = sc.GetActiveNode(owners)
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	startTS := start.Unix() - start.Unix()%int64(period.Seconds())
@@ -114,7 +117,7 @@ func (sc *SnowthClient) ReadHistogramValuesContext(ctx context.Context,
 		int64(period.Seconds())
 	r := []HistogramValue{}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "GET",
+	body, _, err := sc.hedgedRequestContext(ctx, node, owners, "GET",
 		path.Join("/histogram", strconv.FormatInt(startTS, 10),
 			strconv.FormatInt(endTS, 10),
 			strconv.FormatInt(int64(period.Seconds()), 10), uuid,
@@ -146,31 +149,35 @@ type HistogramData struct {
 func (sc *SnowthClient) WriteHistogram(data []HistogramData,
 	nodes ...*SnowthNode,
 ) error {
-	return sc.WriteHistogramContext(context.Background(), data, nodes...)
+	return sc.WriteHistogramContext(context.Background(), data, nodeOptions(nodes)...)
 }
 
 // WriteHistogramContext is the context aware version of WriteHistogram.
+// Unless a node is set by the call options, the data is split into batches by
+// the nodes owning each metric, which are sent in parallel. If any batch
+// fails, a *WriteError containing the result of each batch is returned.
 func (sc *SnowthClient) WriteHistogramContext(ctx context.Context,
-	data []HistogramData, nodes ...*SnowthNode,
+	data []HistogramData, opts ...CallOption,
 ) error {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else if len(data) > 0 {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(data[0].ID,
-			data[0].Metric))
-	}
-
-	if node == nil {
-		return fmt.Errorf("unable to get active node")
-	}
+	return sc.shardWriteContext(ctx, opts, len(data),
+		func(i int) (string, string) {
+			return data[i].ID, data[i].Metric
+		},
+		func(ctx context.Context, node *SnowthNode, idx []int) error {
+			batch := make([]HistogramData, len(idx))
+			for i, j := range idx {
+				batch[i] = data[j]
+			}
 
-	buf := new(bytes.Buffer)
-	if err := json.NewEncoder(buf).Encode(data); err != nil {
-		return fmt.Errorf("failed to encode HistogramData for write: %w", err)
-	}
+			buf := new(bytes.Buffer)
+			if err := json.NewEncoder(buf).Encode(batch); err != nil {
+				return fmt.Errorf("failed to encode HistogramData for "+
+					"write: %w", err)
+			}
 
-	_, _, err := sc.DoRequestContext(ctx, node, "POST", "/histogram/write", buf, nil)
+			_, _, err := sc.DoRequestContext(ctx, node, "POST",
+				"/histogram/write", buf, nil)
 
-	return err
+			return err
+		})
 }
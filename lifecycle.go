@@ -0,0 +1,142 @@
+package gosnowth
+
+import (
+	"context"
+	"fmt"
+)
+
+// Close stops all background work started by the client, such as node
+// discovery and WatchAndUpdate(), and waits for requests in progress to
+// complete. Once Close is called, further requests made with the client fail
+// with ErrClientClosed. If the context is cancelled or expires before all
+// requests complete, an error is returned, and the remaining requests are
+// left to complete on their own. The client spool, if any, is closed once all
+// requests complete. Calling Close more than once is safe.
+func (sc *SnowthClient) Close(ctx context.Context) error {
+	sc.Lock()
+
+	if !sc.closed {
+		sc.closed = true
+
+		if sc.closeCh == nil {
+			sc.closeCh = make(chan struct{})
+		}
+
+		close(sc.closeCh)
+	}
+
+	sc.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		sc.background.Wait()
+		sc.requests.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("unable to close snowth client: %w", ctx.Err())
+	case <-done:
+	}
+
+	sc.RLock()
+	cli := sc.c
+	sc.RUnlock()
+
+	if cic, ok := cli.(interface{ CloseIdleConnections() }); ok {
+		cic.CloseIdleConnections()
+	}
+
+	if sc.spool != nil {
+		if err := sc.spool.Close(); err != nil {
+			return fmt.Errorf("unable to close snowth client: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Closed reports whether Close has been called on the client.
+func (sc *SnowthClient) Closed() bool {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	return sc.closed
+}
+
+// closing returns a channel which is closed when the client is closed.
+func (sc *SnowthClient) closing() <-chan struct{} {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.closeCh == nil {
+		sc.closeCh = make(chan struct{})
+	}
+
+	return sc.closeCh
+}
+
+// backgroundContext returns a context derived from ctx which is also
+// cancelled when the client is closed. The returned cancel function must be
+// called when the context is no longer needed.
+func (sc *SnowthClient) backgroundContext(
+	ctx context.Context,
+) (context.Context, context.CancelFunc) {
+	bCtx, cancel := context.WithCancel(ctx)
+	closeCh := sc.closing()
+
+	go func() {
+		select {
+		case <-closeCh:
+			cancel()
+		case <-bCtx.Done():
+		}
+	}()
+
+	return bCtx, cancel
+}
+
+// startBackground registers a background goroutine owned by the client. It
+// returns false if the client is closed, in which case the goroutine must not
+// be started. Otherwise, endBackground must be called when it exits.
+func (sc *SnowthClient) startBackground() bool {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	if sc.closed {
+		return false
+	}
+
+	sc.background.Add(1)
+
+	return true
+}
+
+// endBackground records that a background goroutine owned by the client has
+// exited.
+func (sc *SnowthClient) endBackground() {
+	sc.background.Done()
+}
+
+// beginRequest registers a request in progress. It returns ErrClientClosed if
+// the client is closed. Otherwise, endRequest must be called when the request
+// completes.
+func (sc *SnowthClient) beginRequest() error {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	if sc.closed {
+		return ErrClientClosed
+	}
+
+	sc.requests.Add(1)
+
+	return nil
+}
+
+// endRequest records that a request in progress has completed.
+func (sc *SnowthClient) endRequest() {
+	sc.requests.Done()
+}
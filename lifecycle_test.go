@@ -0,0 +1,110 @@
+package gosnowth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+
+	var watchCount int32
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		switch r.RequestURI {
+		case "/stats.json":
+			_, _ = w.Write([]byte(statsTestData))
+		case "/state":
+			entered <- struct{}{}
+			<-release
+			_, _ = w.Write([]byte(stateTestData))
+		default:
+			atomic.AddInt32(&watchCount, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer ms.Close()
+
+	cfg := NewConfig(ms.URL)
+	cfg.WatchInterval = 10 * time.Millisecond
+
+	sc, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	sc.WatchAndUpdate(context.Background())
+
+	u, err := url.Parse(ms.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	node := &SnowthNode{url: u}
+
+	resCh := make(chan error, 1)
+
+	go func() {
+		_, err := sc.GetNodeState(node)
+		resCh <- err
+	}()
+
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+
+	if err := sc.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected close to wait for request, got: %v", err)
+	}
+
+	if !sc.Closed() {
+		t.Error("Expected client to be closed")
+	}
+
+	close(release)
+
+	if err := <-resCh; err != nil {
+		t.Errorf("Expected in-flight request to complete, got: %v", err)
+	}
+
+	if err := sc.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	n := atomic.LoadInt32(&watchCount)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if atomic.LoadInt32(&watchCount) != n {
+		t.Error("Expected watch to stop after close")
+	}
+
+	if _, err := sc.GetNodeState(node); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected error: %v, got: %v", ErrClientClosed, err)
+	}
+
+	if _, err := sc.GetStatsNodeContext(context.Background(),
+		node); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected error: %v, got: %v", ErrClientClosed, err)
+	}
+
+	sc.WatchAndUpdate(context.Background())
+
+	if err := sc.Close(context.Background()); err != nil {
+		t.Errorf("Expected no error closing twice, got: %v", err)
+	}
+}
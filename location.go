@@ -24,10 +24,13 @@ func (sc *SnowthClient) LocateMetric(uuid string, metric string,
 
 // LocateMetricContext is the context aware version of LocateMetric.
 func (sc *SnowthClient) LocateMetricContext(ctx context.Context, uuid string,
-	metric string, node ...*SnowthNode,
+	metric string, opts ...CallOption,
 ) ([]TopologyNode, error) {
-	if len(node) > 0 {
-		return sc.LocateMetricRemoteContext(ctx, uuid, metric, node[0])
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node != nil {
+		return sc.LocateMetricRemoteContext(ctx, uuid, metric, node)
 	}
 
 	topo, err := sc.Topology()
@@ -48,14 +51,21 @@ func (sc *SnowthClient) LocateMetricRemote(uuid string, metric string,
 
 // LocateMetricRemoteContext is the context aware version of LocateMetricRemote.
 func (sc *SnowthClient) LocateMetricRemoteContext(ctx context.Context,
-	uuid string, metric string, node *SnowthNode,
+	uuid string, metric string, node *SnowthNode, opts ...CallOption,
 ) ([]TopologyNode, error) {
 	r := &Topology{}
 
+	ctx, n, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if n != nil {
+		node = n
+	}
+
 	if node == nil {
 		nodes := sc.ListActiveNodes()
 		if len(nodes) == 0 {
-			return nil, fmt.Errorf("no active nodes")
+			return nil, ErrNoActiveNode
 		}
 
 		node = nodes[0]
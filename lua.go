@@ -130,22 +130,22 @@ func (le *LuaExtensions) UnmarshalJSON(b []byte) error { //nolint:gocyclo
 func (sc *SnowthClient) GetLuaExtensions(nodes ...*SnowthNode) (LuaExtensions,
 	error,
 ) {
-	return sc.GetLuaExtensionsContext(context.Background(), nodes...)
+	return sc.GetLuaExtensionsContext(context.Background(), nodeOptions(nodes)...)
 }
 
 // GetLuaExtensionsContext is the context aware version of GetLuaExtensions.
 func (sc *SnowthClient) GetLuaExtensionsContext(ctx context.Context,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (LuaExtensions, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	u := "/extension/lua"
@@ -175,23 +175,23 @@ func (sc *SnowthClient) ExecLuaExtension(name string,
 	params []ExtParam, nodes ...*SnowthNode,
 ) (map[string]interface{}, error) {
 	return sc.ExecLuaExtensionContext(context.Background(), name,
-		params, nodes...)
+		params, nodeOptions(nodes)...)
 }
 
 // ExecLuaExtensionContext is the context aware version of ExecLuaExtension.
 func (sc *SnowthClient) ExecLuaExtensionContext(ctx context.Context,
 	name string, params []ExtParam,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (map[string]interface{}, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	u := "/extension/lua/" + name
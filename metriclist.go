@@ -0,0 +1,428 @@
+package gosnowth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/gosnowth/fb/noit"
+	"github.com/google/uuid"
+	"github.com/openhistogram/circonusllhist"
+)
+
+// MetricListBuilder values build raw metric lists, for use with
+// WriteRawMetricList, from Go values. The records added share an account ID,
+// check UUID and check name. Each value is recorded for the metric name and
+// timestamp most recently set with Metric and Timestamp. Errors are recorded
+// by the builder, and the first is returned by Build.
+//
+//	ml, err := gosnowth.NewMetricListBuilder(1, checkUUID).
+//		Metric("requests", gosnowth.Tag{Category: "host", Value: "a"}).
+//		Timestamp(ms).Double(12.5).
+//		Metric("status").String("ok").
+//		Build()
+type MetricListBuilder struct {
+	accountID  int32
+	checkUUID  string
+	checkName  string
+	name       string
+	streamTags []string
+	timestamp  uint64
+	generation int16
+	metrics    []*noit.MetricT
+	err        error
+}
+
+// NewMetricListBuilder creates a new builder for raw metric list data for a
+// check. The check UUID must be a valid UUID.
+func NewMetricListBuilder(accountID int32,
+	checkUUID string,
+) *MetricListBuilder {
+	b := &MetricListBuilder{accountID: accountID, checkUUID: checkUUID}
+
+	if _, err := uuid.Parse(checkUUID); err != nil {
+		b.err = fmt.Errorf("invalid check uuid: %s: %w", checkUUID, err)
+	}
+
+	return b
+}
+
+// CheckName sets the check name of the records added after it is called.
+func (b *MetricListBuilder) CheckName(name string) *MetricListBuilder {
+	b.checkName = name
+
+	return b
+}
+
+// Metric sets the metric name of the values added after it is called. The
+// name may contain stream tags, in the format parsed by ParseMetricName, and
+// further stream tags may be provided. Tag categories and values are
+// canonicalized, using base64 encoding, as b"...", for those which cannot be
+// represented as they are. Measurement tags are not supported.
+func (b *MetricListBuilder) Metric(name string,
+	tags ...Tag,
+) *MetricListBuilder {
+	b.name, b.streamTags = "", nil
+
+	if b.err != nil {
+		return b
+	}
+
+	mn, err := ParseMetricName(name)
+	if err != nil {
+		b.err = fmt.Errorf("invalid metric name: %s: %w", name, err)
+
+		return b
+	}
+
+	if mn.Name == "" {
+		b.err = fmt.Errorf("invalid metric name: %q", name)
+
+		return b
+	}
+
+	if len(mn.MeasurementTags) > 0 {
+		b.err = fmt.Errorf("invalid metric name: %s: measurement tags are "+
+			"not supported", name)
+
+		return b
+	}
+
+	b.name = mn.Name
+
+	for _, t := range append(mn.StreamTags, tags...) {
+		st, err := CanonicalTag(t)
+		if err != nil {
+			b.err = fmt.Errorf("invalid metric name: %s: %w", name, err)
+
+			return b
+		}
+
+		b.streamTags = append(b.streamTags, st)
+	}
+
+	return b
+}
+
+// Timestamp sets the timestamp, in milliseconds since the Unix epoch, of the
+// values added after it is called.
+func (b *MetricListBuilder) Timestamp(ms uint64) *MetricListBuilder {
+	b.timestamp = ms
+
+	return b
+}
+
+// Time sets the timestamp of the values added after it is called, truncated
+// to milliseconds.
+func (b *MetricListBuilder) Time(t time.Time) *MetricListBuilder {
+	return b.Timestamp(uint64(t.UnixNano() / int64(time.Millisecond)))
+}
+
+// Generation sets the generation of the values added after it is called.
+func (b *MetricListBuilder) Generation(g int16) *MetricListBuilder {
+	b.generation = g
+
+	return b
+}
+
+// Int adds a 32 bit signed integer value.
+func (b *MetricListBuilder) Int(v int32) *MetricListBuilder {
+	return b.add(noit.MetricValueUnionIntValue, &noit.IntValueT{Value: v})
+}
+
+// Uint adds a 32 bit unsigned integer value.
+func (b *MetricListBuilder) Uint(v uint32) *MetricListBuilder {
+	return b.add(noit.MetricValueUnionUintValue, &noit.UintValueT{Value: v})
+}
+
+// Long adds a 64 bit signed integer value.
+func (b *MetricListBuilder) Long(v int64) *MetricListBuilder {
+	return b.add(noit.MetricValueUnionLongValue, &noit.LongValueT{Value: v})
+}
+
+// Ulong adds a 64 bit unsigned integer value.
+func (b *MetricListBuilder) Ulong(v uint64) *MetricListBuilder {
+	return b.add(noit.MetricValueUnionUlongValue, &noit.UlongValueT{Value: v})
+}
+
+// Double adds a floating point value.
+func (b *MetricListBuilder) Double(v float64) *MetricListBuilder {
+	return b.add(noit.MetricValueUnionDoubleValue,
+		&noit.DoubleValueT{Value: v})
+}
+
+// String adds a text value.
+func (b *MetricListBuilder) String(v string) *MetricListBuilder {
+	return b.add(noit.MetricValueUnionStringValue,
+		&noit.StringValueT{Value: v})
+}
+
+// Histogram adds a histogram value.
+func (b *MetricListBuilder) Histogram(
+	h *circonusllhist.Histogram,
+) *MetricListBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	hist, err := histogramValue(h)
+	if err != nil {
+		b.err = err
+
+		return b
+	}
+
+	return b.add(noit.MetricValueUnionHistogram, hist)
+}
+
+// AbsentNumeric adds a value recording that a numeric metric was absent.
+func (b *MetricListBuilder) AbsentNumeric() *MetricListBuilder {
+	return b.add(noit.MetricValueUnionAbsentNumericValue,
+		&noit.AbsentNumericValueT{})
+}
+
+// AbsentString adds a value recording that a text metric was absent.
+func (b *MetricListBuilder) AbsentString() *MetricListBuilder {
+	return b.add(noit.MetricValueUnionAbsentStringValue,
+		&noit.AbsentStringValueT{})
+}
+
+// AbsentHistogram adds a value recording that a histogram metric was absent.
+func (b *MetricListBuilder) AbsentHistogram() *MetricListBuilder {
+	return b.add(noit.MetricValueUnionAbsentHistogramValue,
+		&noit.AbsentHistogramValueT{})
+}
+
+// Value adds a value of any supported Go type, choosing the value type from
+// it. Signed integers are added as Int or Long values, and unsigned integers
+// as Uint or Ulong values, by their size. Floating point values are added as
+// Double values, strings as String values, and *circonusllhist.Histogram
+// values as Histogram values. A nil value is added as an absent numeric
+// value.
+func (b *MetricListBuilder) Value(v interface{}) *MetricListBuilder {
+	switch tv := v.(type) {
+	case nil:
+		return b.AbsentNumeric()
+	case int:
+		return b.Long(int64(tv))
+	case int8:
+		return b.Int(int32(tv))
+	case int16:
+		return b.Int(int32(tv))
+	case int32:
+		return b.Int(tv)
+	case int64:
+		return b.Long(tv)
+	case uint:
+		return b.Ulong(uint64(tv))
+	case uint8:
+		return b.Uint(uint32(tv))
+	case uint16:
+		return b.Uint(uint32(tv))
+	case uint32:
+		return b.Uint(tv)
+	case uint64:
+		return b.Ulong(tv)
+	case float32:
+		return b.Double(float64(tv))
+	case float64:
+		return b.Double(tv)
+	case string:
+		return b.String(tv)
+	case *circonusllhist.Histogram:
+		return b.Histogram(tv)
+	default:
+		if b.err == nil {
+			b.err = fmt.Errorf("unsupported metric value type: %T", v)
+		}
+
+		return b
+	}
+}
+
+// Len returns the number of records added to the builder.
+func (b *MetricListBuilder) Len() int {
+	return len(b.metrics)
+}
+
+// Build returns the metric list containing the records added to the builder,
+// or the first error encountered while adding them.
+func (b *MetricListBuilder) Build() (*noit.MetricListT, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return &noit.MetricListT{
+		Metrics: append([]*noit.MetricT{}, b.metrics...),
+	}, nil
+}
+
+// add adds a record containing a value for the current metric and timestamp.
+func (b *MetricListBuilder) add(typ noit.MetricValueUnion,
+	v interface{},
+) *MetricListBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	if b.name == "" {
+		b.err = fmt.Errorf("no metric name set for value: %v", v)
+
+		return b
+	}
+
+	b.metrics = append(b.metrics, &noit.MetricT{
+		Timestamp: b.timestamp,
+		CheckName: b.checkName,
+		CheckUuid: b.checkUUID,
+		AccountId: b.accountID,
+		Value: &noit.MetricValueT{
+			Name:       b.name,
+			Timestamp:  b.timestamp,
+			Value:      &noit.MetricValueUnionT{Type: typ, Value: v},
+			Generation: b.generation,
+			StreamTags: append([]string{}, b.streamTags...),
+		},
+	})
+
+	return b
+}
+
+// CanonicalTag returns the canonical category:value form of a stream tag,
+// which ParseMetricName parses back into the same tag. Categories and values
+// containing characters which cannot be represented as they are, such as tag
+// separators, are base64 encoded, as b"...".
+func CanonicalTag(t Tag) (string, error) {
+	if t.Category == "" {
+		return "", fmt.Errorf("invalid tag: empty category")
+	}
+
+	cat, val := t.Category, t.Value
+	if !plainTag(cat, false) {
+		cat = encodeTagPart(cat)
+	}
+
+	if !plainTag(val, true) {
+		val = encodeTagPart(val)
+	}
+
+	st := cat + ":" + val
+
+	mn, err := ParseMetricName("m|ST[" + st + "]")
+	if err != nil || len(mn.StreamTags) != 1 || mn.StreamTags[0] != t {
+		// Encode both parts when the plain form does not parse as the tag.
+		st = encodeTagPart(t.Category) + ":" + encodeTagPart(t.Value)
+	}
+
+	return st, nil
+}
+
+// rawMetricName returns the canonical name of the metric of a raw metric
+// record, used to find the nodes owning it. The stream tags of the record are
+// appended to the metric name, sorted and without duplicates, as |ST[...].
+func rawMetricName(m *noit.MetricT) string {
+	if m.Value == nil {
+		return ""
+	}
+
+	if len(m.Value.StreamTags) == 0 {
+		return m.Value.Name
+	}
+
+	tags := append([]string{}, m.Value.StreamTags...)
+	sort.Strings(tags)
+
+	uniq := tags[:1]
+
+	for _, t := range tags[1:] {
+		if t != uniq[len(uniq)-1] {
+			uniq = append(uniq, t)
+		}
+	}
+
+	return m.Value.Name + "|ST[" + strings.Join(uniq, ",") + "]"
+}
+
+// plainTag returns whether a tag category or value can be represented as it
+// is in a canonical metric name.
+func plainTag(s string, value bool) bool {
+	if strings.HasPrefix(s, `b"`) {
+		return false
+	}
+
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("`+!@#$%^&'/?._-", c):
+		case value && (c == ':' || c == '='):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// encodeTagPart returns the base64 encoded form of a tag category or value.
+func encodeTagPart(s string) string {
+	return `b"` + base64.StdEncoding.EncodeToString([]byte(s)) + `"`
+}
+
+// histogramValue converts a histogram to a raw metric histogram value.
+func histogramValue(h *circonusllhist.Histogram) (*noit.HistogramT, error) {
+	if h == nil {
+		return nil, fmt.Errorf("invalid histogram value: nil")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := h.Serialize(buf); err != nil {
+		return nil, fmt.Errorf("unable to encode histogram: %w", err)
+	}
+
+	var n int16
+	if err := binary.Read(buf, binary.BigEndian, &n); err != nil {
+		return nil, fmt.Errorf("unable to encode histogram: %w", err)
+	}
+
+	hist := &noit.HistogramT{Buckets: make([]*noit.HistogramBucketT, 0, n)}
+
+	for i := int16(0); i < n; i++ {
+		var bin struct {
+			Val, Exp int8
+			Size     uint8
+		}
+
+		if err := binary.Read(buf, binary.BigEndian, &bin); err != nil {
+			return nil, fmt.Errorf("unable to encode histogram: %w", err)
+		}
+
+		if bin.Size > 7 {
+			return nil, fmt.Errorf("unable to encode histogram: "+
+				"invalid bin size: %d", bin.Size)
+		}
+
+		count := make([]byte, int(bin.Size)+1)
+		if _, err := io.ReadFull(buf, count); err != nil {
+			return nil, fmt.Errorf("unable to encode histogram: %w", err)
+		}
+
+		// Serialized bin counts are stored least significant byte first.
+		c := uint64(0)
+		for j := len(count) - 1; j >= 0; j-- {
+			c = c<<8 | uint64(count[j])
+		}
+
+		hist.Buckets = append(hist.Buckets, &noit.HistogramBucketT{
+			Val:   bin.Val,
+			Exp:   bin.Exp,
+			Count: c,
+		})
+	}
+
+	return hist, nil
+}
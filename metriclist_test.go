@@ -0,0 +1,197 @@
+package gosnowth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/gosnowth/fb/noit"
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/openhistogram/circonusllhist"
+)
+
+func TestMetricListBuilder(t *testing.T) {
+	t.Parallel()
+
+	h := circonusllhist.New()
+	_ = h.RecordValues(1.5, 3)
+	_ = h.RecordValues(300, 1000)
+
+	ml, err := NewMetricListBuilder(1, shardTestUUID).
+		CheckName("test").
+		Metric("requests|ST[b\"aG9zdA==\":a]",
+			Tag{Category: "env", Value: "prod,eu"}).
+		Timestamp(60000).Double(12.5).Value(int64(-3)).
+		Metric("status").Time(time.Unix(120, 0)).String("ok").
+		Metric("latency").Histogram(h).
+		Metric("count").Value(uint8(7)).Value(uint64(8)).Value(nil).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The metric list must encode and decode as a flatbuffer.
+	builder := flatbuffers.NewBuilder(1024)
+	builder.Finish(noit.MetricListPack(builder, ml))
+	ml = noit.GetRootAsMetricList(builder.FinishedBytes(), 0).UnPack()
+
+	if len(ml.Metrics) != 7 {
+		t.Fatalf("Expected metrics: 7, got: %v", len(ml.Metrics))
+	}
+
+	m := ml.Metrics[0]
+	if m.AccountId != 1 || m.CheckUuid != shardTestUUID ||
+		m.CheckName != "test" || m.Timestamp != 60000 ||
+		m.Value.Timestamp != 60000 || m.Value.Name != "requests" {
+		t.Errorf("Expected requests record, got: %+v, %+v", m, m.Value)
+	}
+
+	exp := `host:a,env:b"cHJvZCxldQ=="`
+	if st := strings.Join(m.Value.StreamTags, ","); st != exp {
+		t.Errorf("Expected stream tags: %v, got: %v", exp, st)
+	}
+
+	mn, err := ParseMetricName(m.Value.Name + "|ST[" + exp + "]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mn.StreamTags[1].Value != "prod,eu" {
+		t.Errorf("Expected tag value: prod,eu, got: %v",
+			mn.StreamTags[1].Value)
+	}
+
+	types := []noit.MetricValueUnion{
+		noit.MetricValueUnionDoubleValue,
+		noit.MetricValueUnionLongValue,
+		noit.MetricValueUnionStringValue,
+		noit.MetricValueUnionHistogram,
+		noit.MetricValueUnionUintValue,
+		noit.MetricValueUnionUlongValue,
+		noit.MetricValueUnionAbsentNumericValue,
+	}
+
+	for i, typ := range types {
+		if ml.Metrics[i].Value.Value.Type != typ {
+			t.Errorf("Expected value type %d: %v, got: %v", i, typ,
+				ml.Metrics[i].Value.Value.Type)
+		}
+	}
+
+	if v := ml.Metrics[1].Value.Value.Value.(*noit.LongValueT); v.Value != -3 {
+		t.Errorf("Expected value: -3, got: %v", v.Value)
+	}
+
+	if ts := ml.Metrics[2].Timestamp; ts != 120000 {
+		t.Errorf("Expected timestamp: 120000, got: %v", ts)
+	}
+
+	hist := ml.Metrics[3].Value.Value.Value.(*noit.HistogramT)
+	count := uint64(0)
+
+	for _, b := range hist.Buckets {
+		count += b.Count
+	}
+
+	if len(hist.Buckets) != 2 || count != 1003 {
+		t.Errorf("Expected buckets: 2, count: 1003, got: %v, %v",
+			len(hist.Buckets), count)
+	}
+
+	if b := hist.Buckets[0]; b.Val != 15 || b.Exp != 0 || b.Count != 3 {
+		t.Errorf("Expected bucket: 15e0 3, got: %+v", b)
+	}
+}
+
+func TestMetricListBuilderErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		b    *MetricListBuilder
+		err  string
+	}{
+		{
+			name: "invalid uuid",
+			b:    NewMetricListBuilder(1, "x").Metric("a").Double(1),
+			err:  "invalid check uuid",
+		},
+		{
+			name: "no metric",
+			b:    NewMetricListBuilder(1, shardTestUUID).Double(1),
+			err:  "no metric name",
+		},
+		{
+			name: "measurement tags",
+			b: NewMetricListBuilder(1, shardTestUUID).
+				Metric("a|MT{b:c}").Double(1),
+			err: "measurement tags",
+		},
+		{
+			name: "empty category",
+			b: NewMetricListBuilder(1, shardTestUUID).
+				Metric("a", Tag{Value: "b"}).Double(1),
+			err: "empty category",
+		},
+		{
+			name: "unsupported type",
+			b: NewMetricListBuilder(1, shardTestUUID).
+				Metric("a").Value(true),
+			err: "unsupported metric value type",
+		},
+		{
+			name: "nil histogram",
+			b: NewMetricListBuilder(1, shardTestUUID).
+				Metric("a").Histogram(nil),
+			err: "invalid histogram",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := tt.b.Build()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected error: %v, got: %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestCanonicalTag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		tag Tag
+		exp string
+	}{
+		{Tag{Category: "host", Value: "a.b-c"}, "host:a.b-c"},
+		{Tag{Category: "url", Value: "http://x?y=1"}, "url:http://x?y=1"},
+		{Tag{Category: "a b", Value: "c"}, `b"YSBi":c`},
+		{Tag{Category: "a:b", Value: "c]"}, `b"YTpi":b"Y10="`},
+		{Tag{Category: "a", Value: ""}, "a:"},
+	}
+
+	for _, tt := range tests {
+		st, err := CanonicalTag(tt.tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if st != tt.exp {
+			t.Errorf("Expected canonical tag: %v, got: %v", tt.exp, st)
+		}
+
+		mn, err := ParseMetricName("m|ST[" + st + "]")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if mn.StreamTags[0] != tt.tag {
+			t.Errorf("Expected tag: %+v, got: %+v", tt.tag, mn.StreamTags[0])
+		}
+	}
+}
@@ -146,33 +146,36 @@ func (p *Parts) MarshalJSON() ([]byte, error) {
 
 // WriteNNT writes NNT data to a node.
 func (sc *SnowthClient) WriteNNT(data []NNTData, nodes ...*SnowthNode) error {
-	return sc.WriteNNTContext(context.Background(), data, nodes...)
+	return sc.WriteNNTContext(context.Background(), data, nodeOptions(nodes)...)
 }
 
-// WriteNNTContext is the context aware version of WriteNNT.
+// WriteNNTContext is the context aware version of WriteNNT. Unless a node is
+// set by the call options, the data is split into batches by the nodes owning
+// each metric, which are sent in parallel. If any batch fails, a *WriteError
+// containing the result of each batch is returned.
 func (sc *SnowthClient) WriteNNTContext(ctx context.Context,
-	data []NNTData, nodes ...*SnowthNode,
+	data []NNTData, opts ...CallOption,
 ) error {
-	buf := new(bytes.Buffer)
-	if err := json.NewEncoder(buf).Encode(data); err != nil {
-		return fmt.Errorf("failed to encode NNTData for write: %w", err)
-	}
-
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else if len(data) > 0 {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(data[0].ID,
-			data[0].Metric))
-	}
+	return sc.shardWriteContext(ctx, opts, len(data),
+		func(i int) (string, string) {
+			return data[i].ID, data[i].Metric
+		},
+		func(ctx context.Context, node *SnowthNode, idx []int) error {
+			batch := make([]NNTData, len(idx))
+			for i, j := range idx {
+				batch[i] = data[j]
+			}
 
-	if node == nil {
-		return fmt.Errorf("unable to get active node")
-	}
+			buf := new(bytes.Buffer)
+			if err := json.NewEncoder(buf).Encode(batch); err != nil {
+				return fmt.Errorf("failed to encode NNTData for write: %w", err)
+			}
 
-	_, _, err := sc.DoRequestContext(ctx, node, "POST", "/write/nnt", buf, nil)
+			_, _, err := sc.DoRequestContext(ctx, node, "POST", "/write/nnt",
+				buf, nil)
 
-	return err
+			return err
+		})
 }
 
 // ReadNNTValues reads NNT data from a node.
@@ -180,31 +183,35 @@ func (sc *SnowthClient) ReadNNTValues(start, end time.Time, period int64,
 	t, id, metric string, nodes ...*SnowthNode,
 ) ([]NNTValue, error) {
 	return sc.ReadNNTValuesContext(context.Background(), start, end,
-		period, t, id, metric, nodes...)
+		period, t, id, metric, nodeOptions(nodes)...)
 }
 
 // ReadNNTValuesContext is the context aware version of ReadNNTValues.
 func (sc *SnowthClient) ReadNNTValuesContext(ctx context.Context,
 	start, end time.Time, period int64,
-	t, id, metric string, nodes ...*SnowthNode,
+	t, id, metric string, opts ...CallOption,
 ) ([]NNTValue, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(id, metric))
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	var owners []string
+
+	if node == nil {
+		owners = sc.FindMetricNodeIDs(id, metric)
+		node = sc.GetActiveNode(owners)
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	r := &NNTValueResponse{}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "GET", path.Join("/read",
-		strconv.FormatInt(start.Unix(), 10),
-		strconv.FormatInt(end.Unix(), 10),
-		strconv.FormatInt(period, 10), id, t, metric), nil, nil)
+	body, _, err := sc.hedgedRequestContext(ctx, node, owners, "GET",
+		path.Join("/read",
+			strconv.FormatInt(start.Unix(), 10),
+			strconv.FormatInt(end.Unix(), 10),
+			strconv.FormatInt(period, 10), id, t, metric), nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -221,31 +228,35 @@ func (sc *SnowthClient) ReadNNTAllValues(start, end time.Time, period int64,
 	id, metric string, nodes ...*SnowthNode,
 ) ([]NNTAllValue, error) {
 	return sc.ReadNNTAllValuesContext(context.Background(), start, end,
-		period, id, metric, nodes...)
+		period, id, metric, nodeOptions(nodes)...)
 }
 
 // ReadNNTAllValuesContext is the context aware version of ReadNNTAllValues.
 func (sc *SnowthClient) ReadNNTAllValuesContext(ctx context.Context,
 	start, end time.Time, period int64,
-	id, metric string, nodes ...*SnowthNode,
+	id, metric string, opts ...CallOption,
 ) ([]NNTAllValue, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(id, metric))
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	var owners []string
+
+	if node == nil {
+		owners = sc.FindMetricNodeIDs(id, metric)
+		node = sc.GetActiveNode(owners)
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	r := &NNTAllValueResponse{}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "GET", path.Join("/read",
-		strconv.FormatInt(start.Unix(), 10),
-		strconv.FormatInt(end.Unix(), 10),
-		strconv.FormatInt(period, 10), id, "all", metric), nil, nil)
+	body, _, err := sc.hedgedRequestContext(ctx, node, owners, "GET",
+		path.Join("/read",
+			strconv.FormatInt(start.Unix(), 10),
+			strconv.FormatInt(end.Unix(), 10),
+			strconv.FormatInt(period, 10), id, "all", metric), nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -22,30 +22,41 @@ func (sc *SnowthClient) WriteNNTBSFlatbuffer(merge *nntbs.NNTMergeT,
 	builder *flatbuffers.Builder, nodes ...*SnowthNode,
 ) error {
 	return sc.WriteNNTBSFlatbufferContext(context.Background(), merge,
-		builder, nodes...)
+		builder, nodeOptions(nodes)...)
 }
 
 // WriteNNTBSFlatbufferContext is the context aware version of
-// WriteNNTBSFlatbuffer.
+// WriteNNTBSFlatbuffer. If the node reports the data as misdirected, the
+// topology is refreshed and the data is re-sent to the node owning its metric.
+// See WithMisdirectRetries(). If the client has a spool, data which cannot be
+// sent because no node is available is added to the spool, and no error is
+// returned. See Config Spool.
 func (sc *SnowthClient) WriteNNTBSFlatbufferContext(ctx context.Context,
 	merge *nntbs.NNTMergeT, builder *flatbuffers.Builder,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) error {
 	if merge == nil {
 		return fmt.Errorf("NNTBS merge data must not be null")
 	}
 
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else if len(merge.Ops) > 0 {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(
-			string(merge.Ops[0].Metric.MetricLocator.CheckUuid),
-			merge.Ops[0].Metric.MetricLocator.MetricName))
+	var uuid, metric string
+
+	if len(merge.Ops) > 0 && merge.Ops[0].Metric != nil &&
+		merge.Ops[0].Metric.MetricLocator != nil {
+		uuid = string(merge.Ops[0].Metric.MetricLocator.CheckUuid)
+		metric = merge.Ops[0].Metric.MetricLocator.MetricName
 	}
 
-	if node == nil {
-		return fmt.Errorf("unable to get active node")
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+	if node == nil && len(merge.Ops) > 0 {
+		node = sc.GetActiveNode(sc.FindMetricNodeIDs(uuid, metric))
+	}
+
+	sp := sc.writeSpool(ctx)
+
+	if node == nil && sp == nil {
+		return ErrNoActiveNode
 	}
 
 	if builder == nil {
@@ -58,17 +69,49 @@ func (sc *SnowthClient) WriteNNTBSFlatbufferContext(ctx context.Context,
 	builder.FinishWithFileIdentifier(offset, nntMergeFileIdentifier())
 
 	data := builder.FinishedBytes()
-	hdrs := http.Header{"Content-Type": {"application/snowth-nntbs"}}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "POST", "/nntbs",
-		bytes.NewReader(data), hdrs)
+	var (
+		res *IRONdbPutResponse
+		err error
+	)
+
+	if node == nil {
+		err = ErrNoActiveNode
+	} else {
+		res, err = sc.sendNNTBS(ctx, node, data)
+	}
+
 	if err != nil {
-		return err
+		if sp == nil || !spoolable(err) {
+			return err
+		}
+
+		return sc.spoolWrite(sp, SpoolNNTBS, data, uint64(len(merge.Ops)),
+			err)
 	}
 
-	res := &IRONdbPutResponse{}
-	if err := json.NewDecoder(body).Decode(res); err != nil {
-		return fmt.Errorf("unable to decode IRONdb response: %w", err)
+	topos := routingTopologies{}
+
+	for i := 0; i < misdirectRetries(ctx) && res.Misdirected != 0 &&
+		len(merge.Ops) > 0; i++ {
+		owners, err := sc.metricOwners(ctx, node, topos)
+		if err != nil {
+			return fmt.Errorf("failed to re-route nntbs data: %w", err)
+		}
+
+		target := sc.GetActiveNode(owners(uuid, metric))
+		if target == nil || target == node {
+			break
+		}
+
+		sc.LogDebugf("re-routing misdirected nntbs data from %s to %s",
+			node.GetURL().Host, target.GetURL().Host)
+
+		node = target
+
+		if res, err = sc.sendNNTBS(ctx, node, data); err != nil {
+			return err
+		}
 	}
 
 	if res.Errors != 0 || res.Misdirected != 0 || res.Records != 1 ||
@@ -78,3 +121,23 @@ func (sc *SnowthClient) WriteNNTBSFlatbufferContext(ctx context.Context,
 
 	return nil
 }
+
+// sendNNTBS sends flatbuffer format NNTBS data to a node.
+func (sc *SnowthClient) sendNNTBS(ctx context.Context, node *SnowthNode,
+	data []byte,
+) (*IRONdbPutResponse, error) {
+	hdrs := http.Header{"Content-Type": {"application/snowth-nntbs"}}
+
+	body, _, err := sc.DoRequestContext(ctx, node, "POST", "/nntbs",
+		bytes.NewReader(data), hdrs)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &IRONdbPutResponse{}
+	if err := json.NewDecoder(body).Decode(res); err != nil {
+		return nil, fmt.Errorf("unable to decode IRONdb response: %w", err)
+	}
+
+	return res, nil
+}
@@ -151,34 +151,39 @@ func (p *NumericParts) MarshalJSON() ([]byte, error) {
 func (sc *SnowthClient) WriteNumeric(data []NumericWrite,
 	nodes ...*SnowthNode,
 ) error {
-	return sc.WriteNumericContext(context.Background(), data, nodes...)
+	return sc.WriteNumericContext(context.Background(), data, nodeOptions(nodes)...)
 }
 
-// WriteNumericContext is the context aware version of WriteNumeric.
+// WriteNumericContext is the context aware version of WriteNumeric. Unless a
+// node is set by the call options, the data is split into batches by the
+// nodes owning each metric, which are sent in parallel. If any batch fails, a
+// *WriteError containing the result of each batch is returned. The
+// WithWriteReport() option can be used to obtain the results of a successful
+// write.
 func (sc *SnowthClient) WriteNumericContext(ctx context.Context,
-	data []NumericWrite, nodes ...*SnowthNode,
+	data []NumericWrite, opts ...CallOption,
 ) error {
-	buf := new(bytes.Buffer)
-	if err := json.NewEncoder(buf).Encode(data); err != nil {
-		return fmt.Errorf("failed to encode NumericWrite for write: %w", err)
-	}
-
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else if len(data) > 0 {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(data[0].ID,
-			data[0].Metric))
-	}
+	return sc.shardWriteContext(ctx, opts, len(data),
+		func(i int) (string, string) {
+			return data[i].ID, data[i].Metric
+		},
+		func(ctx context.Context, node *SnowthNode, idx []int) error {
+			batch := make([]NumericWrite, len(idx))
+			for i, j := range idx {
+				batch[i] = data[j]
+			}
 
-	if node == nil {
-		return fmt.Errorf("unable to get active node")
-	}
+			buf := new(bytes.Buffer)
+			if err := json.NewEncoder(buf).Encode(batch); err != nil {
+				return fmt.Errorf("failed to encode NumericWrite for write: %w",
+					err)
+			}
 
-	_, _, err := sc.DoRequestContext(ctx, node, "POST",
-		"/write/numeric", buf, nil)
+			_, _, err := sc.DoRequestContext(ctx, node, "POST",
+				"/write/numeric", buf, nil)
 
-	return err
+			return err
+		})
 }
 
 // ReadNumericValues reads numeric data from a node.
@@ -186,31 +191,35 @@ func (sc *SnowthClient) ReadNumericValues(start, end time.Time, period int64,
 	t, id, metric string, nodes ...*SnowthNode,
 ) ([]NumericValue, error) {
 	return sc.ReadNumericValuesContext(context.Background(), start, end,
-		period, t, id, metric, nodes...)
+		period, t, id, metric, nodeOptions(nodes)...)
 }
 
 // ReadNumericValuesContext is the context aware version of ReadNumericValues.
 func (sc *SnowthClient) ReadNumericValuesContext(ctx context.Context,
 	start, end time.Time, period int64,
-	t, id, metric string, nodes ...*SnowthNode,
+	t, id, metric string, opts ...CallOption,
 ) ([]NumericValue, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(id, metric))
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	var owners []string
+
+	if node == nil {
+		owners = sc.FindMetricNodeIDs(id, metric)
+		node = sc.GetActiveNode(owners)
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	r := &NumericValueResponse{}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "GET", path.Join("/read",
-		strconv.FormatInt(start.Unix(), 10),
-		strconv.FormatInt(end.Unix(), 10),
-		strconv.FormatInt(period, 10), id, t, metric), nil, nil)
+	body, _, err := sc.hedgedRequestContext(ctx, node, owners, "GET",
+		path.Join("/read",
+			strconv.FormatInt(start.Unix(), 10),
+			strconv.FormatInt(end.Unix(), 10),
+			strconv.FormatInt(period, 10), id, t, metric), nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -227,32 +236,36 @@ func (sc *SnowthClient) ReadNumericAllValues(start, end time.Time, period int64,
 	id, metric string, nodes ...*SnowthNode,
 ) ([]NumericAllValue, error) {
 	return sc.ReadNumericAllValuesContext(context.Background(), start, end,
-		period, id, metric, nodes...)
+		period, id, metric, nodeOptions(nodes)...)
 }
 
 // ReadNumericAllValuesContext is the context aware version of
 // ReadNumericAllValues.
 func (sc *SnowthClient) ReadNumericAllValuesContext(ctx context.Context,
 	start, end time.Time, period int64,
-	id, metric string, nodes ...*SnowthNode,
+	id, metric string, opts ...CallOption,
 ) ([]NumericAllValue, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(id, metric))
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	var owners []string
+
+	if node == nil {
+		owners = sc.FindMetricNodeIDs(id, metric)
+		node = sc.GetActiveNode(owners)
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	r := &NumericAllValueResponse{}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "GET", path.Join("/read",
-		strconv.FormatInt(start.Unix(), 10),
-		strconv.FormatInt(end.Unix(), 10),
-		strconv.FormatInt(period, 10), id, "all", metric), nil, nil)
+	body, _, err := sc.hedgedRequestContext(ctx, node, owners, "GET",
+		path.Join("/read",
+			strconv.FormatInt(start.Unix(), 10),
+			strconv.FormatInt(end.Unix(), 10),
+			strconv.FormatInt(period, 10), id, "all", metric), nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,239 @@
+package gosnowth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestEvent values describe a single attempt to send a request to an
+// IRONdb node. A request which is retried produces an event for each attempt.
+type RequestEvent struct {
+	// TraceID is the trace ID used in log output for the request.
+	TraceID string
+
+	// SpanID is the ID of the span sent in the traceparent header of the
+	// request attempt. On request start events, it is the ID generated by the
+	// client, which is replaced if the observer provides a span.
+	SpanID string
+
+	// Node is the node receiving the request.
+	Node *SnowthNode
+
+	// Method is the HTTP method of the request.
+	Method string
+
+	// Endpoint is the request path with variable components, such as metric
+	// names and timestamps, replaced by placeholders. For example:
+	// /read/{start_ts}/{end_ts}/{period}/{uuid}/{type}/{metric}.
+	Endpoint string
+
+	// URL is the full URL of the request.
+	URL string
+
+	// Status is the HTTP status code of the response. It is zero if no
+	// response was received.
+	Status int
+
+	// BytesOut is the size of the request body.
+	BytesOut int64
+
+	// BytesIn is the size of the response body. It is only set on request
+	// end events.
+	BytesIn int64
+
+	// Retry is the number of times the request has been retried after
+	// backing off, and ConnRetry is the number of times it has been retried
+	// on another node during the current retry.
+	Retry     int64
+	ConnRetry int64
+
+	// Start is the time the attempt started, and Duration is the time it
+	// took to complete. Duration is only set on request end events.
+	Start    time.Time
+	Duration time.Duration
+
+	// Err is the error returned by the attempt, if any. It is only set on
+	// request end events.
+	Err error
+}
+
+// RequestObserver values receive events describing the requests sent to
+// IRONdb nodes by a SnowthClient. RequestStart is called before each attempt
+// to send a request, and may return a derived context which will be used for
+// the attempt and passed to the matching RequestEnd call. If the returned
+// context contains a new trace context, added by ContextWithTraceContext(),
+// it identifies a span created by the observer for the attempt, and is sent
+// in the traceparent header in place of the span ID generated by the client.
+// Observers are called synchronously and must be safe for concurrent use.
+type RequestObserver interface {
+	RequestStart(ctx context.Context, ev *RequestEvent) context.Context
+	RequestEnd(ctx context.Context, ev *RequestEvent)
+}
+
+// endpointTemplates contains the request path templates used to determine
+// the endpoint of a request. Placeholders match a single path element, except
+// for {metric} and {name}, which match all remaining elements. Templates are
+// matched in order, and placeholders ending in "_ts" or named {period} or
+// {account} only match numeric elements.
+var endpointTemplates = splitTemplates([]string{
+	"/read/{start_ts}/{end_ts}/{period}/{uuid}/{type}/{metric}",
+	"/read/{start_ts}/{end_ts}/{uuid}/{metric}",
+	"/histogram/write",
+	"/histogram/{start_ts}/{end_ts}/{period}/{uuid}/{metric}",
+	"/rollup/{uuid}/{metric}",
+	"/raw/{uuid}/{metric}",
+	"/locate/xml/{uuid}/{metric}",
+	"/topology/xml/{hash}",
+	"/topology/{hash}",
+	"/activate/{hash}",
+	"/find/{account}/tags",
+	"/find/{account}/tag_cats",
+	"/find/{account}/tag_vals",
+	"/meta/check/tag/{uuid}",
+	"/graphite/{account}/{prefix}/metrics/find",
+	"/graphite/{account}/{prefix}/tags/find",
+	"/graphite/{account}/{prefix}/series_multi",
+	"/extension/lua/public/caql_v1",
+	"/extension/lua/{name}",
+})
+
+// splitTemplates splits request path templates into path elements.
+func splitTemplates(templates []string) [][]string {
+	result := make([][]string, len(templates))
+	for i, t := range templates {
+		result[i] = strings.Split(strings.TrimPrefix(t, "/"), "/")
+	}
+
+	return result
+}
+
+// endpointTemplate returns the endpoint template matching a request URL
+// reference. Paths which do not match a known template are returned without
+// their query string if they contain a single element, otherwise the first
+// element is returned followed by a wildcard.
+func endpointTemplate(ref string) string {
+	p := requestPath(ref)
+
+	elems := strings.Split(strings.TrimPrefix(p, "/"), "/")
+
+	for _, t := range endpointTemplates {
+		if matchTemplate(t, elems) {
+			return "/" + strings.Join(t, "/")
+		}
+	}
+
+	if len(elems) <= 1 {
+		return p
+	}
+
+	return "/" + elems[0] + "/*"
+}
+
+// matchTemplate reports whether path elements match a split template.
+func matchTemplate(t, elems []string) bool {
+	for i, te := range t {
+		if i >= len(elems) || elems[i] == "" {
+			return false
+		}
+
+		if !strings.HasPrefix(te, "{") {
+			if te != elems[i] {
+				return false
+			}
+
+			continue
+		}
+
+		switch {
+		case te == "{metric}" || te == "{name}":
+			return true
+		case strings.HasSuffix(te, "_ts}") || te == "{period}" ||
+			te == "{account}":
+			if !isNumeric(elems[i]) {
+				return false
+			}
+		}
+	}
+
+	return len(t) == len(elems)
+}
+
+// isNumeric reports whether a string contains only decimal digits.
+func isNumeric(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+// observedDo sends a single request attempt to an IRONdb node, reporting the
+// start and end of the attempt to the request observer, if one is set. The
+// attempt is sent as a child span of the trace context, using the span
+// provided by the observer, if any.
+func (sc *SnowthClient) observedDo(ctx context.Context, ev *RequestEvent,
+	body []byte, headers http.Header, tc TraceContext,
+) (io.Reader, http.Header, int, error) {
+	obs := sc.RequestObserver()
+	if obs == nil {
+		return sc.do(ctx, ev.Node, ev.Method, ev.URL, bytes.NewBuffer(body),
+			traceHeaders(headers, tc, ev.SpanID), ev.TraceID)
+	}
+
+	if octx := obs.RequestStart(ctx, ev); octx != nil {
+		ctx = octx
+	}
+
+	spanID := ev.SpanID
+
+	if stc, ok := TraceContextFromContext(ctx); ok && stc.Valid() &&
+		stc != tc {
+		tc, spanID = stc, stc.SpanID
+	}
+
+	bdy, hdr, status, err := sc.do(ctx, ev.Node, ev.Method, ev.URL,
+		bytes.NewBuffer(body), traceHeaders(headers, tc, spanID), ev.TraceID)
+
+	end := *ev
+	end.SpanID = spanID
+	end.Duration = time.Since(ev.Start)
+	end.Err = err
+
+	// Transport errors are reported by do() with a status code, even though
+	// no response was received.
+	if hdr != nil {
+		end.Status = status
+	}
+
+	if b, ok := bdy.(interface{ Len() int }); ok && b != nil {
+		end.BytesIn = int64(b.Len())
+	}
+
+	obs.RequestEnd(ctx, &end)
+
+	return bdy, hdr, status, err
+}
+
+// RequestObserver gets the observer receiving request events from a
+// SnowthClient, if any.
+func (sc *SnowthClient) RequestObserver() RequestObserver {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	return sc.observer
+}
+
+// SetRequestObserver sets an observer which will receive events for every
+// request sent to IRONdb nodes by a SnowthClient. A nil value disables
+// request events.
+func (sc *SnowthClient) SetRequestObserver(obs RequestObserver) {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.observer = obs
+}
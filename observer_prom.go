@@ -0,0 +1,286 @@
+package gosnowth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultPrometheusBuckets are the request duration histogram buckets, in
+// seconds, used by PrometheusObserver values if none are specified.
+var DefaultPrometheusBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// promKey values identify a set of request measurements by their labels.
+type promKey struct {
+	node     string
+	method   string
+	endpoint string
+	status   string
+}
+
+// promSeries values contain the request measurements for a set of labels.
+type promSeries struct {
+	requests  uint64
+	errors    uint64
+	retries   uint64
+	bytesIn   uint64
+	bytesOut  uint64
+	durations []uint64
+	sum       float64
+}
+
+// PrometheusObserver values are RequestObserver values which collect request
+// measurements and expose them in the Prometheus text exposition format.
+// A PrometheusObserver can be used directly as the http.Handler for a
+// metrics endpoint.
+type PrometheusObserver struct {
+	sync.Mutex
+	buckets  []float64
+	series   map[promKey]*promSeries
+	inFlight map[promKey]int64
+}
+
+// NewPrometheusObserver creates a new PrometheusObserver using the provided
+// request duration histogram buckets, in seconds. If no buckets are provided,
+// DefaultPrometheusBuckets are used.
+func NewPrometheusObserver(buckets ...float64) *PrometheusObserver {
+	if len(buckets) == 0 {
+		buckets = DefaultPrometheusBuckets
+	}
+
+	b := append([]float64{}, buckets...)
+	sort.Float64s(b)
+
+	return &PrometheusObserver{
+		buckets:  b,
+		series:   map[promKey]*promSeries{},
+		inFlight: map[promKey]int64{},
+	}
+}
+
+// RequestStart records the start of a request.
+func (po *PrometheusObserver) RequestStart(ctx context.Context,
+	ev *RequestEvent,
+) context.Context {
+	po.Lock()
+	defer po.Unlock()
+
+	po.inFlight[promEventKey(ev, false)]++
+
+	return ctx
+}
+
+// RequestEnd records the completion of a request.
+func (po *PrometheusObserver) RequestEnd(ctx context.Context,
+	ev *RequestEvent,
+) {
+	po.Lock()
+	defer po.Unlock()
+
+	po.inFlight[promEventKey(ev, false)]--
+
+	k := promEventKey(ev, true)
+
+	s := po.series[k]
+	if s == nil {
+		s = &promSeries{durations: make([]uint64, len(po.buckets))}
+		po.series[k] = s
+	}
+
+	s.requests++
+	s.bytesIn += uint64(ev.BytesIn)
+	s.bytesOut += uint64(ev.BytesOut)
+
+	if ev.Err != nil {
+		s.errors++
+	}
+
+	if ev.Retry > 0 || ev.ConnRetry > 0 {
+		s.retries++
+	}
+
+	d := ev.Duration.Seconds()
+	s.sum += d
+
+	for i, b := range po.buckets {
+		if d <= b {
+			s.durations[i]++
+		}
+	}
+}
+
+// promEventKey returns the labels identifying the measurements for a request
+// event. The status label is only included if withStatus is true.
+func promEventKey(ev *RequestEvent, withStatus bool) promKey {
+	k := promKey{method: ev.Method, endpoint: ev.Endpoint}
+
+	if ev.Node != nil && ev.Node.GetURL() != nil {
+		k.node = ev.Node.GetURL().Host
+	}
+
+	if withStatus {
+		k.status = strconv.Itoa(ev.Status)
+	}
+
+	return k
+}
+
+// labels returns the label set for the key in the exposition format, with an
+// optional additional label.
+func (k promKey) labels(extra ...string) string {
+	l := []string{
+		`node="` + promEscape(k.node) + `"`,
+		`method="` + promEscape(k.method) + `"`,
+		`endpoint="` + promEscape(k.endpoint) + `"`,
+	}
+
+	if k.status != "" {
+		l = append(l, `status="`+k.status+`"`)
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		l = append(l, extra[i]+`="`+promEscape(extra[i+1])+`"`)
+	}
+
+	return "{" + strings.Join(l, ",") + "}"
+}
+
+// promEscape escapes a label value for the exposition format.
+func promEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+// WriteTo writes the collected request measurements to a writer in the
+// Prometheus text exposition format.
+func (po *PrometheusObserver) WriteTo(w io.Writer) (int64, error) {
+	po.Lock()
+
+	keys := make([]promKey, 0, len(po.series))
+	for k := range po.series {
+		keys = append(keys, k)
+	}
+
+	inFlight := make([]promKey, 0, len(po.inFlight))
+	for k := range po.inFlight {
+		inFlight = append(inFlight, k)
+	}
+
+	sortPromKeys(keys)
+	sortPromKeys(inFlight)
+
+	buf := &bytes.Buffer{}
+
+	counters := []struct {
+		name, help string
+		value      func(*promSeries) uint64
+	}{
+		{
+			"gosnowth_requests_total",
+			"Total number of requests sent to IRONdb nodes.",
+			func(s *promSeries) uint64 { return s.requests },
+		},
+		{
+			"gosnowth_request_errors_total",
+			"Total number of requests to IRONdb nodes which failed.",
+			func(s *promSeries) uint64 { return s.errors },
+		},
+		{
+			"gosnowth_request_retries_total",
+			"Total number of requests to IRONdb nodes which were retries.",
+			func(s *promSeries) uint64 { return s.retries },
+		},
+		{
+			"gosnowth_request_bytes_total",
+			"Total size of request bodies sent to IRONdb nodes.",
+			func(s *promSeries) uint64 { return s.bytesOut },
+		},
+		{
+			"gosnowth_response_bytes_total",
+			"Total size of response bodies received from IRONdb nodes.",
+			func(s *promSeries) uint64 { return s.bytesIn },
+		},
+	}
+
+	for _, c := range counters {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s counter\n",
+			c.name, c.help, c.name)
+
+		for _, k := range keys {
+			fmt.Fprintf(buf, "%s%s %d\n", c.name, k.labels(),
+				c.value(po.series[k]))
+		}
+	}
+
+	name := "gosnowth_request_duration_seconds"
+
+	fmt.Fprintf(buf, "# HELP %s Duration of requests to IRONdb nodes.\n"+
+		"# TYPE %s histogram\n", name, name)
+
+	for _, k := range keys {
+		s := po.series[k]
+
+		for i, b := range po.buckets {
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", name,
+				k.labels("le", strconv.FormatFloat(b, 'g', -1, 64)),
+				s.durations[i])
+		}
+
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", name, k.labels("le", "+Inf"),
+			s.requests)
+		fmt.Fprintf(buf, "%s_sum%s %s\n", name, k.labels(),
+			strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(buf, "%s_count%s %d\n", name, k.labels(), s.requests)
+	}
+
+	name = "gosnowth_requests_in_flight"
+
+	fmt.Fprintf(buf, "# HELP %s Number of requests to IRONdb nodes in "+
+		"progress.\n# TYPE %s gauge\n", name, name)
+
+	for _, k := range inFlight {
+		fmt.Fprintf(buf, "%s%s %d\n", name, k.labels(), po.inFlight[k])
+	}
+
+	po.Unlock()
+
+	return buf.WriteTo(w)
+}
+
+// sortPromKeys sorts measurement keys so that output is stable.
+func sortPromKeys(keys []promKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+
+		if a.node != b.node {
+			return a.node < b.node
+		}
+
+		if a.endpoint != b.endpoint {
+			return a.endpoint < b.endpoint
+		}
+
+		if a.method != b.method {
+			return a.method < b.method
+		}
+
+		return a.status < b.status
+	})
+}
+
+// ServeHTTP writes the collected request measurements in response to an HTTP
+// request, allowing a PrometheusObserver to be used as a metrics endpoint.
+func (po *PrometheusObserver) ServeHTTP(w http.ResponseWriter,
+	r *http.Request,
+) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	_, _ = po.WriteTo(w)
+}
@@ -0,0 +1,101 @@
+package gosnowth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusObserver(t *testing.T) {
+	t.Parallel()
+
+	u, err := url.Parse("http://localhost:8112")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	po := NewPrometheusObserver(0.1, 1)
+	ev := &RequestEvent{
+		Node:     &SnowthNode{url: u},
+		Method:   "GET",
+		Endpoint: "/read/{start_ts}/{end_ts}/{uuid}/{metric}",
+	}
+
+	ctx := po.RequestStart(context.Background(), ev)
+
+	buf := &bytes.Buffer{}
+	if _, err := po.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `gosnowth_requests_in_flight{node="localhost:8112",method="GET",` +
+		`endpoint="/read/{start_ts}/{end_ts}/{uuid}/{metric}"} 1`
+	if !strings.Contains(buf.String(), exp) {
+		t.Errorf("Expected output to contain: %s, got: %s", exp, buf)
+	}
+
+	end := *ev
+	end.Status = http.StatusOK
+	end.BytesIn = 100
+	end.Duration = 500 * time.Millisecond
+	po.RequestEnd(ctx, &end)
+
+	po.RequestStart(context.Background(), ev)
+
+	end.Status = http.StatusInternalServerError
+	end.Err = errors.New("test")
+	end.Retry = 1
+	end.Duration = 50 * time.Millisecond
+	po.RequestEnd(ctx, &end)
+
+	rec := httptest.NewRecorder()
+	po.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	out := rec.Body.String()
+	labels := `{node="localhost:8112",method="GET",` +
+		`endpoint="/read/{start_ts}/{end_ts}/{uuid}/{metric}",`
+
+	for _, exp := range []string{
+		"# TYPE gosnowth_requests_total counter",
+		"gosnowth_requests_total" + labels + `status="200"} 1`,
+		"gosnowth_requests_total" + labels + `status="500"} 1`,
+		"gosnowth_request_errors_total" + labels + `status="500"} 1`,
+		"gosnowth_request_errors_total" + labels + `status="200"} 0`,
+		"gosnowth_request_retries_total" + labels + `status="500"} 1`,
+		"gosnowth_response_bytes_total" + labels + `status="200"} 100`,
+		"# TYPE gosnowth_request_duration_seconds histogram",
+		"gosnowth_request_duration_seconds_bucket" + labels +
+			`status="200",le="0.1"} 0`,
+		"gosnowth_request_duration_seconds_bucket" + labels +
+			`status="200",le="1"} 1`,
+		"gosnowth_request_duration_seconds_bucket" + labels +
+			`status="500",le="0.1"} 1`,
+		"gosnowth_request_duration_seconds_bucket" + labels +
+			`status="500",le="+Inf"} 1`,
+		"gosnowth_request_duration_seconds_sum" + labels +
+			`status="200"} 0.5`,
+		"gosnowth_request_duration_seconds_count" + labels +
+			`status="200"} 1`,
+		`gosnowth_requests_in_flight{node="localhost:8112",method="GET",` +
+			`endpoint="/read/{start_ts}/{end_ts}/{uuid}/{metric}"} 0`,
+	} {
+		if !strings.Contains(out, exp+"\n") {
+			t.Errorf("Expected output to contain: %s, got: %s", exp, out)
+		}
+	}
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct,
+		"text/plain; version=0.0.4") {
+		t.Errorf("Expected exposition content type, got: %v", ct)
+	}
+
+	if res := promEscape("a\"b\\c\nd"); res != `a\"b\\c\nd` {
+		t.Errorf("Expected escaped label value, got: %v", res)
+	}
+}
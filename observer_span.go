@@ -0,0 +1,118 @@
+package gosnowth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Span values represent a single traced operation, in the style of an
+// OpenTelemetry span. An OpenTelemetry trace.Span can be adapted to this
+// interface with a small wrapper. TraceContext returns the IDs of the span,
+// which are sent to IRONdb in the traceparent header, so that the span is the
+// parent of the spans recorded by IRONdb. If the returned trace context is not
+// valid, the request is sent with a span ID generated by the client.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+	TraceContext() TraceContext
+}
+
+// SpanTracer values start new spans, in the style of an OpenTelemetry
+// trace.Tracer. The returned context must contain the new span.
+type SpanTracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// SpanObserver values are RequestObserver values which create a client span
+// for each request sent to an IRONdb node. Span names and attributes follow
+// the OpenTelemetry HTTP client semantic conventions, with additional
+// attributes prefixed with "gosnowth.".
+type SpanObserver struct {
+	Tracer SpanTracer
+}
+
+// spanCtxKey is the context key used to store the span for a request.
+type spanCtxKey struct{}
+
+// NewSpanObserver creates a new SpanObserver which uses the provided tracer.
+func NewSpanObserver(tracer SpanTracer) *SpanObserver {
+	return &SpanObserver{Tracer: tracer}
+}
+
+// RequestStart starts a span for a request. The returned context contains the
+// trace context of the span, which is sent to IRONdb with the request.
+func (so *SpanObserver) RequestStart(ctx context.Context,
+	ev *RequestEvent,
+) context.Context {
+	if so.Tracer == nil {
+		return ctx
+	}
+
+	ctx, span := so.Tracer.StartSpan(ctx, ev.Method+" "+ev.Endpoint)
+	if span == nil {
+		return ctx
+	}
+
+	spanID := ev.SpanID
+
+	if tc := span.TraceContext(); tc.Valid() {
+		ctx = ContextWithTraceContext(ctx, tc)
+		spanID = tc.SpanID
+	}
+
+	span.SetAttribute("http.request.method", ev.Method)
+	span.SetAttribute("url.full", ev.URL)
+	span.SetAttribute("http.request.body.size", ev.BytesOut)
+	span.SetAttribute("gosnowth.endpoint", ev.Endpoint)
+	span.SetAttribute("gosnowth.trace_id", ev.TraceID)
+	span.SetAttribute("gosnowth.span_id", spanID)
+	span.SetAttribute("gosnowth.retry", ev.Retry)
+	span.SetAttribute("gosnowth.conn_retry", ev.ConnRetry)
+
+	if ev.Node != nil && ev.Node.GetURL() != nil {
+		span.SetAttribute("server.address", ev.Node.GetURL().Hostname())
+
+		if p := ev.Node.GetURL().Port(); p != "" {
+			span.SetAttribute("server.port", p)
+		}
+
+		if id := ev.Node.id(); id != "" {
+			span.SetAttribute("gosnowth.node_id", id)
+		}
+	}
+
+	return context.WithValue(ctx, spanCtxKey{}, span)
+}
+
+// RequestEnd ends the span for a request.
+func (so *SpanObserver) RequestEnd(ctx context.Context, ev *RequestEvent) {
+	span, ok := ctx.Value(spanCtxKey{}).(Span)
+	if !ok {
+		return
+	}
+
+	if ev.Status != 0 {
+		span.SetAttribute("http.response.status_code", ev.Status)
+	}
+
+	span.SetAttribute("http.response.body.size", ev.BytesIn)
+
+	if ev.Err != nil {
+		span.SetAttribute("error.type", errorType(ev))
+		span.RecordError(ev.Err)
+	}
+
+	span.End()
+}
+
+// errorType returns the value of the error.type span attribute for a failed
+// request.
+func errorType(ev *RequestEvent) string {
+	if ev.Status != 0 {
+		return strconv.Itoa(ev.Status)
+	}
+
+	return fmt.Sprintf("%T", ev.Err)
+}
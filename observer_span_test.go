@@ -0,0 +1,210 @@
+package gosnowth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+	tc    TraceContext
+}
+
+func (ts *testSpan) SetAttribute(key string, value interface{}) {
+	ts.attrs[key] = value
+}
+
+func (ts *testSpan) RecordError(err error) {
+	ts.err = err
+}
+
+func (ts *testSpan) End() {
+	ts.ended = true
+}
+
+func (ts *testSpan) TraceContext() TraceContext {
+	return ts.tc
+}
+
+type testTracer struct {
+	sync.Mutex
+	spans []*testSpan
+}
+
+func (tt *testTracer) StartSpan(ctx context.Context,
+	name string,
+) (context.Context, Span) {
+	tt.Lock()
+	defer tt.Unlock()
+
+	// Spans are children of the trace context, or start a new trace.
+	tc := TraceContext{TraceID: newTraceID(16)}
+	if parent, ok := TraceContextFromContext(ctx); ok {
+		tc = parent
+	}
+
+	tc.SpanID = newTraceID(8)
+
+	s := &testSpan{name: name, attrs: map[string]interface{}{}, tc: tc}
+	tt.spans = append(tt.spans, s)
+
+	return ctx, s
+}
+
+func TestSpanObserver(t *testing.T) {
+	t.Parallel()
+
+	u, err := url.Parse("http://localhost:8112")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := &testTracer{}
+	so := NewSpanObserver(tr)
+	ev := &RequestEvent{
+		TraceID:  "1",
+		Node:     &SnowthNode{url: u, identifier: "abc"},
+		Method:   "POST",
+		Endpoint: "/fetch",
+		URL:      "http://localhost:8112/fetch",
+		BytesOut: 10,
+		Retry:    1,
+	}
+
+	ctx := so.RequestStart(context.Background(), ev)
+
+	end := *ev
+	end.Status = 503
+	end.BytesIn = 5
+	end.Err = errors.New("test")
+	so.RequestEnd(ctx, &end)
+
+	if len(tr.spans) != 1 {
+		t.Fatalf("Expected spans: 1, got: %v", len(tr.spans))
+	}
+
+	s := tr.spans[0]
+
+	if s.name != "POST /fetch" {
+		t.Errorf("Expected span name: POST /fetch, got: %v", s.name)
+	}
+
+	if !s.ended || s.err == nil {
+		t.Errorf("Expected ended span with error, got: %+v", s)
+	}
+
+	exp := map[string]interface{}{
+		"http.request.method":       "POST",
+		"url.full":                  "http://localhost:8112/fetch",
+		"server.address":            "localhost",
+		"server.port":               "8112",
+		"http.request.body.size":    int64(10),
+		"http.response.body.size":   int64(5),
+		"http.response.status_code": 503,
+		"error.type":                "503",
+		"gosnowth.retry":            int64(1),
+		"gosnowth.node_id":          "abc",
+		"gosnowth.trace_id":         "1",
+	}
+
+	for k, v := range exp {
+		if s.attrs[k] != v {
+			t.Errorf("Expected attribute %s: %v, got: %v", k, v, s.attrs[k])
+		}
+	}
+
+	so.RequestEnd(context.Background(), &end)
+
+	if ctx := NewSpanObserver(nil).RequestStart(context.Background(),
+		ev); ctx == nil {
+		t.Error("Expected context without tracer")
+	}
+}
+
+func TestSpanObserverPropagation(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+
+	received := []string{}
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		mu.Lock()
+		received = append(received, r.Header.Get(TraceParentHeader))
+		mu.Unlock()
+
+		_, _ = w.Write([]byte(stateTestData))
+	}))
+
+	defer ms.Close()
+
+	tr := &testTracer{}
+	cfg := NewConfig(ms.URL)
+	cfg.RequestObserver = NewSpanObserver(tr)
+
+	sc, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	node := sc.ListActiveNodes()[0]
+	parent := TraceContext{
+		TraceID: "0af7651916cd43dd8448eb211c80319c",
+		SpanID:  "b7ad6b7169203331",
+	}
+
+	ctx := ContextWithTraceContext(context.Background(), parent)
+
+	if _, err := sc.GetNodeStateContext(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a trace context, the span of the attempt starts a new trace.
+	if _, err := sc.GetNodeStateContext(context.Background(),
+		node); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	tr.Lock()
+	defer tr.Unlock()
+
+	if len(received) != 2 || len(tr.spans) != 2 {
+		t.Fatalf("Expected requests and spans: 2, got: %v, %v",
+			len(received), len(tr.spans))
+	}
+
+	if tc := tr.spans[0].tc; tc.TraceID != parent.TraceID {
+		t.Errorf("Expected span trace ID: %v, got: %v", parent.TraceID,
+			tc.TraceID)
+	}
+
+	for i, s := range tr.spans {
+		if exp := s.tc.TraceParent(); received[i] != exp {
+			t.Errorf("Expected traceparent: %v, got: %v", exp, received[i])
+		}
+
+		if s.attrs["gosnowth.span_id"] != s.tc.SpanID {
+			t.Errorf("Expected span ID attribute: %v, got: %v", s.tc.SpanID,
+				s.attrs["gosnowth.span_id"])
+		}
+	}
+}
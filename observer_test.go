@@ -0,0 +1,176 @@
+package gosnowth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type testObserverKey struct{}
+
+type testObserver struct {
+	sync.Mutex
+	starts []RequestEvent
+	ends   []RequestEvent
+}
+
+func (to *testObserver) RequestStart(ctx context.Context,
+	ev *RequestEvent,
+) context.Context {
+	to.Lock()
+	defer to.Unlock()
+
+	to.starts = append(to.starts, *ev)
+
+	return context.WithValue(ctx, testObserverKey{}, len(to.starts))
+}
+
+func (to *testObserver) RequestEnd(ctx context.Context, ev *RequestEvent) {
+	to.Lock()
+	defer to.Unlock()
+
+	if n, ok := ctx.Value(testObserverKey{}).(int); !ok || n != len(to.starts) {
+		ev.Err = nil
+		ev.Status = -1
+	}
+
+	to.ends = append(to.ends, *ev)
+}
+
+func TestEndpointTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ref, exp string
+	}{
+		{"/stats.json", "/stats.json"},
+		{"/state", "/state"},
+		{
+			"/read/1/2/60/3aa57ac2-28de-4ec4-aa3d-ed0ddd48fa4d/count/a|ST[b:c]",
+			"/read/{start_ts}/{end_ts}/{period}/{uuid}/{type}/{metric}",
+		},
+		{
+			"/read/1/2/3aa57ac2-28de-4ec4-aa3d-ed0ddd48fa4d/a/b/c",
+			"/read/{start_ts}/{end_ts}/{uuid}/{metric}",
+		},
+		{"/histogram/write", "/histogram/write"},
+		{
+			"/histogram/1/2/60/3aa57ac2-28de-4ec4-aa3d-ed0ddd48fa4d/test",
+			"/histogram/{start_ts}/{end_ts}/{period}/{uuid}/{metric}",
+		},
+		{
+			"/rollup/3aa57ac2-28de-4ec4-aa3d-ed0ddd48fa4d/test?start_ts=1",
+			"/rollup/{uuid}/{metric}",
+		},
+		{"/find/1/tags?query=and(a:b)", "/find/{account}/tags"},
+		{"/find/x/tags", "/find/*"},
+		{"/graphite/1/test/metrics/find?query=a.*", "/graphite/{account}/" +
+			"{prefix}/metrics/find"},
+		{"/extension/lua/public/caql_v1", "/extension/lua/public/caql_v1"},
+		{"/extension/lua/test", "/extension/lua/{name}"},
+		{"/write/numeric", "/write/*"},
+		{"/fetch", "/fetch"},
+	}
+
+	for _, tt := range tests {
+		if res := endpointTemplate(tt.ref); res != tt.exp {
+			t.Errorf("Expected endpoint for %s: %v, got: %v", tt.ref, tt.exp,
+				res)
+		}
+	}
+}
+
+func TestRequestObserver(t *testing.T) {
+	t.Parallel()
+
+	var failures int32 = 1
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(stateTestData))
+	}))
+
+	defer ms.Close()
+
+	obs := &testObserver{}
+	cfg := NewConfig(ms.URL)
+	cfg.RequestObserver = obs
+	cfg.RetryPolicy = &DefaultRetryPolicy{}
+	cfg.Retries = 1
+
+	sc, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	u, err := url.Parse(ms.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	node := &SnowthNode{url: u}
+
+	if _, err := sc.GetNodeState(node); err != nil {
+		t.Fatal(err)
+	}
+
+	obs.Lock()
+	defer obs.Unlock()
+
+	if len(obs.starts) != 2 || len(obs.ends) != 2 {
+		t.Fatalf("Expected events: 2, got: %v starts, %v ends",
+			len(obs.starts), len(obs.ends))
+	}
+
+	first, last := obs.ends[0], obs.ends[1]
+
+	if first.Status != http.StatusServiceUnavailable || first.Err == nil {
+		t.Errorf("Expected failed first attempt, got: %v %v", first.Status,
+			first.Err)
+	}
+
+	if last.Status != http.StatusOK || last.Err != nil {
+		t.Errorf("Expected successful retry, got: %v %v", last.Status,
+			last.Err)
+	}
+
+	if last.Retry != 1 || obs.starts[1].Retry != 1 || first.Retry != 0 {
+		t.Errorf("Expected retry counts: 0, 1, got: %v, %v", first.Retry,
+			last.Retry)
+	}
+
+	if last.Endpoint != "/state" || last.Method != "GET" {
+		t.Errorf("Expected endpoint: GET /state, got: %v %v", last.Method,
+			last.Endpoint)
+	}
+
+	if last.BytesIn != int64(len(stateTestData)) {
+		t.Errorf("Expected bytes in: %v, got: %v", len(stateTestData),
+			last.BytesIn)
+	}
+
+	if last.Duration <= 0 || last.Start.IsZero() || last.TraceID == "" {
+		t.Errorf("Expected duration, start and trace ID, got: %+v", last)
+	}
+
+	if last.Node.GetURL().String() != ms.URL {
+		t.Errorf("Expected node: %v, got: %v", ms.URL, last.Node.GetURL())
+	}
+}
@@ -0,0 +1,208 @@
+package gosnowth
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// CallOption values modify the behavior of a single call to a *Context method
+// of a SnowthClient. A *SnowthNode value is also a CallOption, equivalent to
+// WithNode(), so that a node can be passed to these methods directly.
+type CallOption interface {
+	applyCallOption(co *callOptions)
+}
+
+// callOptions values contain the settings applied by call options.
+type callOptions struct {
+	node         *SnowthNode
+	timeout      time.Duration
+	retries      int64
+	hasRetries   bool
+	headers      http.Header
+	trace        *bool
+	dump         *bool
+	account      int64
+	hasAccount   bool
+	tc           *TraceContext
+	report       *WriteReport
+	misdirect    int
+	hasMisdirect bool
+	noSpool      bool
+	pinned       bool
+}
+
+// callOptionFunc values implement CallOption using a function.
+type callOptionFunc func(co *callOptions)
+
+// applyCallOption applies the option to the call options.
+func (f callOptionFunc) applyCallOption(co *callOptions) {
+	f(co)
+}
+
+// applyCallOption sets the node as the node receiving the request.
+func (sn *SnowthNode) applyCallOption(co *callOptions) {
+	if sn != nil {
+		co.node = sn
+	}
+}
+
+// WithNode sets the node which receives the request. If not set, or if the
+// node is nil, an active node is chosen by the client.
+func WithNode(node *SnowthNode) CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		if node != nil {
+			co.node = node
+		}
+	})
+}
+
+// WithTimeout limits the duration of the call, including any retries. The
+// X-Snowth-Timeout header sent to IRONdb is derived from the resulting
+// context deadline.
+func WithTimeout(d time.Duration) CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		co.timeout = d
+	})
+}
+
+// WithRetries sets the number of retries attempted for the call when errors
+// other than connection errors occur, in place of the client Retries()
+// setting.
+func WithRetries(n int64) CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		co.retries, co.hasRetries = n, true
+	})
+}
+
+// WithHeader adds a header value to the requests made by the call.
+func WithHeader(key, value string) CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		if co.headers == nil {
+			co.headers = http.Header{}
+		}
+
+		co.headers.Add(key, value)
+	})
+}
+
+// WithTrace enables or disables logging of the HTTP trace of the requests
+// made by the call, in place of the GOSNOWTH_TRACE_REQUESTS environment
+// setting.
+func WithTrace(enabled bool) CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		co.trace = &enabled
+	})
+}
+
+// WithDump enables or disables dumping the full requests made by the call to
+// the client debug log, in place of the GOSNOWTH_DUMP_REQUESTS environment
+// setting.
+func WithDump(enabled bool) CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		co.dump = &enabled
+	})
+}
+
+// WithTraceContext sets the W3C trace context propagated to IRONdb by the
+// requests made by the call. This is equivalent to calling the method with a
+// context returned by ContextWithTraceContext().
+func WithTraceContext(tc TraceContext) CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		co.tc = &tc
+	})
+}
+
+// WithAccount sets the account ID used by queries which do not contain one,
+// such as CAQL and PromQL queries with an empty account ID.
+func WithAccount(id int64) CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		co.account, co.hasAccount = id, true
+	})
+}
+
+// callOptionsKey is the context key used to store call options.
+type callOptionsKey struct{}
+
+// callOptionsFromContext returns the call options stored in a context.
+func callOptionsFromContext(ctx context.Context) *callOptions {
+	if ctx == nil {
+		return &callOptions{}
+	}
+
+	if co, ok := ctx.Value(callOptionsKey{}).(*callOptions); ok {
+		return co
+	}
+
+	return &callOptions{}
+}
+
+// accountFromContext returns the account ID set by the WithAccount() call
+// option, if any.
+func accountFromContext(ctx context.Context) (int64, bool) {
+	co := callOptionsFromContext(ctx)
+
+	return co.account, co.hasAccount
+}
+
+// callContext applies call options to a context. The options are combined
+// with any options already stored in the context, except for the node, which
+// is returned separately and is nil if none was provided. The returned
+// cancel function must be called when the call completes.
+func (sc *SnowthClient) callContext(ctx context.Context,
+	opts []CallOption,
+) (context.Context, *SnowthNode, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(opts) == 0 {
+		return ctx, nil, func() {}
+	}
+
+	parent := callOptionsFromContext(ctx)
+	co := *parent
+	co.node, co.timeout, co.report = nil, 0, nil
+	co.headers = parent.headers.Clone()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt.applyCallOption(&co)
+		}
+	}
+
+	node := co.node
+	co.node = nil
+
+	if co.tc != nil {
+		ctx = ContextWithTraceContext(ctx, *co.tc)
+	}
+
+	ctx = context.WithValue(ctx, callOptionsKey{}, &co)
+
+	if co.timeout > 0 {
+		tCtx, cancel := context.WithTimeout(ctx, co.timeout)
+
+		return tCtx, node, cancel
+	}
+
+	return ctx, node, func() {}
+}
+
+// nodeOptions converts the nodes parameter of methods which do not accept
+// call options into call options. As before, only the first node is used.
+func nodeOptions(nodes []*SnowthNode) []CallOption {
+	if len(nodes) == 0 || nodes[0] == nil {
+		return nil
+	}
+
+	return []CallOption{nodes[0]}
+}
+
+// pinNode sends the requests made by a call only to the node set for the
+// call, without failing over to other nodes or checking its circuit breaker.
+func pinNode() CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		co.pinned = true
+	})
+}
@@ -0,0 +1,162 @@
+package gosnowth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallOptions(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		lastReq  *http.Request
+		lastBody string
+		failures int32
+	)
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		b, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		lastReq, lastBody = r, string(b)
+		mu.Unlock()
+
+		switch {
+		case r.RequestURI == "/stats.json":
+			_, _ = w.Write([]byte(statsTestData))
+		case r.RequestURI == "/state":
+			if r.Header.Get("X-Fail") != "" {
+				atomic.AddInt32(&failures, 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			_, _ = w.Write([]byte(stateTestData))
+		case strings.HasPrefix(r.RequestURI, "/extension/lua/public/caql_v1"):
+			_, _ = w.Write([]byte(testDF4Response))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer ms.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	sc.SetRetryPolicy(&DefaultRetryPolicy{BaseDelay: time.Millisecond})
+
+	u, err := url.Parse(ms.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	node := &SnowthNode{url: u}
+
+	if _, err := sc.GetNodeStateContext(context.Background(), WithNode(node),
+		WithHeader("X-Test", "a"), WithHeader("X-Test", "b"),
+		WithTimeout(3*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+
+	if v := lastReq.Header.Values("X-Test"); len(v) != 2 {
+		t.Errorf("Expected header values: [a b], got: %v", v)
+	}
+
+	to, err := time.ParseDuration(lastReq.Header.Get("X-Snowth-Timeout"))
+	if err != nil || to > 2*time.Second || to < time.Second {
+		t.Errorf("Expected timeout header under 2s, got: %v",
+			lastReq.Header.Get("X-Snowth-Timeout"))
+	}
+
+	mu.Unlock()
+
+	if _, err := sc.GetNodeStateContext(context.Background(), node,
+		WithHeader("X-Fail", "1"), WithRetries(2)); err == nil {
+		t.Fatal("Expected error")
+	}
+
+	if n := atomic.LoadInt32(&failures); n != 3 {
+		t.Errorf("Expected attempts: 3, got: %v", n)
+	}
+
+	if _, err := sc.GetCAQLQueryContext(context.Background(),
+		&CAQLQuery{Query: "test", Start: 1, End: 2, Period: 1}, node,
+		WithAccount(42)); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+
+	if !strings.Contains(lastBody, `"account_id":"42"`) {
+		t.Errorf("Expected account ID in query, got: %v", lastBody)
+	}
+
+	mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	<-ctx.Done()
+
+	if _, err := sc.GetNodeStateContext(ctx, node); err == nil {
+		t.Error("Expected error for expired context")
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	t.Parallel()
+
+	sc := &SnowthClient{}
+	node := &SnowthNode{url: &url.URL{Host: "localhost:8112"}}
+
+	ctx, n, cancel := sc.callContext(context.Background(), []CallOption{
+		WithHeader("A", "1"), WithAccount(1), WithNode(node),
+	})
+	defer cancel()
+
+	if n != node {
+		t.Errorf("Expected node: %v, got: %v", node, n)
+	}
+
+	ctx, n, cancel2 := sc.callContext(ctx, []CallOption{
+		WithHeader("B", "2"), WithTimeout(time.Minute), nil,
+		(*SnowthNode)(nil),
+	})
+	defer cancel2()
+
+	if n != nil {
+		t.Errorf("Expected no node, got: %v", n)
+	}
+
+	co := callOptionsFromContext(ctx)
+	if co.headers.Get("A") != "1" || co.headers.Get("B") != "2" ||
+		!co.hasAccount {
+		t.Errorf("Expected inherited options, got: %+v", co)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		t.Error("Expected context deadline")
+	}
+
+	if opts := nodeOptions([]*SnowthNode{nil, node}); len(opts) != 0 {
+		t.Errorf("Expected no options, got: %v", opts)
+	}
+}
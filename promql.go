@@ -55,6 +55,9 @@ type PromQLError struct {
 	ErrorType string      `json:"errorType,omitempty"`
 	Err       string      `json:"error,omitempty"`
 	Warnings  []string    `json:"warnings,omitempty"`
+
+	// err is the error which caused the PromQL error.
+	err error
 }
 
 // String returns this value as a JSON format string.
@@ -76,32 +79,42 @@ func (pe *PromQLError) Error() string {
 	return pe.String()
 }
 
+// Is reports whether the error matches a target error. A PromQL error
+// caused by a CAQL user error matches ErrInvalidQuery.
+func (pe *PromQLError) Is(target error) bool {
+	return target == ErrInvalidQuery && pe.ErrorType == "caql"
+}
+
+// Unwrap returns the error which caused the PromQL error.
+func (pe *PromQLError) Unwrap() error {
+	return pe.err
+}
+
 // PromQLInstantQuery evaluates a PromQL query at a single point in time.
 func (sc *SnowthClient) PromQLInstantQuery(query *PromQLInstantQuery,
 	nodes ...*SnowthNode,
 ) (*PromQLResponse, error) {
-	return sc.PromQLInstantQueryContext(context.Background(), query, nodes...)
+	return sc.PromQLInstantQueryContext(context.Background(), query, nodeOptions(nodes)...)
 }
 
 // PromQLInstantQueryContext is the context aware version of PromQLInstantQuery.
 func (sc *SnowthClient) PromQLInstantQueryContext(ctx context.Context,
 	query *PromQLInstantQuery,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*PromQLResponse, error) {
-	var node *SnowthNode
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
 
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	if query == nil {
-		return nil, fmt.Errorf("invalid PromQL query: null")
+		return nil, invalidQueryf("invalid PromQL query: null")
 	}
 
 	u := "/extension/lua/public/caql_v1"
@@ -116,11 +129,13 @@ func (sc *SnowthClient) PromQLInstantQueryContext(ctx context.Context,
 		i, err := strconv.ParseInt(query.AccountID, 10, 64)
 		if err != nil {
 			return nil,
-				fmt.Errorf("invalid PromQL query: invalid account_id: %v",
+				invalidQueryf("invalid PromQL query: invalid account_id: %v",
 					query.AccountID)
 		}
 
 		q.AccountID = i
+	} else if id, ok := accountFromContext(ctx); ok {
+		q.AccountID = id
 	}
 
 	if query.Time != "" {
@@ -128,7 +143,7 @@ func (sc *SnowthClient) PromQLInstantQueryContext(ctx context.Context,
 			f, err := strconv.ParseFloat(query.Time, 64)
 			if err != nil {
 				return nil,
-					fmt.Errorf("invalid PromQL query: invalid time: %v",
+					invalidQueryf("invalid PromQL query: invalid time: %v",
 						query.Time)
 			}
 
@@ -145,7 +160,7 @@ func (sc *SnowthClient) PromQLInstantQueryContext(ctx context.Context,
 			f, err := strconv.ParseFloat(query.Timeout, 64)
 			if err != nil {
 				return nil,
-					fmt.Errorf("invalid PromQL range query: invalid timeout: %v",
+					invalidQueryf("invalid PromQL range query: invalid timeout: %v",
 						query.Timeout)
 			}
 
@@ -209,6 +224,7 @@ func (sc *SnowthClient) PromQLInstantQueryContext(ctx context.Context,
 			Status:    r.Status,
 			ErrorType: r.ErrorType,
 			Err:       r.Error,
+			err:       rErr,
 		}
 	} else {
 		if err := decodeJSON(bytes.NewBuffer(buf), &r); err != nil {
@@ -224,28 +240,27 @@ func (sc *SnowthClient) PromQLInstantQueryContext(ctx context.Context,
 func (sc *SnowthClient) PromQLRangeQuery(query *PromQLRangeQuery,
 	nodes ...*SnowthNode,
 ) (*PromQLResponse, error) {
-	return sc.PromQLRangeQueryContext(context.Background(), query, nodes...)
+	return sc.PromQLRangeQueryContext(context.Background(), query, nodeOptions(nodes)...)
 }
 
 // PromQLRangeQueryContext is the context aware version of PromQLRangeQuery.
 func (sc *SnowthClient) PromQLRangeQueryContext(ctx context.Context,
 	query *PromQLRangeQuery,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*PromQLResponse, error) {
-	var node *SnowthNode
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
 
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	if query == nil {
-		return nil, fmt.Errorf("invalid PromQL range query: null")
+		return nil, invalidQueryf("invalid PromQL range query: null")
 	}
 
 	u := "/extension/lua/public/caql_v1"
@@ -259,11 +274,13 @@ func (sc *SnowthClient) PromQLRangeQueryContext(ctx context.Context,
 		i, err := strconv.ParseInt(query.AccountID, 10, 64)
 		if err != nil {
 			return nil,
-				fmt.Errorf("invalid PromQL range query: invalid account_id: %v",
+				invalidQueryf("invalid PromQL range query: invalid account_id: %v",
 					query.AccountID)
 		}
 
 		q.AccountID = i
+	} else if id, ok := accountFromContext(ctx); ok {
+		q.AccountID = id
 	}
 
 	if query.Start != "" {
@@ -271,7 +288,7 @@ func (sc *SnowthClient) PromQLRangeQueryContext(ctx context.Context,
 			f, err := strconv.ParseFloat(query.Start, 64)
 			if err != nil {
 				return nil,
-					fmt.Errorf("invalid PromQL range query: invalid start: %v",
+					invalidQueryf("invalid PromQL range query: invalid start: %v",
 						query.Start)
 			}
 
@@ -286,7 +303,7 @@ func (sc *SnowthClient) PromQLRangeQueryContext(ctx context.Context,
 			f, err := strconv.ParseFloat(query.End, 64)
 			if err != nil {
 				return nil,
-					fmt.Errorf("invalid PromQL range query: invalid end: %v",
+					invalidQueryf("invalid PromQL range query: invalid end: %v",
 						query.End)
 			}
 
@@ -301,7 +318,7 @@ func (sc *SnowthClient) PromQLRangeQueryContext(ctx context.Context,
 			f, err := strconv.ParseFloat(query.Step, 64)
 			if err != nil {
 				return nil,
-					fmt.Errorf("invalid PromQL range query: invalid step: %v",
+					invalidQueryf("invalid PromQL range query: invalid step: %v",
 						query.Step)
 			}
 
@@ -316,7 +333,7 @@ func (sc *SnowthClient) PromQLRangeQueryContext(ctx context.Context,
 			f, err := strconv.ParseFloat(query.Timeout, 64)
 			if err != nil {
 				return nil,
-					fmt.Errorf("invalid PromQL range query: invalid timeout: %v",
+					invalidQueryf("invalid PromQL range query: invalid timeout: %v",
 						query.Timeout)
 			}
 
@@ -380,6 +397,7 @@ func (sc *SnowthClient) PromQLRangeQueryContext(ctx context.Context,
 			Status:    r.Status,
 			ErrorType: r.ErrorType,
 			Err:       r.Error,
+			err:       rErr,
 		}
 	} else {
 		if err := decodeJSON(bytes.NewBuffer(buf), &r); err != nil {
@@ -597,40 +615,39 @@ type PromQLSeriesQuery struct {
 func (sc *SnowthClient) PromQLSeriesQuery(query *PromQLSeriesQuery,
 	nodes ...*SnowthNode,
 ) (*PromQLResponse, error) {
-	return sc.PromQLSeriesQueryContext(context.Background(), query, nodes...)
+	return sc.PromQLSeriesQueryContext(context.Background(), query, nodeOptions(nodes)...)
 }
 
 // PromQLSeriesQueryContext is the context aware version of PromQLSeriesQuery.
 func (sc *SnowthClient) PromQLSeriesQueryContext( //nolint:gocyclo,maintidx
 	ctx context.Context,
 	query *PromQLSeriesQuery,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*PromQLResponse, error) {
-	var node *SnowthNode
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
 
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	if query == nil {
-		return nil, fmt.Errorf("invalid PromQL series query: null")
+		return nil, invalidQueryf("invalid PromQL series query: null")
 	}
 
 	aID := int64(0)
 
-	opts := &FindTagsOptions{
+	tagOpts := &FindTagsOptions{
 		Activity: 0,
 		Latest:   0,
 	}
 
 	if len(query.Match) == 0 {
-		return nil, fmt.Errorf("invalid PromQL series query: missing match[]")
+		return nil, invalidQueryf("invalid PromQL series query: missing match[]")
 	}
 
 	terms := []string{}
@@ -638,7 +655,7 @@ func (sc *SnowthClient) PromQLSeriesQueryContext( //nolint:gocyclo,maintidx
 	for _, sel := range query.Match {
 		mt, err := ConvertSeriesSelector(sel)
 		if err != nil {
-			return nil, fmt.Errorf("invalid PromQL series query: "+
+			return nil, invalidQueryf("invalid PromQL series query: "+
 				"invalid series selector: %s: %w", sel, err)
 		}
 
@@ -646,7 +663,7 @@ func (sc *SnowthClient) PromQLSeriesQueryContext( //nolint:gocyclo,maintidx
 	}
 
 	if len(terms) == 0 {
-		return nil, fmt.Errorf("invalid PromQL series query: missing match[]")
+		return nil, invalidQueryf("invalid PromQL series query: missing match[]")
 	}
 
 	q := "or("
@@ -665,11 +682,13 @@ func (sc *SnowthClient) PromQLSeriesQueryContext( //nolint:gocyclo,maintidx
 		i, err := strconv.ParseInt(query.AccountID, 10, 64)
 		if err != nil {
 			return nil,
-				fmt.Errorf("invalid PromQL series query: invalid account_id: %v",
+				invalidQueryf("invalid PromQL series query: invalid account_id: %v",
 					query.AccountID)
 		}
 
 		aID = i
+	} else if id, ok := accountFromContext(ctx); ok {
+		aID = id
 	}
 
 	if query.Start != "" {
@@ -677,13 +696,13 @@ func (sc *SnowthClient) PromQLSeriesQueryContext( //nolint:gocyclo,maintidx
 			f, err := strconv.ParseFloat(query.Start, 64)
 			if err != nil {
 				return nil,
-					fmt.Errorf("invalid PromQL series query: invalid start: %v",
+					invalidQueryf("invalid PromQL series query: invalid start: %v",
 						query.Start)
 			}
 
-			opts.Start = time.Unix(int64(f), 0)
+			tagOpts.Start = time.Unix(int64(f), 0)
 		} else {
-			opts.Start = t
+			tagOpts.Start = t
 		}
 	}
 
@@ -692,17 +711,17 @@ func (sc *SnowthClient) PromQLSeriesQueryContext( //nolint:gocyclo,maintidx
 			f, err := strconv.ParseFloat(query.End, 64)
 			if err != nil {
 				return nil,
-					fmt.Errorf("invalid PromQL series query: invalid end: %v",
+					invalidQueryf("invalid PromQL series query: invalid end: %v",
 						query.End)
 			}
 
-			opts.End = time.Unix(int64(f), 0)
+			tagOpts.End = time.Unix(int64(f), 0)
 		} else {
-			opts.End = t
+			tagOpts.End = t
 		}
 	}
 
-	res, err := sc.FindTagsContext(ctx, aID, q, opts, node)
+	res, err := sc.FindTagsContext(ctx, aID, q, tagOpts, node)
 	if err != nil {
 		r := &PromQLResponse{
 			Status:    "error",
@@ -714,6 +733,7 @@ func (sc *SnowthClient) PromQLSeriesQueryContext( //nolint:gocyclo,maintidx
 			Status:    r.Status,
 			ErrorType: r.ErrorType,
 			Err:       r.Error,
+			err:       err,
 		}
 
 		return r, rErr
@@ -819,28 +839,27 @@ type PromQLLabelQuery struct {
 func (sc *SnowthClient) PromQLLabelQuery(query *PromQLLabelQuery,
 	nodes ...*SnowthNode,
 ) (*PromQLResponse, error) {
-	return sc.PromQLLabelQueryContext(context.Background(), query, nodes...)
+	return sc.PromQLLabelQueryContext(context.Background(), query, nodeOptions(nodes)...)
 }
 
 // PromQLLabelQueryContext is the context aware version of PromQLLabelQuery.
 func (sc *SnowthClient) PromQLLabelQueryContext(ctx context.Context,
 	query *PromQLLabelQuery,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*PromQLResponse, error) {
-	var node *SnowthNode
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
 
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	if query == nil {
-		return nil, fmt.Errorf("invalid PromQL label query: null")
+		return nil, invalidQueryf("invalid PromQL label query: null")
 	}
 
 	aID := int64(0)
@@ -850,7 +869,7 @@ func (sc *SnowthClient) PromQLLabelQueryContext(ctx context.Context,
 	for _, sel := range query.Match {
 		mt, err := ConvertSeriesSelector(sel)
 		if err != nil {
-			return nil, fmt.Errorf("invalid PromQL label query: "+
+			return nil, invalidQueryf("invalid PromQL label query: "+
 				"invalid series selector: %s: %w", sel, err)
 		}
 
@@ -877,11 +896,13 @@ func (sc *SnowthClient) PromQLLabelQueryContext(ctx context.Context,
 		i, err := strconv.ParseInt(query.AccountID, 10, 64)
 		if err != nil {
 			return nil,
-				fmt.Errorf("invalid PromQL label query: invalid account_id: %v",
+				invalidQueryf("invalid PromQL label query: invalid account_id: %v",
 					query.AccountID)
 		}
 
 		aID = i
+	} else if id, ok := accountFromContext(ctx); ok {
+		aID = id
 	}
 
 	res, err := sc.FindTagCatsContext(ctx, aID, q, node)
@@ -896,6 +917,7 @@ func (sc *SnowthClient) PromQLLabelQueryContext(ctx context.Context,
 			Status:    r.Status,
 			ErrorType: r.ErrorType,
 			Err:       r.Error,
+			err:       err,
 		}
 
 		return r, rErr
@@ -921,7 +943,7 @@ func (sc *SnowthClient) PromQLLabelValuesQuery(label string,
 	nodes ...*SnowthNode,
 ) (*PromQLResponse, error) {
 	return sc.PromQLLabelValuesQueryContext(context.Background(), label,
-		query, nodes...)
+		query, nodeOptions(nodes)...)
 }
 
 // PromQLLabelValuesQueryContext is the context aware version of
@@ -929,27 +951,26 @@ func (sc *SnowthClient) PromQLLabelValuesQuery(label string,
 func (sc *SnowthClient) PromQLLabelValuesQueryContext(ctx context.Context,
 	label string,
 	query *PromQLLabelQuery,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*PromQLResponse, error) {
-	var node *SnowthNode
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
 
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	if label == "" {
-		return nil, fmt.Errorf("invalid PromQL label values query: " +
+		return nil, invalidQueryf("invalid PromQL label values query: " +
 			"missing label name")
 	}
 
 	if query == nil {
-		return nil, fmt.Errorf("invalid PromQL label values query: null")
+		return nil, invalidQueryf("invalid PromQL label values query: null")
 	}
 
 	aID := int64(0)
@@ -959,7 +980,7 @@ func (sc *SnowthClient) PromQLLabelValuesQueryContext(ctx context.Context,
 	for _, sel := range query.Match {
 		mt, err := ConvertSeriesSelector(sel)
 		if err != nil {
-			return nil, fmt.Errorf("invalid PromQL label query: "+
+			return nil, invalidQueryf("invalid PromQL label query: "+
 				"invalid series selector: %s: %w", sel, err)
 		}
 
@@ -986,11 +1007,13 @@ func (sc *SnowthClient) PromQLLabelValuesQueryContext(ctx context.Context,
 		i, err := strconv.ParseInt(query.AccountID, 10, 64)
 		if err != nil {
 			return nil,
-				fmt.Errorf("invalid PromQL label values query: "+
+				invalidQueryf("invalid PromQL label values query: "+
 					"invalid account_id: %v", query.AccountID)
 		}
 
 		aID = i
+	} else if id, ok := accountFromContext(ctx); ok {
+		aID = id
 	}
 
 	if label == "__name__" {
@@ -1009,6 +1032,7 @@ func (sc *SnowthClient) PromQLLabelValuesQueryContext(ctx context.Context,
 			Status:    r.Status,
 			ErrorType: r.ErrorType,
 			Err:       r.Error,
+			err:       err,
 		}
 
 		return r, rErr
@@ -1035,34 +1059,33 @@ type PromQLMetadataQuery struct {
 func (sc *SnowthClient) PromQLMetadataQuery(query *PromQLMetadataQuery,
 	nodes ...*SnowthNode,
 ) (*PromQLResponse, error) {
-	return sc.PromQLMetadataQueryContext(context.Background(), query, nodes...)
+	return sc.PromQLMetadataQueryContext(context.Background(), query, nodeOptions(nodes)...)
 }
 
 // PromQLMetadataQueryContext is the context aware version of PromQLMetadataQuery.
 func (sc *SnowthClient) PromQLMetadataQueryContext(
 	ctx context.Context,
 	query *PromQLMetadataQuery,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*PromQLResponse, error) {
-	var node *SnowthNode
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
 
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	if query == nil {
-		return nil, fmt.Errorf("invalid PromQL metadata query: null")
+		return nil, invalidQueryf("invalid PromQL metadata query: null")
 	}
 
 	aID := int64(0)
 
-	opts := &FindTagsOptions{
+	tagOpts := &FindTagsOptions{
 		Activity: 0,
 		Latest:   0,
 	}
@@ -1072,7 +1095,7 @@ func (sc *SnowthClient) PromQLMetadataQueryContext(
 	if query.Metric != "" {
 		mt, err := ConvertSeriesSelector(query.Metric)
 		if err != nil {
-			return nil, fmt.Errorf("invalid PromQL metadata query: "+
+			return nil, invalidQueryf("invalid PromQL metadata query: "+
 				"invalid metric selector: %s: %w", query.Metric, err)
 		}
 
@@ -1085,25 +1108,27 @@ func (sc *SnowthClient) PromQLMetadataQueryContext(
 		i, err := strconv.ParseInt(query.Limit, 10, 64)
 		if err != nil {
 			return nil,
-				fmt.Errorf("invalid PromQL metadata query: invalid limit: %v",
+				invalidQueryf("invalid PromQL metadata query: invalid limit: %v",
 					query.Limit)
 		}
 
-		opts.Limit = i
+		tagOpts.Limit = i
 	}
 
 	if query.AccountID != "" {
 		i, err := strconv.ParseInt(query.AccountID, 10, 64)
 		if err != nil {
 			return nil,
-				fmt.Errorf("invalid PromQL series query: invalid account_id: %v",
+				invalidQueryf("invalid PromQL series query: invalid account_id: %v",
 					query.AccountID)
 		}
 
 		aID = i
+	} else if id, ok := accountFromContext(ctx); ok {
+		aID = id
 	}
 
-	res, err := sc.FindTagsContext(ctx, aID, q, opts, node)
+	res, err := sc.FindTagsContext(ctx, aID, q, tagOpts, node)
 	if err != nil {
 		r := &PromQLResponse{
 			Status:    "error",
@@ -1115,6 +1140,7 @@ func (sc *SnowthClient) PromQLMetadataQueryContext(
 			Status:    r.Status,
 			ErrorType: r.ErrorType,
 			Err:       r.Error,
+			err:       err,
 		}
 
 		return r, rErr
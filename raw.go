@@ -65,24 +65,27 @@ func (sc *SnowthClient) ReadRawNumericValues(start time.Time, end time.Time,
 	nodes ...*SnowthNode,
 ) ([]RawNumericValue, error) {
 	return sc.ReadRawNumericValuesContext(context.Background(), start, end,
-		uuid, metric, nodes...)
+		uuid, metric, nodeOptions(nodes)...)
 }
 
 // ReadRawNumericValuesContext is the context aware version of
 // ReadRawNumericValues.
 func (sc *SnowthClient) ReadRawNumericValuesContext(ctx context.Context,
 	start, end time.Time, uuid, metric string,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) ([]RawNumericValue, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(uuid, metric))
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	var owners []string
+
+	if node == nil {
+		owners = sc.FindMetricNodeIDs(uuid, metric)
+		node = sc.GetActiveNode(owners)
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	qp := url.Values{}
@@ -91,8 +94,8 @@ func (sc *SnowthClient) ReadRawNumericValuesContext(ctx context.Context,
 
 	r := &RawNumericValueResponse{}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "GET", path.Join("/raw",
-		uuid, metric)+"?"+qp.Encode(), nil, nil)
+	body, _, err := sc.hedgedRequestContext(ctx, node, owners, "GET",
+		path.Join("/raw", uuid, metric)+"?"+qp.Encode(), nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -110,25 +113,83 @@ func (sc *SnowthClient) WriteRaw(data io.Reader,
 	nodes ...*SnowthNode,
 ) (*IRONdbPutResponse, error) {
 	return sc.WriteRawContext(context.Background(), data, fb, dataPoints,
-		nodes...)
+		nodeOptions(nodes)...)
 }
 
-// WriteRawContext is the context aware version of WriteRaw.
+// WriteRawContext is the context aware version of WriteRaw. If the data is
+// a flatbuffer metric list and the node reports misdirected records, the
+// topology is refreshed and the records not owned by the node are re-sent to
+// the nodes owning them. The response then includes the re-sent records, with
+// the number re-routed in its Rerouted field. See WithMisdirectRetries().
+// If the client has a spool, flatbuffer data which cannot be sent because no
+// node is available is added to the spool, and the response Spooled field is
+// set. See Config Spool.
 func (sc *SnowthClient) WriteRawContext(ctx context.Context,
 	data io.Reader, fb bool, dataPoints uint64,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*IRONdbPutResponse, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
+	retries := misdirectRetries(ctx)
+	sp := sc.writeSpool(ctx)
+
+	if node == nil && (sp == nil || !fb) {
+		return nil, ErrNoActiveNode
+	}
+
+	var b []byte
+
+	if fb && (retries > 0 || sp != nil) {
+		var err error
+		if b, err = io.ReadAll(data); err != nil {
+			return nil, fmt.Errorf("unable to read raw data: %w", err)
+		}
+
+		data = bytes.NewReader(b)
+	}
+
+	var (
+		r   *IRONdbPutResponse
+		err error
+	)
+
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		err = ErrNoActiveNode
+	} else {
+		r, err = sc.sendRaw(ctx, node, data, fb, dataPoints)
 	}
 
+	if err != nil {
+		if sp == nil || !spoolable(err) {
+			return nil, err
+		}
+
+		if err := sc.spoolWrite(sp, SpoolMetricList, b, dataPoints,
+			err); err != nil {
+			return nil, err
+		}
+
+		return &IRONdbPutResponse{Spooled: true}, nil
+	}
+
+	if r.Misdirected > 0 && b != nil {
+		if ml := unpackMetricList(b); ml != nil {
+			sc.rerouteMetrics(ctx, node, ml.Metrics, r, retries)
+		}
+	}
+
+	return r, nil
+}
+
+// sendRaw sends raw IRONdb data to a node.
+func (sc *SnowthClient) sendRaw(ctx context.Context, node *SnowthNode,
+	data io.Reader, fb bool, dataPoints uint64,
+) (*IRONdbPutResponse, error) {
 	hdrs := http.Header{
 		"X-Snowth-Datapoints": {strconv.FormatUint(dataPoints, 10)},
 	}
@@ -156,13 +217,13 @@ func (sc *SnowthClient) WriteRawMetricList(metricList *noit.MetricListT,
 	nodes ...*SnowthNode,
 ) (*IRONdbPutResponse, error) {
 	return sc.WriteRawMetricListContext(context.Background(),
-		metricList, builder, nodes...)
+		metricList, builder, nodeOptions(nodes)...)
 }
 
 // WriteRawMetricListContext is the context aware version of WriteRawMetricList.
 func (sc *SnowthClient) WriteRawMetricListContext(ctx context.Context,
 	metricList *noit.MetricListT, builder *flatbuffers.Builder,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*IRONdbPutResponse, error) {
 	if metricList == nil {
 		return nil, fmt.Errorf("metric list cannot be nil")
@@ -183,5 +244,5 @@ func (sc *SnowthClient) WriteRawMetricListContext(ctx context.Context,
 	builder.FinishWithFileIdentifier(offset, []byte("CIML"))
 	reader := bytes.NewReader(builder.FinishedBytes())
 
-	return sc.WriteRawContext(ctx, reader, true, datapoints, nodes...)
+	return sc.WriteRawContext(ctx, reader, true, datapoints, opts...)
 }
@@ -0,0 +1,274 @@
+package gosnowth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/circonus-labs/gosnowth/fb/noit"
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// defaultMisdirectRetries is the number of times misdirected records are
+// re-sent to the nodes owning them, unless set by WithMisdirectRetries().
+const defaultMisdirectRetries = 2
+
+// WithMisdirectRetries sets the number of times records which a node reports
+// as misdirected are re-sent to the nodes owning them, after the topology is
+// refreshed. This applies to WriteRawContext, for flatbuffer data, and to
+// WriteRawMetricListContext and WriteNNTBSFlatbufferContext. A value of zero
+// disables re-routing, so that misdirected records are only reported.
+func WithMisdirectRetries(n int) CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		co.misdirect, co.hasMisdirect = n, true
+	})
+}
+
+// misdirectRetries returns the number of times misdirected records are
+// re-sent for a call.
+func misdirectRetries(ctx context.Context) int {
+	co := callOptionsFromContext(ctx)
+	if co.hasMisdirect {
+		return co.misdirect
+	}
+
+	return defaultMisdirectRetries
+}
+
+// routingTopologies contains the topologies used to re-route misdirected
+// records, which are retrieved once per call.
+type routingTopologies map[string]*Topology
+
+// metricOwners returns a function which returns the node identifiers owning a
+// metric in the current and next topologies reported by a node. Topologies
+// which are not cached are retrieved from the node. No next topology owners
+// are returned unless a topology change is in progress.
+func (sc *SnowthClient) metricOwners(ctx context.Context, node *SnowthNode,
+	topos routingTopologies,
+) (func(uuid, metric string) ([]string, []string), error) {
+	stats, err := sc.GetStatsNodeContext(ctx, node)
+	if err != nil {
+		return nil, fmt.Errorf("unable to refresh topology: %w", err)
+	}
+
+	current, err := sc.routingTopology(ctx, node, stats.CurrentTopology(),
+		topos)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		return nil, fmt.Errorf("unable to refresh topology: " +
+			"no current topology")
+	}
+
+	next, err := sc.routingTopology(ctx, node, stats.NextTopology(), topos)
+	if err != nil {
+		return nil, err
+	}
+
+	find := func(topo *Topology, uuid, metric string) []string {
+		if topo == nil {
+			return nil
+		}
+
+		ids, err := topo.FindMetricNodeIDs(uuid, metric)
+		if err != nil {
+			return nil
+		}
+
+		return ids
+	}
+
+	return func(uuid, metric string) ([]string, []string) {
+		return find(current, uuid, metric), find(next, uuid, metric)
+	}, nil
+}
+
+// routingTopology returns the topology with the provided hash, retrieving it
+// from a node if it has not already been retrieved. It returns nil for the
+// empty topology hash, "-".
+func (sc *SnowthClient) routingTopology(ctx context.Context, node *SnowthNode,
+	hash string, topos routingTopologies,
+) (*Topology, error) {
+	if hash == "" || hash == "-" {
+		return nil, nil
+	}
+
+	if topo, ok := topos[hash]; ok {
+		return topo, nil
+	}
+
+	topo := sc.topology.get(hash)
+	if topo == nil {
+		var err error
+
+		if topo, err = sc.fetchTopology(ctx, node, hash); err != nil {
+			return nil, fmt.Errorf("unable to refresh topology: %w", err)
+		}
+
+		sc.RLock()
+		current := sc.currentTopology
+		sc.RUnlock()
+
+		if current == hash {
+			sc.topology.store(hash, topo)
+			sc.LogDebugf("updated topology: %s", hash)
+		}
+	}
+
+	topos[hash] = topo
+
+	return topo, nil
+}
+
+// containsID returns whether a list of node identifiers contains one.
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if strings.EqualFold(v, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rawBatch values contain raw metric records sent to a node.
+type rawBatch struct {
+	node    *SnowthNode
+	metrics []*noit.MetricT
+}
+
+// rerouteMetrics re-sends the metric records of a raw write which a node
+// reported as misdirected to the nodes owning them, updating the response of
+// the write. Records are re-sent up to the provided number of times.
+func (sc *SnowthClient) rerouteMetrics(ctx context.Context, node *SnowthNode,
+	metrics []*noit.MetricT, res *IRONdbPutResponse, retries int,
+) {
+	topos := routingTopologies{}
+	batches := []rawBatch{{node: node, metrics: metrics}}
+
+	for attempt := 0; attempt < retries && len(batches) > 0; attempt++ {
+		var next []rawBatch
+
+		for _, b := range batches {
+			next = append(next, sc.rerouteBatch(ctx, b, res, topos)...)
+		}
+
+		batches = next
+	}
+}
+
+// rerouteBatch re-sends the records of a batch which are not owned by the
+// node the batch was sent to. It returns the batches which were reported as
+// misdirected again.
+func (sc *SnowthClient) rerouteBatch(ctx context.Context, b rawBatch,
+	res *IRONdbPutResponse, topos routingTopologies,
+) []rawBatch {
+	id := b.node.id()
+	if id == "" {
+		sc.LogWarnf("unable to re-route misdirected records: "+
+			"unknown node identifier: %s", b.node.GetURL().Host)
+
+		return nil
+	}
+
+	owners, err := sc.metricOwners(ctx, b.node, topos)
+	if err != nil {
+		sc.LogWarnf("unable to re-route misdirected records: %s",
+			err.Error())
+
+		return nil
+	}
+
+	targets := map[*SnowthNode][]*noit.MetricT{}
+	order := []*SnowthNode{}
+
+	for _, m := range b.metrics {
+		cur, nxt := owners(m.CheckUuid, rawMetricName(m))
+		if containsID(cur, id) || containsID(nxt, id) {
+			continue
+		}
+
+		target := sc.GetActiveNode(cur, nxt)
+		if target == nil || target == b.node {
+			continue
+		}
+
+		if _, ok := targets[target]; !ok {
+			order = append(order, target)
+		}
+
+		targets[target] = append(targets[target], m)
+	}
+
+	var again []rawBatch
+
+	for _, target := range order {
+		metrics := targets[target]
+
+		r, err := sc.sendMetricList(ctx, target, metrics)
+		if err != nil {
+			sc.LogWarnf("unable to re-route %d misdirected records to %s: %s",
+				len(metrics), target.GetURL().Host, err.Error())
+
+			continue
+		}
+
+		sc.LogDebugf("re-routed %d misdirected records from %s to %s",
+			len(metrics), b.node.GetURL().Host, target.GetURL().Host)
+
+		res.Errors += r.Errors
+		res.Records += r.Records
+		res.Updated += r.Updated
+
+		accepted := uint64(len(metrics)) - r.Misdirected
+		if r.Misdirected > uint64(len(metrics)) {
+			accepted = 0
+		}
+
+		if accepted > res.Misdirected {
+			accepted = res.Misdirected
+		}
+
+		res.Misdirected -= accepted
+		res.Rerouted += accepted
+
+		if r.Misdirected > 0 {
+			again = append(again, rawBatch{node: target, metrics: metrics})
+		}
+	}
+
+	return again
+}
+
+// sendMetricList sends metric records to a node as a raw flatbuffer write,
+// without re-routing any misdirected records.
+func (sc *SnowthClient) sendMetricList(ctx context.Context, node *SnowthNode,
+	metrics []*noit.MetricT,
+) (*IRONdbPutResponse, error) {
+	builder := flatbuffers.NewBuilder(1024)
+	offset := noit.MetricListPack(builder,
+		&noit.MetricListT{Metrics: metrics})
+	builder.FinishWithFileIdentifier(offset, []byte("CIML"))
+
+	return sc.sendRaw(ctx, node, bytes.NewReader(builder.FinishedBytes()),
+		true, uint64(len(metrics)))
+}
+
+// unpackMetricList decodes a raw flatbuffer metric list, returning nil if the
+// data cannot be decoded.
+func unpackMetricList(data []byte) (ml *noit.MetricListT) {
+	defer func() {
+		if r := recover(); r != nil {
+			ml = nil
+		}
+	}()
+
+	if len(data) < 8 {
+		return nil
+	}
+
+	return noit.GetRootAsMetricList(data, 0).UnPack()
+}
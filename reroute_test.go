@@ -0,0 +1,251 @@
+package gosnowth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/circonus-labs/gosnowth/fb/nntbs"
+	"github.com/circonus-labs/gosnowth/fb/noit"
+)
+
+// rerouteTestServer returns a test server for a node of the shard test
+// topology, which reports the records it does not own as misdirected.
+func rerouteTestServer(t *testing.T, topo *Topology, id string,
+	mu *sync.Mutex, received map[string][]string,
+) *httptest.Server {
+	t.Helper()
+
+	stats := `{"identity":{"_type":"s","_value":"` + id + `"},` +
+		`"semver":{"_type":"s","_value":"1.0.0"},` +
+		`"topology":{"current":{"_type":"s","_value":"` + topo.Hash + `"},` +
+		`"next":{"_type":"s","_value":"-"}}}`
+
+	owns := func(uuid, metric string) bool {
+		owners, err := topo.FindMetricNodeIDs(uuid, metric)
+
+		return err == nil && owners[0] == id
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		switch {
+		case r.RequestURI == "/stats.json":
+			_, _ = w.Write([]byte(stats))
+		case r.RequestURI == "/topology/xml/"+topo.Hash:
+			_, _ = w.Write([]byte(shardTopologyXMLTestData))
+		case r.RequestURI == "/raw":
+			b, _ := io.ReadAll(r.Body)
+			ml := noit.GetRootAsMetricList(b, 0).UnPack()
+			res := IRONdbPutResponse{}
+
+			for _, m := range ml.Metrics {
+				name := m.Value.Name
+				if len(m.Value.StreamTags) > 0 {
+					tags := append([]string{}, m.Value.StreamTags...)
+					sort.Strings(tags)
+					name += "|ST[" + strings.Join(tags, ",") + "]"
+				}
+
+				if !owns(m.CheckUuid, name) {
+					res.Misdirected++
+
+					continue
+				}
+
+				res.Records++
+				res.Updated++
+
+				mu.Lock()
+				received[id] = append(received[id], m.Value.Name)
+				mu.Unlock()
+			}
+
+			_, _ = fmt.Fprintf(w, `{"records":%d,"updated":%d,`+
+				`"misdirected":%d,"errors":0}`, res.Records, res.Updated,
+				res.Misdirected)
+		case r.RequestURI == "/nntbs":
+			b, _ := io.ReadAll(r.Body)
+			m := nntbs.GetRootAsNNTMerge(b, 0).UnPack()
+			loc := m.Ops[0].Metric.MetricLocator
+
+			if !owns(string(loc.CheckUuid), loc.MetricName) {
+				_, _ = w.Write([]byte(`{"records":0,"updated":0,` +
+					`"misdirected":1,"errors":0}`))
+
+				return
+			}
+
+			mu.Lock()
+			received[id] = append(received[id], loc.MetricName)
+			mu.Unlock()
+
+			_, _ = w.Write([]byte(`{"records":1,"updated":1,` +
+				`"misdirected":0,"errors":0}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// rerouteTestMetrics returns a raw metric list for the shard test topology,
+// in which half of the metrics have stream tags, and the names and canonical
+// names of the metrics owned by the provided node. Owners of tagged metrics
+// are found using their canonical names.
+func rerouteTestMetrics(t *testing.T, topo *Topology,
+	id string,
+) (*noit.MetricListT, []string, []string) {
+	t.Helper()
+
+	ml := &noit.MetricListT{}
+	owned, canonicals := []string{}, []string{}
+
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("test%d", i)
+		canonical := name
+
+		var tags []string
+		if i%2 == 0 {
+			tags = []string{"zone:b", "app:a"}
+			canonical += "|ST[app:a,zone:b]"
+		}
+
+		ml.Metrics = append(ml.Metrics, &noit.MetricT{
+			Timestamp: 60000,
+			CheckUuid: shardTestUUID,
+			AccountId: 1,
+			Value: &noit.MetricValueT{
+				Name:      name,
+				Timestamp: 60000,
+				Value: &noit.MetricValueUnionT{
+					Type:  noit.MetricValueUnionDoubleValue,
+					Value: &noit.DoubleValueT{Value: 1},
+				},
+				StreamTags: tags,
+			},
+		})
+
+		if owners, _ := topo.FindMetricNodeIDs(shardTestUUID,
+			canonical); owners[0] == id {
+			owned = append(owned, name)
+			canonicals = append(canonicals, canonical)
+		}
+	}
+
+	if len(owned) == 0 || len(owned) == 20 {
+		t.Fatalf("Expected metrics owned by both nodes, got: %v", owned)
+	}
+
+	return ml, owned, canonicals
+}
+
+func TestRerouteMisdirected(t *testing.T) {
+	t.Parallel()
+
+	topo, err := TopologyLoadXML(shardTopologyXMLTestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{topo.Nodes[0].ID, topo.Nodes[1].ID}
+
+	var mu sync.Mutex
+
+	received := map[string][]string{}
+
+	ms0 := rerouteTestServer(t, topo, ids[0], &mu, received)
+	defer ms0.Close()
+
+	ms1 := rerouteTestServer(t, topo, ids[1], &mu, received)
+	defer ms1.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms0.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	u, err := url.Parse(ms1.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	node0 := sc.ListActiveNodes()[0]
+	node1 := &SnowthNode{url: u, identifier: ids[1]}
+
+	sc.AddNodes(node1)
+	sc.ActivateNodes(node1)
+
+	ml, owned1, canonical1 := rerouteTestMetrics(t, topo, ids[1])
+
+	res, err := sc.WriteRawMetricListContext(context.Background(), ml, nil,
+		node0, WithMisdirectRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Misdirected != uint64(len(owned1)) || res.Rerouted != 0 {
+		t.Errorf("Expected misdirected: %v, got: %+v", len(owned1), res)
+	}
+
+	res, err = sc.WriteRawMetricList(ml, nil, node0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Records != 20 || res.Misdirected != 0 ||
+		res.Rerouted != uint64(len(owned1)) {
+		t.Errorf("Expected re-routed: %v, got: %+v", len(owned1), res)
+	}
+
+	mu.Lock()
+
+	if strings.Join(received[ids[1]], ",") != strings.Join(owned1, ",") {
+		t.Errorf("Expected re-routed metrics: %v, got: %v", owned1,
+			received[ids[1]])
+	}
+
+	received[ids[1]] = nil
+
+	mu.Unlock()
+
+	if sc.TopologyCacheStatus().Hash != topo.Hash {
+		t.Errorf("Expected cached topology: %v, got: %v", topo.Hash,
+			sc.TopologyCacheStatus().Hash)
+	}
+
+	merge := &nntbs.NNTMergeT{Ops: []*nntbs.NNTMergeOpT{{
+		Metric: &nntbs.MetricInfoT{
+			MetricLocator: &nntbs.MetricLocatorT{
+				CheckUuid:  []byte(shardTestUUID),
+				MetricName: canonical1[0],
+			},
+			AccountId: 1,
+		},
+	}}}
+
+	if err := sc.WriteNNTBSFlatbufferContext(context.Background(), merge,
+		nil, node0, WithMisdirectRetries(0)); err == nil {
+		t.Error("Expected error for misdirected nntbs data")
+	}
+
+	if err := sc.WriteNNTBSFlatbuffer(merge, nil, node0); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(received[ids[1]]) != 1 || received[ids[1]][0] != canonical1[0] {
+		t.Errorf("Expected re-routed nntbs metric: %v, got: %v", canonical1[0],
+			received[ids[1]])
+	}
+}
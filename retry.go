@@ -0,0 +1,267 @@
+package gosnowth
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy values determine how SnowthClient values retry failed requests
+// to IRONdb.
+type RetryPolicy interface {
+	// Idempotent reports whether a request using the provided method and
+	// endpoint path can safely be sent to IRONdb more than once. Requests
+	// which are not idempotent are only retried when the request could not
+	// have reached a node, such as when a connection could not be made.
+	Idempotent(method, path string) bool
+
+	// Retryable reports whether a request which failed with the provided
+	// HTTP status code and error should be retried.
+	Retryable(status int, err error) bool
+
+	// Backoff returns the duration to wait before the provided retry
+	// attempt. Attempts are numbered starting at 1.
+	Backoff(attempt int64) time.Duration
+
+	// AllowRetry reports whether the retry budget permits another retry. A
+	// true result consumes part of the budget.
+	AllowRetry() bool
+
+	// RecordSuccess records a successful request, replenishing the retry
+	// budget.
+	RecordSuccess()
+}
+
+// DefaultRetryPolicy values implement a RetryPolicy using exponential backoff
+// with jitter and a token bucket retry budget.
+type DefaultRetryPolicy struct {
+	// BaseDelay is the backoff duration before the first retry. Each
+	// subsequent retry doubles the delay.
+	BaseDelay time.Duration
+
+	// MaxDelay is the maximum backoff duration.
+	MaxDelay time.Duration
+
+	// Jitter is the fraction, from 0 to 1, of each backoff duration which
+	// is randomized.
+	Jitter float64
+
+	// Budget is the maximum number of retry tokens available. Each retry
+	// consumes one token. A value of zero disables the retry budget.
+	Budget float64
+
+	// BudgetRatio is the number of retry tokens returned to the budget by
+	// each successful request.
+	BudgetRatio float64
+
+	// RetryWrites allows requests which are not idempotent, such as data
+	// writes, to be retried after they may have reached a node.
+	RetryWrites bool
+
+	mu     sync.Mutex
+	filled bool
+	tokens float64
+	rnd    *rand.Rand
+}
+
+// NewDefaultRetryPolicy creates and initializes a new DefaultRetryPolicy value
+// using default settings.
+func NewDefaultRetryPolicy() *DefaultRetryPolicy {
+	return &DefaultRetryPolicy{
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		Jitter:      0.5,
+		Budget:      10,
+		BudgetRatio: 0.1,
+	}
+}
+
+// idempotentPostPaths contains the prefixes of endpoint paths which use the
+// POST method, but only read data from IRONdb.
+var idempotentPostPaths = []string{
+	"/fetch",
+	"/extension/lua/public/caql_v1",
+	"/graphite/",
+}
+
+// Idempotent reports whether a request using the provided method and
+// endpoint path can safely be sent to IRONdb more than once.
+func (rp *DefaultRetryPolicy) Idempotent(method, path string) bool {
+	if rp.RetryWrites {
+		return true
+	}
+
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		// Topology activation uses GET, but modifies the node.
+		return !strings.HasPrefix(path, "/activate")
+	case http.MethodPost:
+		for _, p := range idempotentPostPaths {
+			if strings.HasPrefix(path, p) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Retryable reports whether a request which failed with the provided HTTP
+// status code and error should be retried. Errors indicating a problem with
+// the request itself, such as 4xx status codes and IRONdb errors reporting
+// query parsing errors, are not retried.
+func (rp *DefaultRetryPolicy) Retryable(status int, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var ie *IRONdbError
+	if errors.As(err, &ie) {
+		return !ie.requestError()
+	}
+
+	return status < http.StatusBadRequest ||
+		status >= http.StatusInternalServerError
+}
+
+// Backoff returns the duration to wait before the provided retry attempt.
+func (rp *DefaultRetryPolicy) Backoff(attempt int64) time.Duration {
+	if attempt < 1 || rp.BaseDelay <= 0 {
+		return 0
+	}
+
+	d := float64(rp.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if rp.MaxDelay > 0 && d > float64(rp.MaxDelay) {
+		d = float64(rp.MaxDelay)
+	}
+
+	jitter := rp.Jitter
+	if jitter > 1 {
+		jitter = 1
+	}
+
+	if jitter > 0 {
+		rp.mu.Lock()
+
+		if rp.rnd == nil {
+			rp.rnd = rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gosec
+		}
+
+		r := rp.rnd.Float64()
+
+		rp.mu.Unlock()
+
+		d = d*(1-jitter) + d*jitter*r
+	}
+
+	return time.Duration(d)
+}
+
+// AllowRetry reports whether the retry budget permits another retry.
+func (rp *DefaultRetryPolicy) AllowRetry() bool {
+	if rp.Budget <= 0 {
+		return true
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	rp.fill()
+
+	if rp.tokens < 1 {
+		return false
+	}
+
+	rp.tokens--
+
+	return true
+}
+
+// RecordSuccess records a successful request, replenishing the retry budget.
+func (rp *DefaultRetryPolicy) RecordSuccess() {
+	if rp.Budget <= 0 {
+		return
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	rp.fill()
+
+	rp.tokens += rp.BudgetRatio
+	if rp.tokens > rp.Budget {
+		rp.tokens = rp.Budget
+	}
+}
+
+// fill initializes the retry budget to full the first time it is used. The
+// caller must hold the policy lock.
+func (rp *DefaultRetryPolicy) fill() {
+	if !rp.filled {
+		rp.tokens = rp.Budget
+		rp.filled = true
+	}
+}
+
+// isConnectError reports whether an error occurred while establishing a
+// connection to a node, meaning the request could not have been received.
+// This includes failures to resolve the node address, to connect to a proxy,
+// and refused connections.
+func isConnectError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op == "dial" || opErr.Op == "proxyconnect"
+	}
+
+	return false
+}
+
+// requestPath returns the path portion of an endpoint reference.
+func requestPath(ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	return u.Path
+}
+
+// waitContext waits for the provided duration, returning early with an error
+// if the context is cancelled or expires first.
+func waitContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
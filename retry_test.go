@@ -0,0 +1,268 @@
+package gosnowth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDefaultRetryPolicyIdempotent(t *testing.T) {
+	t.Parallel()
+
+	rp := NewDefaultRetryPolicy()
+
+	tests := []struct {
+		method string
+		path   string
+		exp    bool
+	}{
+		{"GET", "/stats.json", true},
+		{"GET", "/activate/abc", false},
+		{"DELETE", "/meta/check/tag/abc", false},
+		{"POST", "/fetch", true},
+		{"POST", "/extension/lua/public/caql_v1", true},
+		{"POST", "/graphite/1/test/series_multi", true},
+		{"POST", "/raw", false},
+		{"POST", "/write/numeric", false},
+		{"POST", "/meta/check/tag/abc", false},
+	}
+
+	for _, test := range tests {
+		if v := rp.Idempotent(test.method, test.path); v != test.exp {
+			t.Errorf("Expected idempotent %s %s: %v, got: %v",
+				test.method, test.path, test.exp, v)
+		}
+	}
+
+	rp.RetryWrites = true
+
+	if !rp.Idempotent("POST", "/raw") {
+		t.Error("Expected writes to be retryable")
+	}
+}
+
+func TestDefaultRetryPolicyRetryable(t *testing.T) {
+	t.Parallel()
+
+	rp := NewDefaultRetryPolicy()
+
+	if rp.Retryable(http.StatusOK, nil) {
+		t.Error("Expected nil error to not be retryable")
+	}
+
+	if rp.Retryable(http.StatusBadRequest, fmt.Errorf("bad")) {
+		t.Error("Expected 4xx status to not be retryable")
+	}
+
+	if rp.Retryable(http.StatusInternalServerError, fmt.Errorf("wrap: %w",
+		&IRONdbError{
+			StatusCode: http.StatusInternalServerError,
+			Body:       []byte("error: cannot parse query"),
+		})) {
+		t.Error("Expected parse error to not be retryable")
+	}
+
+	if !rp.Retryable(http.StatusInternalServerError, &IRONdbError{
+		StatusCode: http.StatusInternalServerError,
+		Body:       []byte("internal error"),
+	}) {
+		t.Error("Expected IRONdb server error to be retryable")
+	}
+
+	if !rp.Retryable(0, fmt.Errorf("cannot parse response")) {
+		t.Error("Expected transport error to be retryable")
+	}
+
+	if rp.Retryable(0, fmt.Errorf("wrap: %w", context.Canceled)) {
+		t.Error("Expected context error to not be retryable")
+	}
+
+	if !rp.Retryable(http.StatusServiceUnavailable, fmt.Errorf("down")) {
+		t.Error("Expected 5xx status to be retryable")
+	}
+}
+
+func TestDefaultRetryPolicyBackoff(t *testing.T) {
+	t.Parallel()
+
+	rp := &DefaultRetryPolicy{
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  time.Second,
+	}
+
+	exp := []time.Duration{
+		0, 100 * time.Millisecond, 200 * time.Millisecond,
+		400 * time.Millisecond, 800 * time.Millisecond, time.Second,
+	}
+
+	for i, e := range exp {
+		if d := rp.Backoff(int64(i)); d != e {
+			t.Errorf("Expected backoff %d: %v, got: %v", i, e, d)
+		}
+	}
+
+	rp.Jitter = 0.5
+
+	for i := int64(1); i < 5; i++ {
+		d := rp.Backoff(i)
+		hi := exp[i]
+		lo := hi / 2
+
+		if d < lo || d > hi {
+			t.Errorf("Expected backoff %d between %v and %v, got: %v",
+				i, lo, hi, d)
+		}
+	}
+}
+
+func TestDefaultRetryPolicyBudget(t *testing.T) {
+	t.Parallel()
+
+	rp := &DefaultRetryPolicy{Budget: 2, BudgetRatio: 0.5}
+
+	if !rp.AllowRetry() || !rp.AllowRetry() {
+		t.Fatal("Expected retries to be allowed")
+	}
+
+	if rp.AllowRetry() {
+		t.Fatal("Expected retry budget to be exhausted")
+	}
+
+	rp.RecordSuccess()
+	rp.RecordSuccess()
+
+	if !rp.AllowRetry() {
+		t.Error("Expected retry budget to be replenished")
+	}
+
+	rp = &DefaultRetryPolicy{}
+
+	for i := 0; i < 100; i++ {
+		if !rp.AllowRetry() {
+			t.Fatal("Expected unlimited retry budget")
+		}
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+
+	if err := waitContext(ctx, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context canceled error, got: %v", err)
+	}
+
+	if time.Since(start) > time.Second {
+		t.Error("Expected wait to end when context was cancelled")
+	}
+
+	if err := waitContext(context.Background(),
+		time.Millisecond); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIsConnectError(t *testing.T) {
+	t.Parallel()
+
+	err := fmt.Errorf("failed: %w", &net.OpError{
+		Op:  "dial",
+		Err: fmt.Errorf("connection refused"),
+	})
+
+	if !isConnectError(err) {
+		t.Error("Expected dial error to be a connection error")
+	}
+
+	for _, err := range []error{
+		&net.OpError{Op: "proxyconnect", Err: fmt.Errorf("proxy down")},
+		&net.DNSError{Err: "no such host", Name: "node", IsNotFound: true},
+		fmt.Errorf("read: %w", syscall.ECONNREFUSED),
+	} {
+		if !isConnectError(err) {
+			t.Errorf("Expected connection error: %v", err)
+		}
+	}
+
+	if isConnectError(&net.OpError{Op: "read", Err: fmt.Errorf("reset")}) {
+		t.Error("Expected read error to not be a connection error")
+	}
+
+	if isConnectError(fmt.Errorf("timeout")) {
+		t.Error("Expected other error to not be a connection error")
+	}
+}
+
+func TestDoRequestRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+
+	counts := map[string]int{}
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		mu.Lock()
+		counts[r.URL.Path]++
+		n := counts[r.URL.Path]
+		mu.Unlock()
+
+		if r.URL.Path == "/state" && n > 2 {
+			_, _ = w.Write([]byte(stateTestData))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("failure"))
+	}))
+
+	defer ms.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	sc.SetRetries(2)
+	sc.SetRetryPolicy(&DefaultRetryPolicy{BaseDelay: time.Millisecond})
+
+	if _, err := sc.GetNodeState(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sc.WriteNumeric([]NumericWrite{{ID: "test"}}); err == nil {
+		t.Fatal("Expected error writing numeric data")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if counts["/state"] != 3 {
+		t.Errorf("Expected state requests: 3, got: %v", counts["/state"])
+	}
+
+	if counts["/write/numeric"] != 1 {
+		t.Errorf("Expected write requests: 1, got: %v",
+			counts["/write/numeric"])
+	}
+}
@@ -191,23 +191,26 @@ func (sc *SnowthClient) ReadRollupValues(uuid, metric string, period time.Durati
 	start, end time.Time, dataType string, nodes ...*SnowthNode,
 ) ([]RollupValue, error) {
 	return sc.ReadRollupValuesContext(context.Background(), uuid, metric,
-		period, start, end, dataType, nodes...)
+		period, start, end, dataType, nodeOptions(nodes)...)
 }
 
 // ReadRollupValuesContext is the context aware version of ReadRollupValues.
 func (sc *SnowthClient) ReadRollupValuesContext(ctx context.Context,
 	uuid, metric string, period time.Duration, start, end time.Time,
-	dataType string, nodes ...*SnowthNode,
+	dataType string, opts ...CallOption,
 ) ([]RollupValue, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(uuid, metric))
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	var owners []string
+
+	if node == nil {
+		owners = sc.FindMetricNodeIDs(uuid, metric)
+		node = sc.GetActiveNode(owners)
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	if dataType == "" {
@@ -219,7 +222,7 @@ func (sc *SnowthClient) ReadRollupValuesContext(ctx context.Context,
 		"derive_stddev", "counter_stddev", "derive2", "counter2",
 		"derive2_stddev", "counter2_stddev":
 	default:
-		return nil, fmt.Errorf("invalid rollup data type: " + dataType)
+		return nil, invalidQueryf("invalid rollup data type: %s", dataType)
 	}
 
 	startTS := start.Unix() - start.Unix()%int64(period/time.Second)
@@ -227,7 +230,7 @@ func (sc *SnowthClient) ReadRollupValuesContext(ctx context.Context,
 		int64(period/time.Second)
 	r := []RollupValue{}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "GET",
+	body, _, err := sc.hedgedRequestContext(ctx, node, owners, "GET",
 		fmt.Sprintf("%s?start_ts=%d&end_ts=%d&rollup_span=%ds&type=%s",
 			path.Join("/rollup", uuid, url.QueryEscape(metric)),
 			startTS, endTS, int64(period/time.Second), dataType), nil, nil)
@@ -248,23 +251,26 @@ func (sc *SnowthClient) ReadRollupAllValues(
 	start, end time.Time, nodes ...*SnowthNode,
 ) ([]RollupAllValue, error) {
 	return sc.ReadRollupAllValuesContext(context.Background(), uuid,
-		metric, period, start, end, nodes...)
+		metric, period, start, end, nodeOptions(nodes)...)
 }
 
 // ReadRollupAllValuesContext is the context aware version of ReadRollupValues.
 func (sc *SnowthClient) ReadRollupAllValuesContext(ctx context.Context,
 	uuid, metric string, period time.Duration,
-	start, end time.Time, nodes ...*SnowthNode,
+	start, end time.Time, opts ...CallOption,
 ) ([]RollupAllValue, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(uuid, metric))
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	var owners []string
+
+	if node == nil {
+		owners = sc.FindMetricNodeIDs(uuid, metric)
+		node = sc.GetActiveNode(owners)
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	startTS := start.Unix() - start.Unix()%int64(period/time.Second)
@@ -272,7 +278,7 @@ func (sc *SnowthClient) ReadRollupAllValuesContext(ctx context.Context,
 		int64(period/time.Second)
 	r := []RollupAllValue{}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "GET",
+	body, _, err := sc.hedgedRequestContext(ctx, node, owners, "GET",
 		fmt.Sprintf("%s?start_ts=%d&end_ts=%d&rollup_span=%ds&type=all",
 			path.Join("/rollup", uuid, url.QueryEscape(metric)),
 			startTS, endTS, int64(period/time.Second)), nil, nil)
@@ -0,0 +1,294 @@
+package gosnowth
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyEWMAWeight is the weight given to each new request latency
+// measurement when updating the moving average latency of a node.
+const latencyEWMAWeight = 0.3
+
+// latencyFailurePenalty is added to the latency measurement of failed
+// requests, so that nodes which fail quickly, such as by refusing
+// connections, are not preferred for their low latency.
+const latencyFailurePenalty = 10 * time.Second
+
+// NodeLoad values contain the measurements for a node used by NodeSelector
+// values to choose which node will receive a request.
+type NodeLoad struct {
+	// Node is the candidate node.
+	Node *SnowthNode
+
+	// Outstanding is the number of requests currently in progress to the
+	// node from this client.
+	Outstanding int64
+
+	// Latency is the exponentially weighted moving average of the duration
+	// of requests sent to the node. It is zero if no requests have been
+	// measured.
+	Latency time.Duration
+
+	// GossipLatency is the average latency reported by the other nodes in
+	// the cluster for communicating with this node. It is negative if no
+	// gossip latency information is available.
+	GossipLatency time.Duration
+}
+
+// NodeSelector values choose which of several candidate nodes will receive a
+// request. The candidates are never empty.
+type NodeSelector interface {
+	Select(candidates []NodeLoad) *SnowthNode
+}
+
+// RandomSelector values choose a random candidate node. This is the default
+// node selection strategy.
+type RandomSelector struct{}
+
+// Select chooses a random candidate node.
+func (rs *RandomSelector) Select(candidates []NodeLoad) *SnowthNode {
+	return candidates[time.Now().UnixNano()%int64(len(candidates))].Node
+}
+
+// RoundRobinSelector values choose candidate nodes in turn.
+type RoundRobinSelector struct {
+	next uint64
+}
+
+// Select chooses the next candidate node in turn.
+func (rrs *RoundRobinSelector) Select(candidates []NodeLoad) *SnowthNode {
+	n := atomic.AddUint64(&rrs.next, 1) - 1
+
+	return candidates[n%uint64(len(candidates))].Node
+}
+
+// LeastOutstandingSelector values choose the candidate node with the fewest
+// requests in progress.
+type LeastOutstandingSelector struct{}
+
+// Select chooses the candidate node with the fewest requests in progress.
+func (los *LeastOutstandingSelector) Select(candidates []NodeLoad) *SnowthNode {
+	return selectMin(candidates, func(nl NodeLoad) float64 {
+		return float64(nl.Outstanding)
+	})
+}
+
+// LatencyEWMASelector values choose the candidate node with the lowest moving
+// average request latency. Nodes without measurements are preferred, so that
+// every node is measured.
+type LatencyEWMASelector struct{}
+
+// Select chooses the candidate node with the lowest average request latency.
+func (les *LatencyEWMASelector) Select(candidates []NodeLoad) *SnowthNode {
+	return selectMin(candidates, func(nl NodeLoad) float64 {
+		// Account for requests in progress, so that a burst of requests
+		// is not sent to a single node before its latency is measured.
+		return float64(nl.Latency) * float64(nl.Outstanding+1)
+	})
+}
+
+// GossipLatencySelector values choose the candidate node with the lowest
+// gossip latency. Nodes without gossip latency information are chosen last.
+type GossipLatencySelector struct{}
+
+// Select chooses the candidate node with the lowest gossip latency.
+func (gls *GossipLatencySelector) Select(candidates []NodeLoad) *SnowthNode {
+	return selectMin(candidates, func(nl NodeLoad) float64 {
+		if nl.GossipLatency < 0 {
+			return math.MaxFloat64
+		}
+
+		return float64(nl.GossipLatency)
+	})
+}
+
+// selectMin returns the candidate node with the lowest value. Ties are broken
+// by starting the search at a random candidate.
+func selectMin(candidates []NodeLoad,
+	value func(NodeLoad) float64,
+) *SnowthNode {
+	start := int(time.Now().UnixNano() % int64(len(candidates)))
+	best := candidates[start]
+	bv := value(best)
+
+	for i := 1; i < len(candidates); i++ {
+		c := candidates[(start+i)%len(candidates)]
+
+		if v := value(c); v < bv {
+			best, bv = c, v
+		}
+	}
+
+	return best.Node
+}
+
+// nodeStats values contain request measurements for a single node.
+type nodeStats struct {
+	sync.Mutex
+	outstanding   int64
+	latency       float64
+	gossipLatency float64
+}
+
+// begin records the start of a request to the node.
+func (ns *nodeStats) begin() {
+	atomic.AddInt64(&ns.outstanding, 1)
+}
+
+// abort records the completion of a request to the node which was cancelled
+// by the caller, without measuring its latency, since it does not reflect the
+// performance of the node.
+func (ns *nodeStats) abort() {
+	atomic.AddInt64(&ns.outstanding, -1)
+}
+
+// end records the completion of a request to the node and its duration. The
+// latency of failed requests is penalized.
+func (ns *nodeStats) end(d time.Duration, ok bool) {
+	atomic.AddInt64(&ns.outstanding, -1)
+
+	if !ok {
+		d += latencyFailurePenalty
+	}
+
+	ns.Lock()
+	defer ns.Unlock()
+
+	if ns.latency == 0 {
+		ns.latency = float64(d)
+
+		return
+	}
+
+	ns.latency = latencyEWMAWeight*float64(d) +
+		(1-latencyEWMAWeight)*ns.latency
+}
+
+// load returns the measurements for the node as a NodeLoad value.
+func (ns *nodeStats) load(node *SnowthNode) NodeLoad {
+	ns.Lock()
+	defer ns.Unlock()
+
+	return NodeLoad{
+		Node:          node,
+		Outstanding:   atomic.LoadInt64(&ns.outstanding),
+		Latency:       time.Duration(ns.latency),
+		GossipLatency: time.Duration(ns.gossipLatency * float64(time.Second)),
+	}
+}
+
+// getNodeStats returns the request measurements for a node, creating them if
+// needed.
+func (sc *SnowthClient) getNodeStats(node *SnowthNode) *nodeStats {
+	key := node.GetURL().String()
+
+	sc.RLock()
+	ns := sc.nodeStats[key]
+	sc.RUnlock()
+
+	if ns != nil {
+		return ns
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	if ns = sc.nodeStats[key]; ns == nil {
+		ns = &nodeStats{gossipLatency: -1}
+
+		if sc.nodeStats == nil {
+			sc.nodeStats = map[string]*nodeStats{}
+		}
+
+		sc.nodeStats[key] = ns
+	}
+
+	return ns
+}
+
+// updateGossipLatency updates the gossip latency measurements for all known
+// nodes from gossip information retrieved from the cluster.
+func (sc *SnowthClient) updateGossipLatency(gossip *Gossip) {
+	if gossip == nil {
+		return
+	}
+
+	nodes := append(sc.ListActiveNodes(), sc.ListInactiveNodes()...)
+
+	for _, node := range nodes {
+		id := node.id()
+		if id == "" {
+			continue
+		}
+
+		total, count := 0.0, 0
+
+		for _, entry := range []GossipDetail(*gossip) {
+			v, ok := entry.Latency[id]
+			if !ok || entry.ID == id {
+				continue
+			}
+
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+
+			total += f
+			count++
+		}
+
+		if count == 0 {
+			continue
+		}
+
+		ns := sc.getNodeStats(node)
+
+		ns.Lock()
+		ns.gossipLatency = total / float64(count)
+		ns.Unlock()
+	}
+}
+
+// NodeSelector gets the strategy a SnowthClient uses to choose which node
+// will receive a request.
+func (sc *SnowthClient) NodeSelector() NodeSelector {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.nodeSelector == nil {
+		sc.nodeSelector = &RandomSelector{}
+	}
+
+	return sc.nodeSelector
+}
+
+// SetNodeSelector sets the strategy a SnowthClient uses to choose which node
+// will receive a request. A nil value restores the default random selection.
+func (sc *SnowthClient) SetNodeSelector(ns NodeSelector) {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.nodeSelector = ns
+}
+
+// selectNode uses the client node selector to choose one of the candidate
+// nodes.
+func (sc *SnowthClient) selectNode(nodes []*SnowthNode) *SnowthNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+
+	candidates := make([]NodeLoad, len(nodes))
+	for i, node := range nodes {
+		candidates[i] = sc.getNodeStats(node).load(node)
+	}
+
+	return sc.NodeSelector().Select(candidates)
+}
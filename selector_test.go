@@ -0,0 +1,160 @@
+package gosnowth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func testSelectorNodes(t *testing.T, n int) []*SnowthNode {
+	t.Helper()
+
+	nodes := make([]*SnowthNode, n)
+
+	for i := range nodes {
+		u, err := url.Parse("http://node" + string(rune('a'+i)) + ":8112")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		nodes[i] = &SnowthNode{url: u, identifier: string(rune('a' + i))}
+	}
+
+	return nodes
+}
+
+func TestNodeSelectors(t *testing.T) {
+	t.Parallel()
+
+	nodes := testSelectorNodes(t, 3)
+	loads := []NodeLoad{
+		{
+			Node: nodes[0], Outstanding: 4, Latency: 5 * time.Millisecond,
+			GossipLatency: -1,
+		},
+		{
+			Node: nodes[1], Outstanding: 1, Latency: 20 * time.Millisecond,
+			GossipLatency: 3 * time.Millisecond,
+		},
+		{
+			Node: nodes[2], Outstanding: 2, Latency: 30 * time.Millisecond,
+			GossipLatency: time.Millisecond,
+		},
+	}
+
+	rr := &RoundRobinSelector{}
+
+	for i := 0; i < 6; i++ {
+		if n := rr.Select(loads); n != nodes[i%3] {
+			t.Errorf("Expected round robin node %d: %v, got: %v",
+				i, nodes[i%3].identifier, n.identifier)
+		}
+	}
+
+	if n := (&LeastOutstandingSelector{}).Select(loads); n != nodes[1] {
+		t.Errorf("Expected least outstanding node: b, got: %v", n.identifier)
+	}
+
+	if n := (&LatencyEWMASelector{}).Select(loads); n != nodes[0] {
+		t.Errorf("Expected lowest latency node: a, got: %v", n.identifier)
+	}
+
+	if n := (&GossipLatencySelector{}).Select(loads); n != nodes[2] {
+		t.Errorf("Expected lowest gossip latency node: c, got: %v",
+			n.identifier)
+	}
+
+	if n := (&RandomSelector{}).Select(loads); n == nil {
+		t.Error("Expected random node")
+	}
+}
+
+func TestNodeStats(t *testing.T) {
+	t.Parallel()
+
+	ns := &nodeStats{gossipLatency: -1}
+	ns.begin()
+	ns.begin()
+
+	nl := ns.load(nil)
+	if nl.Outstanding != 2 {
+		t.Errorf("Expected outstanding: 2, got: %v", nl.Outstanding)
+	}
+
+	if nl.GossipLatency >= 0 {
+		t.Errorf("Expected unknown gossip latency, got: %v", nl.GossipLatency)
+	}
+
+	ns.end(100*time.Millisecond, true)
+	ns.end(200*time.Millisecond, true)
+
+	nl = ns.load(nil)
+	if nl.Outstanding != 0 {
+		t.Errorf("Expected outstanding: 0, got: %v", nl.Outstanding)
+	}
+
+	if nl.Latency != 130*time.Millisecond {
+		t.Errorf("Expected latency: 130ms, got: %v", nl.Latency)
+	}
+
+	// Cancelled requests are not measured.
+	ns.begin()
+	ns.abort()
+
+	nl = ns.load(nil)
+	if nl.Outstanding != 0 || nl.Latency != 130*time.Millisecond {
+		t.Errorf("Expected outstanding: 0, latency: 130ms, got: %+v", nl)
+	}
+
+	// Failed requests are penalized, however quickly they fail.
+	ns.begin()
+	ns.end(time.Millisecond, false)
+
+	if nl = ns.load(nil); nl.Latency < time.Second {
+		t.Errorf("Expected penalized latency, got: %v", nl.Latency)
+	}
+}
+
+func TestGetActiveNodeOwners(t *testing.T) {
+	t.Parallel()
+
+	nodes := testSelectorNodes(t, 4)
+	sc := &SnowthClient{}
+
+	sc.AddNodes(nodes...)
+	sc.ActivateNodes(nodes...)
+	sc.SetNodeSelector(&RoundRobinSelector{})
+
+	seen := map[string]int{}
+
+	for i := 0; i < 10; i++ {
+		n := sc.GetActiveNode([]string{"x", "c", "b"})
+		seen[n.identifier]++
+	}
+
+	if len(seen) != 2 || seen["b"] != 5 || seen["c"] != 5 {
+		t.Errorf("Expected reads spread across owners b and c, got: %v", seen)
+	}
+
+	if n := sc.GetActiveNode([]string{"x"}); n == nil {
+		t.Error("Expected an active node when no owners are active")
+	}
+
+	gossip := Gossip{
+		{ID: "a", Latency: GossipLatency{"b": "0.004", "c": "0.001"}},
+		{ID: "d", Latency: GossipLatency{"b": "0.002", "c": "0.001"}},
+	}
+
+	sc.updateGossipLatency(&gossip)
+	sc.SetNodeSelector(&GossipLatencySelector{})
+
+	if n := sc.GetActiveNode([]string{"b", "c"}); n != nodes[2] {
+		t.Errorf("Expected lowest gossip latency node: c, got: %v",
+			n.identifier)
+	}
+
+	nl := sc.getNodeStats(nodes[1]).load(nodes[1])
+	if nl.GossipLatency != 3*time.Millisecond {
+		t.Errorf("Expected gossip latency: 3ms, got: %v", nl.GossipLatency)
+	}
+}
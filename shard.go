@@ -0,0 +1,267 @@
+package gosnowth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// NodeWriteResult values contain the result of the part of a write sent to
+// one node.
+type NodeWriteResult struct {
+	// Node is the node the records were sent to. It is nil if no active node
+	// was available to receive them.
+	Node *SnowthNode
+
+	// Records is the number of records sent to the node.
+	Records int
+
+	// Err is the error returned by the node, if the write failed.
+	Err error
+}
+
+// WriteReport values report the results of a write which was split into
+// batches by the nodes owning the written metrics.
+type WriteReport struct {
+	// Nodes contains the result of each batch, in the order they were created.
+	Nodes []NodeWriteResult
+}
+
+// Records returns the total number of records written.
+func (wr *WriteReport) Records() int {
+	n := 0
+
+	for _, r := range wr.Nodes {
+		n += r.Records
+	}
+
+	return n
+}
+
+// Failed returns the number of records which were not written because the
+// batch containing them failed.
+func (wr *WriteReport) Failed() int {
+	n := 0
+
+	for _, r := range wr.Nodes {
+		if r.Err != nil {
+			n += r.Records
+		}
+	}
+
+	return n
+}
+
+// Err returns a *WriteError if any batch of the write failed, or nil.
+func (wr *WriteReport) Err() error {
+	for _, r := range wr.Nodes {
+		if r.Err != nil {
+			return &WriteError{Report: wr}
+		}
+	}
+
+	return nil
+}
+
+// WriteError values are returned by writes split into batches by node when
+// some or all of the batches failed, even if the write was sent as a single
+// batch. The report contains the result of every batch, so that partial
+// failures can be handled. Errors returned by write methods can be converted
+// to WriteError values using errors.As().
+type WriteError struct {
+	Report *WriteReport
+}
+
+// Error returns the error message, which includes the error of each failed
+// batch.
+func (we *WriteError) Error() string {
+	msgs := []string{}
+	nodes := 0
+
+	for _, r := range we.Report.Nodes {
+		if r.Err == nil {
+			continue
+		}
+
+		nodes++
+
+		host := "no node"
+		if r.Node != nil && r.Node.GetURL() != nil {
+			host = r.Node.GetURL().Host
+		}
+
+		msgs = append(msgs, host+": "+r.Err.Error())
+	}
+
+	return fmt.Sprintf("write failed for %d of %d records on %d nodes: %s",
+		we.Report.Failed(), we.Report.Records(), nodes,
+		strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the error of the first failed batch, so that errors.Is()
+// and errors.As() can be used to inspect it.
+func (we *WriteError) Unwrap() error {
+	for _, r := range we.Report.Nodes {
+		if r.Err != nil {
+			return r.Err
+		}
+	}
+
+	return nil
+}
+
+// WithWriteReport sets a report which is filled with the per node results of
+// a write, whether or not it succeeds. It is used by WriteNumericContext,
+// WriteNNTContext, WriteTextContext and WriteHistogramContext.
+func WithWriteReport(report *WriteReport) CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		co.report = report
+	})
+}
+
+// writeBatch values contain the indexes of the records sent to a node.
+type writeBatch struct {
+	node *SnowthNode
+	idx  []int
+}
+
+// shardWrites groups the records of a write by the active node owning their
+// metrics. The metric function returns the check UUID and metric name of a
+// record. Records whose owners are all inactive are sent to another active
+// node, and are grouped with a nil node if there is none.
+func (sc *SnowthClient) shardWrites(ctx context.Context, n int,
+	metric func(i int) (string, string),
+) []*writeBatch {
+	topo, err := sc.topologyContext(ctx)
+	if err != nil {
+		topo = nil
+	}
+
+	// Records with the same owners are sent to the same node, so that a load
+	// balancing node selector is consulted once per owner set.
+	owners := map[string]*SnowthNode{}
+	batches := []*writeBatch{}
+	byNode := map[*SnowthNode]*writeBatch{}
+
+	for i := 0; i < n; i++ {
+		var ids []string
+
+		if topo != nil {
+			id, name := metric(i)
+
+			if tn, err := topo.FindMetric(id, name); err == nil {
+				for _, t := range tn {
+					ids = append(ids, t.ID)
+				}
+			}
+		}
+
+		key := strings.Join(ids, ",")
+
+		node, ok := owners[key]
+		if !ok {
+			node = sc.GetActiveNode(ids)
+			owners[key] = node
+		}
+
+		b := byNode[node]
+		if b == nil {
+			b = &writeBatch{node: node}
+			byNode[node] = b
+			batches = append(batches, b)
+		}
+
+		b.idx = append(b.idx, i)
+	}
+
+	return batches
+}
+
+// shardWriteContext sends the records of a write to the nodes owning their
+// metrics, in parallel. The send function sends the records with the given
+// indexes to a node. If a node is set by the call options, all of the records
+// are sent to it. Any failed batches are returned as a *WriteError.
+func (sc *SnowthClient) shardWriteContext(ctx context.Context,
+	opts []CallOption, n int, metric func(i int) (string, string),
+	send func(ctx context.Context, node *SnowthNode, idx []int) error,
+) error {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	var report *WriteReport
+	if len(opts) > 0 {
+		report = callOptionsFromContext(ctx).report
+	}
+
+	var batches []*writeBatch
+
+	switch {
+	case node != nil:
+		batches = []*writeBatch{{node: node, idx: indexes(n)}}
+	case n == 0:
+		batches = []*writeBatch{{node: sc.GetActiveNode()}}
+	default:
+		batches = sc.shardWrites(ctx, n, metric)
+	}
+
+	wr := &WriteReport{Nodes: make([]NodeWriteResult, len(batches))}
+
+	// A single batch is sent without starting a goroutine.
+	if len(batches) == 1 {
+		wr.Nodes[0] = sc.sendBatch(ctx, batches[0], send)
+
+		if report != nil {
+			*report = *wr
+		}
+
+		return wr.Err()
+	}
+
+	var wg sync.WaitGroup
+
+	for i, b := range batches {
+		wg.Add(1)
+
+		go func(i int, b *writeBatch) {
+			defer wg.Done()
+
+			wr.Nodes[i] = sc.sendBatch(ctx, b, send)
+		}(i, b)
+	}
+
+	wg.Wait()
+
+	if report != nil {
+		*report = *wr
+	}
+
+	return wr.Err()
+}
+
+// sendBatch sends a batch of records to its node.
+func (sc *SnowthClient) sendBatch(ctx context.Context, b *writeBatch,
+	send func(ctx context.Context, node *SnowthNode, idx []int) error,
+) NodeWriteResult {
+	r := NodeWriteResult{Node: b.node, Records: len(b.idx)}
+
+	if b.node == nil {
+		r.Err = ErrNoActiveNode
+
+		return r
+	}
+
+	r.Err = send(ctx, b.node, b.idx)
+
+	return r
+}
+
+// indexes returns the indexes of n records.
+func indexes(n int) []int {
+	r := make([]int, n)
+	for i := range r {
+		r[i] = i
+	}
+
+	return r
+}
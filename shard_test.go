@@ -0,0 +1,182 @@
+package gosnowth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+const shardTopologyXMLTestData = `<nodes write_copies="1">
+<node id="0c4e7b5a-2cd6-4a5a-9d6c-5a8f6f4c4d01" address="127.0.0.1" port="1" apiport="1" weight="32"/>
+<node id="6b3f0b0e-8d6a-4f0e-9a9e-1e2f3a4b5c02" address="127.0.0.1" port="2" apiport="2" weight="32"/>
+</nodes>`
+
+const shardTestUUID = "3aa57ac2-28de-4ec4-aa3d-ed0ddd48fa4d"
+
+func TestShardWrites(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+
+	written := map[string][]string{}
+
+	handler := func(id string, fail bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.RequestURI == "/stats.json" {
+				_, _ = w.Write([]byte(statsTestData))
+
+				return
+			}
+
+			if fail {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			var data []struct {
+				Metric string `json:"metric"`
+			}
+
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			mu.Lock()
+			for _, d := range data {
+				written[id] = append(written[id], d.Metric)
+			}
+			mu.Unlock()
+		}
+	}
+
+	topo, err := TopologyLoadXML(shardTopologyXMLTestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{topo.Nodes[0].ID, topo.Nodes[1].ID}
+
+	ms0 := httptest.NewServer(handler(ids[0], false))
+	defer ms0.Close()
+
+	ms1 := httptest.NewServer(handler(ids[1], true))
+	defer ms1.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms0.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	u, err := url.Parse(ms1.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	node0 := sc.ListActiveNodes()[0]
+	node0.identifier = ids[0]
+	node1 := &SnowthNode{url: u, identifier: ids[1]}
+
+	sc.AddNodes(node1)
+	sc.ActivateNodes(node1)
+	sc.topology.store(topo.Hash, topo)
+
+	data := []NumericWrite{}
+	owned := map[string]int{}
+
+	for i := 0; i < 20; i++ {
+		metric := fmt.Sprintf("test%d", i)
+		data = append(data, NumericWrite{ID: shardTestUUID, Metric: metric})
+
+		owners, err := topo.FindMetricNodeIDs(shardTestUUID, metric)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		owned[owners[0]]++
+	}
+
+	if owned[ids[0]] == 0 || owned[ids[1]] == 0 {
+		t.Fatalf("Expected metrics owned by both nodes, got: %v", owned)
+	}
+
+	report := &WriteReport{}
+
+	err = sc.WriteNumericContext(context.Background(), data,
+		WithWriteReport(report))
+
+	var we *WriteError
+	if !errors.As(err, &we) {
+		t.Fatalf("Expected WriteError, got: %v", err)
+	}
+
+	var ie *IRONdbError
+	if !errors.As(err, &ie) || ie.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected IRONdbError status: 400, got: %v", err)
+	}
+
+	if len(report.Nodes) != 2 {
+		t.Fatalf("Expected node results: 2, got: %v", len(report.Nodes))
+	}
+
+	if report.Records() != 20 {
+		t.Errorf("Expected records: 20, got: %v", report.Records())
+	}
+
+	if report.Failed() != owned[ids[1]] {
+		t.Errorf("Expected failed: %v, got: %v", owned[ids[1]],
+			report.Failed())
+	}
+
+	for _, r := range report.Nodes {
+		if r.Node == node0 && r.Err != nil {
+			t.Errorf("Expected no error from node: %v, got: %v", ids[0], r.Err)
+		}
+	}
+
+	mu.Lock()
+
+	if len(written[ids[0]]) != owned[ids[0]] {
+		t.Errorf("Expected written: %v, got: %v", owned[ids[0]],
+			len(written[ids[0]]))
+	}
+
+	for _, m := range written[ids[0]] {
+		owners, _ := topo.FindMetricNodeIDs(shardTestUUID, m)
+		if owners[0] != ids[0] {
+			t.Errorf("Expected metric owned by: %v, got: %v", ids[0],
+				owners[0])
+		}
+	}
+
+	mu.Unlock()
+
+	// Writes with a node set are not split.
+	if err := sc.WriteNumericContext(context.Background(), data,
+		WithNode(node0), WithWriteReport(report)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Nodes) != 1 || report.Nodes[0].Records != 20 {
+		t.Errorf("Expected one batch of 20 records, got: %+v", report.Nodes)
+	}
+
+	// Failed writes sent as one batch also return a WriteError.
+	err = sc.WriteNumericContext(context.Background(), data, WithNode(node1))
+	if !errors.As(err, &we) || len(we.Report.Nodes) != 1 {
+		t.Errorf("Expected WriteError with one batch, got: %v", err)
+	}
+
+	if !errors.As(err, &ie) || ie.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected IRONdbError status: 400, got: %v", err)
+	}
+}
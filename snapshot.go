@@ -0,0 +1,252 @@
+package gosnowth
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format written by
+// SaveSnapshot().
+const SnapshotVersion = 1
+
+// SnapshotNode values represent a node saved in a topology snapshot.
+type SnapshotNode struct {
+	URL             string `json:"url"`
+	ID              string `json:"id,omitempty"`
+	SemVer          string `json:"semver,omitempty"`
+	CurrentTopology string `json:"current_topology,omitempty"`
+	Active          bool   `json:"active"`
+}
+
+// TopologySnapshot values contain the cluster state known by a SnowthClient,
+// which can be saved and used to start a client without contacting any
+// nodes.
+type TopologySnapshot struct {
+	Version  int            `json:"version"`
+	Time     time.Time      `json:"time"`
+	Topology string         `json:"topology,omitempty"`
+	XML      string         `json:"topology_xml,omitempty"`
+	Nodes    []SnapshotNode `json:"nodes"`
+}
+
+// Snapshot returns a snapshot of the nodes and the compiled topology known by
+// the client. The topology is only included if it has already been retrieved.
+func (sc *SnowthClient) Snapshot() (*TopologySnapshot, error) {
+	sc.RLock()
+	hash := sc.currentTopology
+	sc.RUnlock()
+
+	topo := sc.topology.get(hash)
+
+	snap := &TopologySnapshot{
+		Version:  SnapshotVersion,
+		Time:     time.Now(),
+		Topology: hash,
+		Nodes:    []SnapshotNode{},
+	}
+
+	if topo != nil {
+		b, err := xml.Marshal(topo)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode topology: %w", err)
+		}
+
+		snap.XML = string(b)
+	}
+
+	for _, n := range sc.ListActiveNodes() {
+		snap.Nodes = append(snap.Nodes, snapshotNode(n, true))
+	}
+
+	for _, n := range sc.ListInactiveNodes() {
+		snap.Nodes = append(snap.Nodes, snapshotNode(n, false))
+	}
+
+	return snap, nil
+}
+
+// snapshotNode converts a node into a snapshot node.
+func snapshotNode(n *SnowthNode, active bool) SnapshotNode {
+	return SnapshotNode{
+		URL:             n.GetURL().String(),
+		ID:              n.id(),
+		SemVer:          n.SemVer(),
+		CurrentTopology: n.GetCurrentTopology(),
+		Active:          active,
+	}
+}
+
+// SaveSnapshot writes a snapshot of the nodes and the compiled topology known
+// by the client to a writer.
+func (sc *SnowthClient) SaveSnapshot(w io.Writer) error {
+	snap, err := sc.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(snap); err != nil {
+		return fmt.Errorf("unable to encode snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// SaveSnapshotFile writes a snapshot of the nodes and the compiled topology
+// known by the client to a file. The file is replaced atomically, so an
+// existing snapshot is not lost if writing fails.
+func (sc *SnowthClient) SaveSnapshotFile(name string) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to create snapshot file: %w", err)
+	}
+
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+
+	if err := sc.SaveSnapshot(f); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("unable to write snapshot file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to write snapshot file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), name); err != nil {
+		return fmt.Errorf("unable to write snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSnapshot reads a topology snapshot written by SaveSnapshot().
+func LoadSnapshot(r io.Reader) (*TopologySnapshot, error) {
+	snap := &TopologySnapshot{}
+
+	if err := decodeJSON(r, snap); err != nil {
+		return nil, fmt.Errorf("unable to decode snapshot: %w", err)
+	}
+
+	if snap.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version: %d",
+			snap.Version)
+	}
+
+	return snap, nil
+}
+
+// LoadSnapshotFile reads a topology snapshot written by SaveSnapshotFile().
+func LoadSnapshotFile(name string) (*TopologySnapshot, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open snapshot file: %w", err)
+	}
+
+	defer f.Close()
+
+	return LoadSnapshot(f)
+}
+
+// restoreSnapshot adds the nodes and topology contained in a snapshot to the
+// client.
+func (sc *SnowthClient) restoreSnapshot(snap *TopologySnapshot) error {
+	var topo *Topology
+
+	if snap.XML != "" {
+		t, err := TopologyLoadXML(snap.XML)
+		if err != nil {
+			return fmt.Errorf("unable to load snapshot topology: %w", err)
+		}
+
+		if t.Hash != snap.Topology {
+			return fmt.Errorf("snapshot topology hash mismatch: %s != %s",
+				t.Hash, snap.Topology)
+		}
+
+		topo = t
+	}
+
+	active := []*SnowthNode{}
+	inactive := []*SnowthNode{}
+
+	for _, sn := range snap.Nodes {
+		u, err := url.Parse(sn.URL)
+		if err != nil {
+			return fmt.Errorf("unable to parse snapshot node url %s: %w",
+				sn.URL, err)
+		}
+
+		node := &SnowthNode{
+			url:             u,
+			identifier:      sn.ID,
+			semVer:          sn.SemVer,
+			currentTopology: sn.CurrentTopology,
+		}
+
+		if sn.Active {
+			active = append(active, node)
+		} else {
+			inactive = append(inactive, node)
+		}
+	}
+
+	sc.AddNodes(append(active, inactive...)...)
+	sc.ActivateNodes(active...)
+
+	sc.Lock()
+
+	oldTopo := sc.currentTopology
+
+	if snap.Topology != "" {
+		sc.currentTopology = snap.Topology
+		sc.snapshotTopology = true
+	}
+
+	sc.Unlock()
+
+	if topo != nil {
+		sc.topology.store(snap.Topology, topo)
+	}
+
+	sc.emit(topologyEvent(nil, oldTopo, snap.Topology)...)
+
+	return nil
+}
+
+// configSnapshot returns the snapshot used to start a client, if one has been
+// configured. A missing snapshot file is not an error, so that a client can
+// be started before its first snapshot has been saved.
+func configSnapshot(cfg *Config) (*TopologySnapshot, error) {
+	if cfg.Snapshot != nil || cfg.SnapshotFile == "" {
+		return cfg.Snapshot, nil
+	}
+
+	snap, err := LoadSnapshotFile(cfg.SnapshotFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return snap, nil
+}
@@ -0,0 +1,239 @@
+package gosnowth
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	topo, err := TopologyLoadXML(topologyXMLTestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	u, err := url.Parse(dead.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	sc := &SnowthClient{currentTopology: topo.Hash}
+	sc.topology.store(topo.Hash, topo)
+
+	node := &SnowthNode{
+		url:             u,
+		identifier:      "5c32c076-ffeb-cfdd-a541-97e25c028dd6",
+		semVer:          "0.1.1570000000",
+		currentTopology: topo.Hash,
+	}
+
+	other := &SnowthNode{url: &url.URL{Scheme: "http", Host: "10.0.0.1:8112"}}
+
+	sc.AddNodes(node, other)
+	sc.ActivateNodes(node)
+
+	buf := &bytes.Buffer{}
+
+	if err := sc.SaveSnapshot(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := LoadSnapshot(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snap.Topology != topo.Hash || len(snap.Nodes) != 2 {
+		t.Fatalf("Expected snapshot topology and nodes, got: %+v", snap)
+	}
+
+	nc, err := NewClient(context.Background(), &Config{
+		Servers:  []string{dead.URL},
+		Snapshot: snap,
+	})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	defer func() {
+		_ = nc.Close(context.Background())
+	}()
+
+	active := nc.ListActiveNodes()
+	if len(active) != 1 || active[0].identifier != node.identifier ||
+		active[0].SemVer() != node.semVer {
+		t.Errorf("Expected active node: %v, got: %v", node.identifier, active)
+	}
+
+	exp, err := topo.FindMetricNodeIDs("3aa57ac2-28de-4ec4-aa3d-ed0ddd48fa4d",
+		"test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := nc.FindMetricNodeIDs("3aa57ac2-28de-4ec4-aa3d-ed0ddd48fa4d", "test")
+	if strings.Join(ids, ",") != strings.Join(exp, ",") {
+		t.Errorf("Expected node IDs: %v, got: %v", exp, ids)
+	}
+
+	snap.XML = strings.Replace(snap.XML, `weight="51"`, `weight="50"`, 1)
+
+	if _, err := NewClient(context.Background(), &Config{
+		Snapshot: snap,
+	}); err == nil {
+		t.Error("Expected error for snapshot hash mismatch")
+	}
+
+	buf = bytes.NewBufferString(`{"version":2}`)
+
+	if _, err := LoadSnapshot(buf); err == nil {
+		t.Error("Expected error for unsupported snapshot version")
+	}
+}
+
+func TestSnapshotFile(t *testing.T) {
+	t.Parallel()
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	defer ms.Close()
+
+	name := filepath.Join(t.TempDir(), "snapshot.json")
+
+	// A missing snapshot file starts the client normally.
+	sc, err := NewClient(context.Background(), &Config{
+		Servers:      []string{ms.URL},
+		SnapshotFile: name,
+	})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	if err := sc.SaveSnapshotFile(name); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := LoadSnapshotFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snap.Nodes) != 1 || snap.Nodes[0].URL != ms.URL ||
+		snap.Nodes[0].ID != "bb6f7162-4828-11df-bab8-6bac200dcc2a" ||
+		!snap.Nodes[0].Active {
+		t.Errorf("Expected snapshot node: %v, got: %+v", ms.URL, snap.Nodes)
+	}
+
+	if snap.Topology == "" || snap.XML != "" {
+		t.Errorf("Expected topology hash without XML, got: %+v", snap)
+	}
+
+	nc, err := NewClient(context.Background(), &Config{
+		SnapshotFile: name,
+	})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	if len(nc.ListActiveNodes()) != 1 {
+		t.Errorf("Expected active nodes: 1, got: %v",
+			len(nc.ListActiveNodes()))
+	}
+}
+
+func TestSnapshotTopologyChange(t *testing.T) {
+	t.Parallel()
+
+	oldTopo, err := TopologyLoadXML(topologyXMLTestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newXML := strings.Replace(topologyXMLTestData, `weight="51"`,
+		`weight="50"`, 1)
+
+	newTopo, err := TopologyLoadXML(newXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := `{"identity":{"_type":"s","_value":"live"},` +
+		`"semver":{"_type":"s","_value":"1.0.0"},` +
+		`"topology":{"current":{"_type":"s","_value":"` + newTopo.Hash +
+		`"},"next":{"_type":"s","_value":"-"}}}`
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		switch r.RequestURI {
+		case "/stats.json":
+			_, _ = w.Write([]byte(stats))
+		case "/topology/xml/" + newTopo.Hash:
+			_, _ = w.Write([]byte(newXML))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer ms.Close()
+
+	snap := &TopologySnapshot{
+		Version:  1,
+		Topology: oldTopo.Hash,
+		XML:      topologyXMLTestData,
+	}
+
+	// The nodes saved in the snapshot do not prevent the client from moving
+	// to the topology reported by a live node.
+	for _, host := range []string{"10.0.0.1:8112", "10.0.0.2:8112"} {
+		snap.Nodes = append(snap.Nodes, SnapshotNode{
+			URL:             "http://" + host,
+			CurrentTopology: oldTopo.Hash,
+			Active:          true,
+		})
+	}
+
+	sc, err := NewClient(context.Background(), &Config{
+		Servers:  []string{ms.URL},
+		Snapshot: snap,
+	})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	defer func() {
+		_ = sc.Close(context.Background())
+	}()
+
+	deadline := time.Now().Add(time.Second)
+
+	for sc.TopologyCacheStatus().Hash != newTopo.Hash {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected cached topology: %v, got: %+v", newTopo.Hash,
+				sc.TopologyCacheStatus())
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,1711 @@
+// Package snowthfake provides a configurable fake implementation of the
+// gosnowth.Client interface, which can be used to unit test code using an
+// IRONdb client without making any HTTP requests.
+//
+// Each method of the fake records its arguments and calls the matching
+// function field, if it is set. Both a method and its *Context variant call
+// the same function field, which always receives a context and call options.
+// When the function field is not set, methods return zero values and the
+// value of the Err field.
+package snowthfake
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/gosnowth"
+	"github.com/circonus-labs/gosnowth/fb/fetch"
+	"github.com/circonus-labs/gosnowth/fb/nntbs"
+	"github.com/circonus-labs/gosnowth/fb/noit"
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// Call values contain the method name and arguments of a call made to a fake
+// client. The context and call option arguments are not recorded.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// Client values are fake IRONdb clients implementing gosnowth.Client.
+type Client struct {
+	// Err is returned by methods with an error result whose function field
+	// is not set.
+	Err error
+
+	// Functions called by the gosnowth.DataReader methods.
+	FetchValuesFunc func(ctx context.Context, q *gosnowth.FetchQuery,
+		opts ...gosnowth.CallOption,
+	) (*gosnowth.DF4Response, error)
+	FetchValuesFbFunc func(ctx context.Context, node *gosnowth.SnowthNode,
+		q *fetch.FetchT, opts ...gosnowth.CallOption,
+	) (*fetch.DF4T, error)
+	ReadNumericValuesFunc func(ctx context.Context, start, end time.Time,
+		period int64, t, id, metric string, opts ...gosnowth.CallOption,
+	) ([]gosnowth.NumericValue, error)
+	ReadNumericAllValuesFunc func(ctx context.Context, start, end time.Time,
+		period int64, id, metric string, opts ...gosnowth.CallOption,
+	) ([]gosnowth.NumericAllValue, error)
+	ReadNNTValuesFunc func(ctx context.Context, start, end time.Time,
+		period int64, t, id, metric string, opts ...gosnowth.CallOption,
+	) ([]gosnowth.NNTValue, error)
+	ReadNNTAllValuesFunc func(ctx context.Context, start, end time.Time,
+		period int64, id, metric string, opts ...gosnowth.CallOption,
+	) ([]gosnowth.NNTAllValue, error)
+	ReadHistogramValuesFunc func(ctx context.Context, uuid, metric string,
+		period time.Duration, start, end time.Time,
+		opts ...gosnowth.CallOption,
+	) ([]gosnowth.HistogramValue, error)
+	ReadRawNumericValuesFunc func(ctx context.Context, start, end time.Time,
+		uuid, metric string, opts ...gosnowth.CallOption,
+	) ([]gosnowth.RawNumericValue, error)
+	ReadRollupValuesFunc func(ctx context.Context, uuid, metric string,
+		period time.Duration, start, end time.Time, dataType string,
+		opts ...gosnowth.CallOption,
+	) ([]gosnowth.RollupValue, error)
+	ReadRollupAllValuesFunc func(ctx context.Context, uuid, metric string,
+		period time.Duration, start, end time.Time,
+		opts ...gosnowth.CallOption,
+	) ([]gosnowth.RollupAllValue, error)
+	ReadTextValuesFunc func(ctx context.Context, uuid, metric string,
+		start, end time.Time, opts ...gosnowth.CallOption,
+	) ([]gosnowth.TextValue, error)
+	GraphiteGetDatapointsFunc func(ctx context.Context, accountID int64,
+		prefix string, lookup *gosnowth.GraphiteLookup,
+		options *gosnowth.GraphiteOptions, opts ...gosnowth.CallOption,
+	) (*gosnowth.GraphiteDatapoints, error)
+
+	// Functions called by the gosnowth.DataWriter methods.
+	WriteNumericFunc func(ctx context.Context, data []gosnowth.NumericWrite,
+		opts ...gosnowth.CallOption,
+	) error
+	WriteNNTFunc func(ctx context.Context, data []gosnowth.NNTData,
+		opts ...gosnowth.CallOption,
+	) error
+	WriteTextFunc func(ctx context.Context, data []gosnowth.TextData,
+		opts ...gosnowth.CallOption,
+	) error
+	WriteHistogramFunc func(ctx context.Context, data []gosnowth.HistogramData,
+		opts ...gosnowth.CallOption,
+	) error
+	WriteRawFunc func(ctx context.Context, data io.Reader, fb bool,
+		dataPoints uint64, opts ...gosnowth.CallOption,
+	) (*gosnowth.IRONdbPutResponse, error)
+	WriteRawMetricListFunc func(ctx context.Context,
+		metricList *noit.MetricListT, builder *flatbuffers.Builder,
+		opts ...gosnowth.CallOption,
+	) (*gosnowth.IRONdbPutResponse, error)
+	WriteNNTBSFlatbufferFunc func(ctx context.Context, merge *nntbs.NNTMergeT,
+		builder *flatbuffers.Builder, opts ...gosnowth.CallOption,
+	) error
+	RebuildActivityFunc func(ctx context.Context, node *gosnowth.SnowthNode,
+		rebuildRequest []gosnowth.RebuildActivityRequest,
+		opts ...gosnowth.CallOption,
+	) (*gosnowth.IRONdbPutResponse, error)
+
+	// Functions called by the gosnowth.TagClient methods.
+	FindTagsFunc func(ctx context.Context, accountID int64, query string,
+		options *gosnowth.FindTagsOptions, opts ...gosnowth.CallOption,
+	) (*gosnowth.FindTagsResult, error)
+	FindTagCatsFunc func(ctx context.Context, accountID int64, query string,
+		opts ...gosnowth.CallOption,
+	) ([]string, error)
+	FindTagValsFunc func(ctx context.Context, accountID int64,
+		query, category string, opts ...gosnowth.CallOption,
+	) ([]string, error)
+	GetCheckTagsFunc func(ctx context.Context, checkUUID string,
+		opts ...gosnowth.CallOption,
+	) (gosnowth.CheckTags, error)
+	UpdateCheckTagsFunc func(ctx context.Context, checkUUID string,
+		tags []string, opts ...gosnowth.CallOption,
+	) (int64, error)
+	DeleteCheckTagsFunc func(ctx context.Context, checkUUID string,
+		opts ...gosnowth.CallOption,
+	) error
+	GraphiteFindMetricsFunc func(ctx context.Context, accountID int64,
+		prefix, query string, options *gosnowth.GraphiteOptions,
+		opts ...gosnowth.CallOption,
+	) ([]gosnowth.GraphiteMetric, error)
+	GraphiteFindTagsFunc func(ctx context.Context, accountID int64,
+		prefix, query string, options *gosnowth.GraphiteOptions,
+		opts ...gosnowth.CallOption,
+	) ([]gosnowth.GraphiteMetric, error)
+
+	// Functions called by the gosnowth.TopologyClient methods.
+	TopologyFunc            func() (*gosnowth.Topology, error)
+	TopologyCacheStatusFunc func() gosnowth.TopologyCacheStatus
+	FindMetricNodeIDsFunc   func(uuid, metric string) []string
+	GetTopologyInfoFunc     func(ctx context.Context, opts ...gosnowth.CallOption,
+	) (*gosnowth.Topology, error)
+	LoadTopologyFunc func(ctx context.Context, hash string,
+		t *gosnowth.Topology, node *gosnowth.SnowthNode,
+		opts ...gosnowth.CallOption,
+	) error
+	ActivateTopologyFunc func(ctx context.Context, hash string,
+		node *gosnowth.SnowthNode, opts ...gosnowth.CallOption,
+	) error
+	LocateMetricFunc func(ctx context.Context, uuid, metric string,
+		opts ...gosnowth.CallOption,
+	) ([]gosnowth.TopologyNode, error)
+	LocateMetricRemoteFunc func(ctx context.Context, uuid, metric string,
+		node *gosnowth.SnowthNode, opts ...gosnowth.CallOption,
+	) ([]gosnowth.TopologyNode, error)
+	GetStatsFunc func(ctx context.Context, opts ...gosnowth.CallOption,
+	) (*gosnowth.Stats, error)
+	GetStatsNodeContextFunc func(ctx context.Context,
+		node *gosnowth.SnowthNode, opts ...gosnowth.CallOption,
+	) (*gosnowth.Stats, error)
+	GetNodeStateFunc func(ctx context.Context, opts ...gosnowth.CallOption,
+	) (*gosnowth.NodeState, error)
+	GetGossipInfoFunc func(ctx context.Context, opts ...gosnowth.CallOption,
+	) (*gosnowth.Gossip, error)
+	ListActiveNodesFunc   func() []*gosnowth.SnowthNode
+	ListInactiveNodesFunc func() []*gosnowth.SnowthNode
+	GetActiveNodeFunc     func(idsets ...[]string) *gosnowth.SnowthNode
+	AddNodesFunc          func(nodes ...*gosnowth.SnowthNode)
+	ActivateNodesFunc     func(nodes ...*gosnowth.SnowthNode)
+	DeactivateNodesFunc   func(nodes ...*gosnowth.SnowthNode)
+
+	// Functions called by the gosnowth.PromQLClient methods.
+	PromQLInstantQueryFunc func(ctx context.Context,
+		query *gosnowth.PromQLInstantQuery, opts ...gosnowth.CallOption,
+	) (*gosnowth.PromQLResponse, error)
+	PromQLRangeQueryFunc func(ctx context.Context,
+		query *gosnowth.PromQLRangeQuery, opts ...gosnowth.CallOption,
+	) (*gosnowth.PromQLResponse, error)
+	PromQLSeriesQueryFunc func(ctx context.Context,
+		query *gosnowth.PromQLSeriesQuery, opts ...gosnowth.CallOption,
+	) (*gosnowth.PromQLResponse, error)
+	PromQLLabelQueryFunc func(ctx context.Context,
+		query *gosnowth.PromQLLabelQuery, opts ...gosnowth.CallOption,
+	) (*gosnowth.PromQLResponse, error)
+	PromQLLabelValuesQueryFunc func(ctx context.Context, label string,
+		query *gosnowth.PromQLLabelQuery, opts ...gosnowth.CallOption,
+	) (*gosnowth.PromQLResponse, error)
+	PromQLMetadataQueryFunc func(ctx context.Context,
+		query *gosnowth.PromQLMetadataQuery, opts ...gosnowth.CallOption,
+	) (*gosnowth.PromQLResponse, error)
+
+	// Functions called by the gosnowth.CAQLClient methods.
+	GetCAQLQueryFunc func(ctx context.Context, q *gosnowth.CAQLQuery,
+		opts ...gosnowth.CallOption,
+	) (*gosnowth.DF4Response, error)
+
+	// Functions called by the gosnowth.LuaClient methods.
+	GetLuaExtensionsFunc func(ctx context.Context, opts ...gosnowth.CallOption,
+	) (gosnowth.LuaExtensions, error)
+	ExecLuaExtensionFunc func(ctx context.Context, name string,
+		params []gosnowth.ExtParam, opts ...gosnowth.CallOption,
+	) (map[string]interface{}, error)
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Ensure that Client implements gosnowth.Client.
+var _ gosnowth.Client = (*Client)(nil)
+
+// New creates a new fake client, which returns zero values for every call.
+func New() *Client {
+	return &Client{}
+}
+
+// Calls returns the calls made to the fake client, in the order made.
+func (f *Client) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call{}, f.calls...)
+}
+
+// CallsTo returns the calls made to a method of the fake client.
+func (f *Client) CallsTo(method string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	calls := []Call{}
+
+	for _, c := range f.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+
+	return calls
+}
+
+// CallCount returns the number of calls made to a method of the fake client.
+func (f *Client) CallCount(method string) int {
+	return len(f.CallsTo(method))
+}
+
+// Reset discards the calls recorded by the fake client.
+func (f *Client) Reset() {
+	f.mu.Lock()
+	f.calls = nil
+	f.mu.Unlock()
+}
+
+// record records a call made to the fake client.
+func (f *Client) record(method string, args ...interface{}) {
+	f.mu.Lock()
+	f.calls = append(f.calls, Call{Method: method, Args: args})
+	f.mu.Unlock()
+}
+
+// nodeOptions converts the nodes parameter of methods which do not accept
+// call options into call options. As with gosnowth.SnowthClient, only the
+// first node is used.
+func nodeOptions(nodes []*gosnowth.SnowthNode) []gosnowth.CallOption {
+	if len(nodes) == 0 || nodes[0] == nil {
+		return nil
+	}
+
+	return []gosnowth.CallOption{nodes[0]}
+}
+
+// firstNode returns the first of a list of nodes, or nil.
+func firstNode(nodes []*gosnowth.SnowthNode) *gosnowth.SnowthNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	return nodes[0]
+}
+
+// FetchValues records the call and calls FetchValuesFunc, if set.
+func (f *Client) FetchValues(q *gosnowth.FetchQuery,
+	nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.DF4Response, error) {
+	f.record("FetchValues", q)
+
+	if f.FetchValuesFunc != nil {
+		return f.FetchValuesFunc(context.Background(), q,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// FetchValuesContext records the call and calls FetchValuesFunc, if set.
+func (f *Client) FetchValuesContext(ctx context.Context,
+	q *gosnowth.FetchQuery, opts ...gosnowth.CallOption,
+) (*gosnowth.DF4Response, error) {
+	f.record("FetchValuesContext", q)
+
+	if f.FetchValuesFunc != nil {
+		return f.FetchValuesFunc(ctx, q, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// FetchValuesFb records the call and calls FetchValuesFbFunc, if set.
+func (f *Client) FetchValuesFb(node *gosnowth.SnowthNode, q *fetch.FetchT,
+) (*fetch.DF4T, error) {
+	f.record("FetchValuesFb", node, q)
+
+	if f.FetchValuesFbFunc != nil {
+		return f.FetchValuesFbFunc(context.Background(), node, q)
+	}
+
+	return nil, f.Err
+}
+
+// FetchValuesFbContext records the call and calls FetchValuesFbFunc, if set.
+func (f *Client) FetchValuesFbContext(ctx context.Context,
+	node *gosnowth.SnowthNode, q *fetch.FetchT, opts ...gosnowth.CallOption,
+) (*fetch.DF4T, error) {
+	f.record("FetchValuesFbContext", node, q)
+
+	if f.FetchValuesFbFunc != nil {
+		return f.FetchValuesFbFunc(ctx, node, q, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// ReadNumericValues records the call and calls ReadNumericValuesFunc, if
+// set.
+func (f *Client) ReadNumericValues(start, end time.Time, period int64,
+	t, id, metric string, nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.NumericValue, error) {
+	f.record("ReadNumericValues", start, end, period, t, id, metric)
+
+	if f.ReadNumericValuesFunc != nil {
+		return f.ReadNumericValuesFunc(context.Background(), start, end,
+			period, t, id, metric, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadNumericValuesContext records the call and calls ReadNumericValuesFunc,
+// if set.
+func (f *Client) ReadNumericValuesContext(ctx context.Context,
+	start, end time.Time, period int64, t, id, metric string,
+	opts ...gosnowth.CallOption,
+) ([]gosnowth.NumericValue, error) {
+	f.record("ReadNumericValuesContext", start, end, period, t, id, metric)
+
+	if f.ReadNumericValuesFunc != nil {
+		return f.ReadNumericValuesFunc(ctx, start, end, period, t, id, metric,
+			opts...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadNumericAllValues records the call and calls ReadNumericAllValuesFunc,
+// if set.
+func (f *Client) ReadNumericAllValues(start, end time.Time, period int64,
+	id, metric string, nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.NumericAllValue, error) {
+	f.record("ReadNumericAllValues", start, end, period, id, metric)
+
+	if f.ReadNumericAllValuesFunc != nil {
+		return f.ReadNumericAllValuesFunc(context.Background(), start, end,
+			period, id, metric, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadNumericAllValuesContext records the call and calls
+// ReadNumericAllValuesFunc, if set.
+func (f *Client) ReadNumericAllValuesContext(ctx context.Context,
+	start, end time.Time, period int64, id, metric string,
+	opts ...gosnowth.CallOption,
+) ([]gosnowth.NumericAllValue, error) {
+	f.record("ReadNumericAllValuesContext", start, end, period, id, metric)
+
+	if f.ReadNumericAllValuesFunc != nil {
+		return f.ReadNumericAllValuesFunc(ctx, start, end, period, id, metric,
+			opts...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadNNTValues records the call and calls ReadNNTValuesFunc, if set.
+func (f *Client) ReadNNTValues(start, end time.Time, period int64,
+	t, id, metric string, nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.NNTValue, error) {
+	f.record("ReadNNTValues", start, end, period, t, id, metric)
+
+	if f.ReadNNTValuesFunc != nil {
+		return f.ReadNNTValuesFunc(context.Background(), start, end, period, t,
+			id, metric, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadNNTValuesContext records the call and calls ReadNNTValuesFunc, if set.
+func (f *Client) ReadNNTValuesContext(ctx context.Context,
+	start, end time.Time, period int64, t, id, metric string,
+	opts ...gosnowth.CallOption,
+) ([]gosnowth.NNTValue, error) {
+	f.record("ReadNNTValuesContext", start, end, period, t, id, metric)
+
+	if f.ReadNNTValuesFunc != nil {
+		return f.ReadNNTValuesFunc(ctx, start, end, period, t, id, metric,
+			opts...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadNNTAllValues records the call and calls ReadNNTAllValuesFunc, if set.
+func (f *Client) ReadNNTAllValues(start, end time.Time, period int64,
+	id, metric string, nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.NNTAllValue, error) {
+	f.record("ReadNNTAllValues", start, end, period, id, metric)
+
+	if f.ReadNNTAllValuesFunc != nil {
+		return f.ReadNNTAllValuesFunc(context.Background(), start, end, period,
+			id, metric, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadNNTAllValuesContext records the call and calls ReadNNTAllValuesFunc,
+// if set.
+func (f *Client) ReadNNTAllValuesContext(ctx context.Context,
+	start, end time.Time, period int64, id, metric string,
+	opts ...gosnowth.CallOption,
+) ([]gosnowth.NNTAllValue, error) {
+	f.record("ReadNNTAllValuesContext", start, end, period, id, metric)
+
+	if f.ReadNNTAllValuesFunc != nil {
+		return f.ReadNNTAllValuesFunc(ctx, start, end, period, id, metric,
+			opts...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadHistogramValues records the call and calls ReadHistogramValuesFunc, if
+// set.
+func (f *Client) ReadHistogramValues(uuid, metric string, period time.Duration,
+	start, end time.Time, nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.HistogramValue, error) {
+	f.record("ReadHistogramValues", uuid, metric, period, start, end)
+
+	if f.ReadHistogramValuesFunc != nil {
+		return f.ReadHistogramValuesFunc(context.Background(), uuid, metric,
+			period, start, end, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadHistogramValuesContext records the call and calls
+// ReadHistogramValuesFunc, if set.
+func (f *Client) ReadHistogramValuesContext(ctx context.Context,
+	uuid, metric string, period time.Duration, start, end time.Time,
+	opts ...gosnowth.CallOption,
+) ([]gosnowth.HistogramValue, error) {
+	f.record("ReadHistogramValuesContext", uuid, metric, period, start, end)
+
+	if f.ReadHistogramValuesFunc != nil {
+		return f.ReadHistogramValuesFunc(ctx, uuid, metric, period, start, end,
+			opts...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadRawNumericValues records the call and calls ReadRawNumericValuesFunc,
+// if set.
+func (f *Client) ReadRawNumericValues(start, end time.Time,
+	uuid, metric string, nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.RawNumericValue, error) {
+	f.record("ReadRawNumericValues", start, end, uuid, metric)
+
+	if f.ReadRawNumericValuesFunc != nil {
+		return f.ReadRawNumericValuesFunc(context.Background(), start, end,
+			uuid, metric, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadRawNumericValuesContext records the call and calls
+// ReadRawNumericValuesFunc, if set.
+func (f *Client) ReadRawNumericValuesContext(ctx context.Context,
+	start, end time.Time, uuid, metric string, opts ...gosnowth.CallOption,
+) ([]gosnowth.RawNumericValue, error) {
+	f.record("ReadRawNumericValuesContext", start, end, uuid, metric)
+
+	if f.ReadRawNumericValuesFunc != nil {
+		return f.ReadRawNumericValuesFunc(ctx, start, end, uuid, metric,
+			opts...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadRollupValues records the call and calls ReadRollupValuesFunc, if set.
+func (f *Client) ReadRollupValues(uuid, metric string, period time.Duration,
+	start, end time.Time, dataType string, nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.RollupValue, error) {
+	f.record("ReadRollupValues", uuid, metric, period, start, end, dataType)
+
+	if f.ReadRollupValuesFunc != nil {
+		return f.ReadRollupValuesFunc(context.Background(), uuid, metric,
+			period, start, end, dataType, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadRollupValuesContext records the call and calls ReadRollupValuesFunc,
+// if set.
+func (f *Client) ReadRollupValuesContext(ctx context.Context,
+	uuid, metric string, period time.Duration, start, end time.Time,
+	dataType string, opts ...gosnowth.CallOption,
+) ([]gosnowth.RollupValue, error) {
+	f.record("ReadRollupValuesContext", uuid, metric, period, start, end, dataType)
+
+	if f.ReadRollupValuesFunc != nil {
+		return f.ReadRollupValuesFunc(ctx, uuid, metric, period, start, end,
+			dataType, opts...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadRollupAllValues records the call and calls ReadRollupAllValuesFunc, if
+// set.
+func (f *Client) ReadRollupAllValues(uuid, metric string, period time.Duration,
+	start, end time.Time, nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.RollupAllValue, error) {
+	f.record("ReadRollupAllValues", uuid, metric, period, start, end)
+
+	if f.ReadRollupAllValuesFunc != nil {
+		return f.ReadRollupAllValuesFunc(context.Background(), uuid, metric,
+			period, start, end, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadRollupAllValuesContext records the call and calls
+// ReadRollupAllValuesFunc, if set.
+func (f *Client) ReadRollupAllValuesContext(ctx context.Context,
+	uuid, metric string, period time.Duration, start, end time.Time,
+	opts ...gosnowth.CallOption,
+) ([]gosnowth.RollupAllValue, error) {
+	f.record("ReadRollupAllValuesContext", uuid, metric, period, start, end)
+
+	if f.ReadRollupAllValuesFunc != nil {
+		return f.ReadRollupAllValuesFunc(ctx, uuid, metric, period, start, end,
+			opts...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadTextValues records the call and calls ReadTextValuesFunc, if set.
+func (f *Client) ReadTextValues(uuid, metric string, start, end time.Time,
+	nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.TextValue, error) {
+	f.record("ReadTextValues", uuid, metric, start, end)
+
+	if f.ReadTextValuesFunc != nil {
+		return f.ReadTextValuesFunc(context.Background(), uuid, metric, start,
+			end, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ReadTextValuesContext records the call and calls ReadTextValuesFunc, if
+// set.
+func (f *Client) ReadTextValuesContext(ctx context.Context,
+	uuid, metric string, start, end time.Time, opts ...gosnowth.CallOption,
+) ([]gosnowth.TextValue, error) {
+	f.record("ReadTextValuesContext", uuid, metric, start, end)
+
+	if f.ReadTextValuesFunc != nil {
+		return f.ReadTextValuesFunc(ctx, uuid, metric, start, end, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// GraphiteGetDatapoints records the call and calls
+// GraphiteGetDatapointsFunc, if set.
+func (f *Client) GraphiteGetDatapoints(accountID int64, prefix string,
+	lookup *gosnowth.GraphiteLookup, options *gosnowth.GraphiteOptions,
+	nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.GraphiteDatapoints, error) {
+	f.record("GraphiteGetDatapoints", accountID, prefix, lookup, options)
+
+	if f.GraphiteGetDatapointsFunc != nil {
+		return f.GraphiteGetDatapointsFunc(context.Background(), accountID,
+			prefix, lookup, options, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// GraphiteGetDatapointsContext records the call and calls
+// GraphiteGetDatapointsFunc, if set.
+func (f *Client) GraphiteGetDatapointsContext(ctx context.Context,
+	accountID int64, prefix string, lookup *gosnowth.GraphiteLookup,
+	options *gosnowth.GraphiteOptions, opts ...gosnowth.CallOption,
+) (*gosnowth.GraphiteDatapoints, error) {
+	f.record("GraphiteGetDatapointsContext", accountID, prefix, lookup, options)
+
+	if f.GraphiteGetDatapointsFunc != nil {
+		return f.GraphiteGetDatapointsFunc(ctx, accountID, prefix, lookup,
+			options, opts...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// WriteNumeric records the call and calls WriteNumericFunc, if set.
+func (f *Client) WriteNumeric(data []gosnowth.NumericWrite,
+	nodes ...*gosnowth.SnowthNode,
+) error {
+	f.record("WriteNumeric", data)
+
+	if f.WriteNumericFunc != nil {
+		return f.WriteNumericFunc(context.Background(), data,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return f.Err
+}
+
+// WriteNumericContext records the call and calls WriteNumericFunc, if set.
+func (f *Client) WriteNumericContext(ctx context.Context,
+	data []gosnowth.NumericWrite, opts ...gosnowth.CallOption,
+) error {
+	f.record("WriteNumericContext", data)
+
+	if f.WriteNumericFunc != nil {
+		return f.WriteNumericFunc(ctx, data, opts...)
+	}
+
+	return f.Err
+}
+
+// WriteNNT records the call and calls WriteNNTFunc, if set.
+func (f *Client) WriteNNT(data []gosnowth.NNTData,
+	nodes ...*gosnowth.SnowthNode,
+) error {
+	f.record("WriteNNT", data)
+
+	if f.WriteNNTFunc != nil {
+		return f.WriteNNTFunc(context.Background(), data,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return f.Err
+}
+
+// WriteNNTContext records the call and calls WriteNNTFunc, if set.
+func (f *Client) WriteNNTContext(ctx context.Context, data []gosnowth.NNTData,
+	opts ...gosnowth.CallOption,
+) error {
+	f.record("WriteNNTContext", data)
+
+	if f.WriteNNTFunc != nil {
+		return f.WriteNNTFunc(ctx, data, opts...)
+	}
+
+	return f.Err
+}
+
+// WriteText records the call and calls WriteTextFunc, if set.
+func (f *Client) WriteText(data []gosnowth.TextData,
+	nodes ...*gosnowth.SnowthNode,
+) error {
+	f.record("WriteText", data)
+
+	if f.WriteTextFunc != nil {
+		return f.WriteTextFunc(context.Background(), data,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return f.Err
+}
+
+// WriteTextContext records the call and calls WriteTextFunc, if set.
+func (f *Client) WriteTextContext(ctx context.Context,
+	data []gosnowth.TextData, opts ...gosnowth.CallOption,
+) error {
+	f.record("WriteTextContext", data)
+
+	if f.WriteTextFunc != nil {
+		return f.WriteTextFunc(ctx, data, opts...)
+	}
+
+	return f.Err
+}
+
+// WriteHistogram records the call and calls WriteHistogramFunc, if set.
+func (f *Client) WriteHistogram(data []gosnowth.HistogramData,
+	nodes ...*gosnowth.SnowthNode,
+) error {
+	f.record("WriteHistogram", data)
+
+	if f.WriteHistogramFunc != nil {
+		return f.WriteHistogramFunc(context.Background(), data,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return f.Err
+}
+
+// WriteHistogramContext records the call and calls WriteHistogramFunc, if
+// set.
+func (f *Client) WriteHistogramContext(ctx context.Context,
+	data []gosnowth.HistogramData, opts ...gosnowth.CallOption,
+) error {
+	f.record("WriteHistogramContext", data)
+
+	if f.WriteHistogramFunc != nil {
+		return f.WriteHistogramFunc(ctx, data, opts...)
+	}
+
+	return f.Err
+}
+
+// WriteRaw records the call and calls WriteRawFunc, if set.
+func (f *Client) WriteRaw(data io.Reader, fb bool, dataPoints uint64,
+	nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.IRONdbPutResponse, error) {
+	f.record("WriteRaw", data, fb, dataPoints)
+
+	if f.WriteRawFunc != nil {
+		return f.WriteRawFunc(context.Background(), data, fb, dataPoints,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// WriteRawContext records the call and calls WriteRawFunc, if set.
+func (f *Client) WriteRawContext(ctx context.Context, data io.Reader, fb bool,
+	dataPoints uint64, opts ...gosnowth.CallOption,
+) (*gosnowth.IRONdbPutResponse, error) {
+	f.record("WriteRawContext", data, fb, dataPoints)
+
+	if f.WriteRawFunc != nil {
+		return f.WriteRawFunc(ctx, data, fb, dataPoints, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// WriteRawMetricList records the call and calls WriteRawMetricListFunc, if
+// set.
+func (f *Client) WriteRawMetricList(metricList *noit.MetricListT,
+	builder *flatbuffers.Builder, nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.IRONdbPutResponse, error) {
+	f.record("WriteRawMetricList", metricList, builder)
+
+	if f.WriteRawMetricListFunc != nil {
+		return f.WriteRawMetricListFunc(context.Background(), metricList,
+			builder, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// WriteRawMetricListContext records the call and calls
+// WriteRawMetricListFunc, if set.
+func (f *Client) WriteRawMetricListContext(ctx context.Context,
+	metricList *noit.MetricListT, builder *flatbuffers.Builder,
+	opts ...gosnowth.CallOption,
+) (*gosnowth.IRONdbPutResponse, error) {
+	f.record("WriteRawMetricListContext", metricList, builder)
+
+	if f.WriteRawMetricListFunc != nil {
+		return f.WriteRawMetricListFunc(ctx, metricList, builder, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// WriteNNTBSFlatbuffer records the call and calls WriteNNTBSFlatbufferFunc,
+// if set.
+func (f *Client) WriteNNTBSFlatbuffer(merge *nntbs.NNTMergeT,
+	builder *flatbuffers.Builder, nodes ...*gosnowth.SnowthNode,
+) error {
+	f.record("WriteNNTBSFlatbuffer", merge, builder)
+
+	if f.WriteNNTBSFlatbufferFunc != nil {
+		return f.WriteNNTBSFlatbufferFunc(context.Background(), merge, builder,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return f.Err
+}
+
+// WriteNNTBSFlatbufferContext records the call and calls
+// WriteNNTBSFlatbufferFunc, if set.
+func (f *Client) WriteNNTBSFlatbufferContext(ctx context.Context,
+	merge *nntbs.NNTMergeT, builder *flatbuffers.Builder,
+	opts ...gosnowth.CallOption,
+) error {
+	f.record("WriteNNTBSFlatbufferContext", merge, builder)
+
+	if f.WriteNNTBSFlatbufferFunc != nil {
+		return f.WriteNNTBSFlatbufferFunc(ctx, merge, builder, opts...)
+	}
+
+	return f.Err
+}
+
+// RebuildActivity records the call and calls RebuildActivityFunc, if set.
+func (f *Client) RebuildActivity(node *gosnowth.SnowthNode,
+	rebuildRequest []gosnowth.RebuildActivityRequest,
+) (*gosnowth.IRONdbPutResponse, error) {
+	f.record("RebuildActivity", node, rebuildRequest)
+
+	if f.RebuildActivityFunc != nil {
+		return f.RebuildActivityFunc(context.Background(), node,
+			rebuildRequest,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// RebuildActivityContext records the call and calls RebuildActivityFunc, if
+// set.
+func (f *Client) RebuildActivityContext(ctx context.Context,
+	node *gosnowth.SnowthNode,
+	rebuildRequest []gosnowth.RebuildActivityRequest,
+	opts ...gosnowth.CallOption,
+) (*gosnowth.IRONdbPutResponse, error) {
+	f.record("RebuildActivityContext", node, rebuildRequest)
+
+	if f.RebuildActivityFunc != nil {
+		return f.RebuildActivityFunc(ctx, node, rebuildRequest, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// FindTags records the call and calls FindTagsFunc, if set.
+func (f *Client) FindTags(accountID int64, query string,
+	options *gosnowth.FindTagsOptions, nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.FindTagsResult, error) {
+	f.record("FindTags", accountID, query, options)
+
+	if f.FindTagsFunc != nil {
+		return f.FindTagsFunc(context.Background(), accountID, query, options,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// FindTagsContext records the call and calls FindTagsFunc, if set.
+func (f *Client) FindTagsContext(ctx context.Context, accountID int64,
+	query string, options *gosnowth.FindTagsOptions,
+	opts ...gosnowth.CallOption,
+) (*gosnowth.FindTagsResult, error) {
+	f.record("FindTagsContext", accountID, query, options)
+
+	if f.FindTagsFunc != nil {
+		return f.FindTagsFunc(ctx, accountID, query, options, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// FindTagCats records the call and calls FindTagCatsFunc, if set.
+func (f *Client) FindTagCats(accountID int64, query string,
+	nodes ...*gosnowth.SnowthNode,
+) ([]string, error) {
+	f.record("FindTagCats", accountID, query)
+
+	if f.FindTagCatsFunc != nil {
+		return f.FindTagCatsFunc(context.Background(), accountID, query,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// FindTagCatsContext records the call and calls FindTagCatsFunc, if set.
+func (f *Client) FindTagCatsContext(ctx context.Context, accountID int64,
+	query string, opts ...gosnowth.CallOption,
+) ([]string, error) {
+	f.record("FindTagCatsContext", accountID, query)
+
+	if f.FindTagCatsFunc != nil {
+		return f.FindTagCatsFunc(ctx, accountID, query, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// FindTagVals records the call and calls FindTagValsFunc, if set.
+func (f *Client) FindTagVals(accountID int64, query, category string,
+	nodes ...*gosnowth.SnowthNode,
+) ([]string, error) {
+	f.record("FindTagVals", accountID, query, category)
+
+	if f.FindTagValsFunc != nil {
+		return f.FindTagValsFunc(context.Background(), accountID, query,
+			category, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// FindTagValsContext records the call and calls FindTagValsFunc, if set.
+func (f *Client) FindTagValsContext(ctx context.Context, accountID int64,
+	query, category string, opts ...gosnowth.CallOption,
+) ([]string, error) {
+	f.record("FindTagValsContext", accountID, query, category)
+
+	if f.FindTagValsFunc != nil {
+		return f.FindTagValsFunc(ctx, accountID, query, category, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// GetCheckTags records the call and calls GetCheckTagsFunc, if set.
+func (f *Client) GetCheckTags(checkUUID string, nodes ...*gosnowth.SnowthNode,
+) (gosnowth.CheckTags, error) {
+	f.record("GetCheckTags", checkUUID)
+
+	if f.GetCheckTagsFunc != nil {
+		return f.GetCheckTagsFunc(context.Background(), checkUUID,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// GetCheckTagsContext records the call and calls GetCheckTagsFunc, if set.
+func (f *Client) GetCheckTagsContext(ctx context.Context, checkUUID string,
+	opts ...gosnowth.CallOption,
+) (gosnowth.CheckTags, error) {
+	f.record("GetCheckTagsContext", checkUUID)
+
+	if f.GetCheckTagsFunc != nil {
+		return f.GetCheckTagsFunc(ctx, checkUUID, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// UpdateCheckTags records the call and calls UpdateCheckTagsFunc, if set.
+func (f *Client) UpdateCheckTags(checkUUID string, tags []string,
+	nodes ...*gosnowth.SnowthNode,
+) (int64, error) {
+	f.record("UpdateCheckTags", checkUUID, tags)
+
+	if f.UpdateCheckTagsFunc != nil {
+		return f.UpdateCheckTagsFunc(context.Background(), checkUUID, tags,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return 0, f.Err
+}
+
+// UpdateCheckTagsContext records the call and calls UpdateCheckTagsFunc, if
+// set.
+func (f *Client) UpdateCheckTagsContext(ctx context.Context, checkUUID string,
+	tags []string, opts ...gosnowth.CallOption,
+) (int64, error) {
+	f.record("UpdateCheckTagsContext", checkUUID, tags)
+
+	if f.UpdateCheckTagsFunc != nil {
+		return f.UpdateCheckTagsFunc(ctx, checkUUID, tags, opts...)
+	}
+
+	return 0, f.Err
+}
+
+// DeleteCheckTags records the call and calls DeleteCheckTagsFunc, if set.
+func (f *Client) DeleteCheckTags(checkUUID string,
+	nodes ...*gosnowth.SnowthNode,
+) error {
+	f.record("DeleteCheckTags", checkUUID)
+
+	if f.DeleteCheckTagsFunc != nil {
+		return f.DeleteCheckTagsFunc(context.Background(), checkUUID,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return f.Err
+}
+
+// DeleteCheckTagsContext records the call and calls DeleteCheckTagsFunc, if
+// set.
+func (f *Client) DeleteCheckTagsContext(ctx context.Context, checkUUID string,
+	opts ...gosnowth.CallOption,
+) error {
+	f.record("DeleteCheckTagsContext", checkUUID)
+
+	if f.DeleteCheckTagsFunc != nil {
+		return f.DeleteCheckTagsFunc(ctx, checkUUID, opts...)
+	}
+
+	return f.Err
+}
+
+// GraphiteFindMetrics records the call and calls GraphiteFindMetricsFunc, if
+// set.
+func (f *Client) GraphiteFindMetrics(accountID int64, prefix, query string,
+	options *gosnowth.GraphiteOptions, nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.GraphiteMetric, error) {
+	f.record("GraphiteFindMetrics", accountID, prefix, query, options)
+
+	if f.GraphiteFindMetricsFunc != nil {
+		return f.GraphiteFindMetricsFunc(context.Background(), accountID,
+			prefix, query, options, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// GraphiteFindMetricsContext records the call and calls
+// GraphiteFindMetricsFunc, if set.
+func (f *Client) GraphiteFindMetricsContext(ctx context.Context,
+	accountID int64, prefix, query string, options *gosnowth.GraphiteOptions,
+	opts ...gosnowth.CallOption,
+) ([]gosnowth.GraphiteMetric, error) {
+	f.record("GraphiteFindMetricsContext", accountID, prefix, query, options)
+
+	if f.GraphiteFindMetricsFunc != nil {
+		return f.GraphiteFindMetricsFunc(ctx, accountID, prefix, query,
+			options, opts...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// GraphiteFindTags records the call and calls GraphiteFindTagsFunc, if set.
+func (f *Client) GraphiteFindTags(accountID int64, prefix, query string,
+	options *gosnowth.GraphiteOptions, nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.GraphiteMetric, error) {
+	f.record("GraphiteFindTags", accountID, prefix, query, options)
+
+	if f.GraphiteFindTagsFunc != nil {
+		return f.GraphiteFindTagsFunc(context.Background(), accountID, prefix,
+			query, options, nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// GraphiteFindTagsContext records the call and calls GraphiteFindTagsFunc,
+// if set.
+func (f *Client) GraphiteFindTagsContext(ctx context.Context, accountID int64,
+	prefix, query string, options *gosnowth.GraphiteOptions,
+	opts ...gosnowth.CallOption,
+) ([]gosnowth.GraphiteMetric, error) {
+	f.record("GraphiteFindTagsContext", accountID, prefix, query, options)
+
+	if f.GraphiteFindTagsFunc != nil {
+		return f.GraphiteFindTagsFunc(ctx, accountID, prefix, query, options,
+			opts...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// Topology records the call and calls TopologyFunc, if set.
+func (f *Client) Topology() (*gosnowth.Topology, error) {
+	f.record("Topology")
+
+	if f.TopologyFunc != nil {
+		return f.TopologyFunc()
+	}
+
+	return nil, f.Err
+}
+
+// TopologyCacheStatus records the call and calls TopologyCacheStatusFunc, if
+// set.
+func (f *Client) TopologyCacheStatus() gosnowth.TopologyCacheStatus {
+	f.record("TopologyCacheStatus")
+
+	if f.TopologyCacheStatusFunc != nil {
+		return f.TopologyCacheStatusFunc()
+	}
+
+	return gosnowth.TopologyCacheStatus{}
+}
+
+// FindMetricNodeIDs records the call and calls FindMetricNodeIDsFunc, if
+// set.
+func (f *Client) FindMetricNodeIDs(uuid, metric string) []string {
+	f.record("FindMetricNodeIDs", uuid, metric)
+
+	if f.FindMetricNodeIDsFunc != nil {
+		return f.FindMetricNodeIDsFunc(uuid, metric)
+	}
+
+	return nil
+}
+
+// GetTopologyInfo records the call and calls GetTopologyInfoFunc, if set.
+func (f *Client) GetTopologyInfo(nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.Topology, error) {
+	f.record("GetTopologyInfo")
+
+	if f.GetTopologyInfoFunc != nil {
+		return f.GetTopologyInfoFunc(context.Background(),
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// GetTopologyInfoContext records the call and calls GetTopologyInfoFunc, if
+// set.
+func (f *Client) GetTopologyInfoContext(ctx context.Context,
+	opts ...gosnowth.CallOption,
+) (*gosnowth.Topology, error) {
+	f.record("GetTopologyInfoContext")
+
+	if f.GetTopologyInfoFunc != nil {
+		return f.GetTopologyInfoFunc(ctx, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// LoadTopology records the call and calls LoadTopologyFunc, if set.
+func (f *Client) LoadTopology(hash string, t *gosnowth.Topology,
+	nodes ...*gosnowth.SnowthNode,
+) error {
+	f.record("LoadTopology", hash, t)
+
+	if f.LoadTopologyFunc != nil {
+		return f.LoadTopologyFunc(context.Background(), hash, t,
+			firstNode(nodes),
+		)
+	}
+
+	return f.Err
+}
+
+// LoadTopologyContext records the call and calls LoadTopologyFunc, if set.
+func (f *Client) LoadTopologyContext(ctx context.Context, hash string,
+	t *gosnowth.Topology, node *gosnowth.SnowthNode,
+	opts ...gosnowth.CallOption,
+) error {
+	f.record("LoadTopologyContext", hash, t, node)
+
+	if f.LoadTopologyFunc != nil {
+		return f.LoadTopologyFunc(ctx, hash, t, node, opts...)
+	}
+
+	return f.Err
+}
+
+// ActivateTopology records the call and calls ActivateTopologyFunc, if set.
+func (f *Client) ActivateTopology(hash string,
+	node *gosnowth.SnowthNode,
+) error {
+	f.record("ActivateTopology", hash, node)
+
+	if f.ActivateTopologyFunc != nil {
+		return f.ActivateTopologyFunc(context.Background(), hash, node)
+	}
+
+	return f.Err
+}
+
+// ActivateTopologyContext records the call and calls ActivateTopologyFunc,
+// if set.
+func (f *Client) ActivateTopologyContext(ctx context.Context, hash string,
+	node *gosnowth.SnowthNode, opts ...gosnowth.CallOption,
+) error {
+	f.record("ActivateTopologyContext", hash, node)
+
+	if f.ActivateTopologyFunc != nil {
+		return f.ActivateTopologyFunc(ctx, hash, node, opts...)
+	}
+
+	return f.Err
+}
+
+// LocateMetric records the call and calls LocateMetricFunc, if set.
+func (f *Client) LocateMetric(uuid, metric string,
+	nodes ...*gosnowth.SnowthNode,
+) ([]gosnowth.TopologyNode, error) {
+	f.record("LocateMetric", uuid, metric)
+
+	if f.LocateMetricFunc != nil {
+		return f.LocateMetricFunc(context.Background(), uuid, metric,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// LocateMetricContext records the call and calls LocateMetricFunc, if set.
+func (f *Client) LocateMetricContext(ctx context.Context, uuid, metric string,
+	opts ...gosnowth.CallOption,
+) ([]gosnowth.TopologyNode, error) {
+	f.record("LocateMetricContext", uuid, metric)
+
+	if f.LocateMetricFunc != nil {
+		return f.LocateMetricFunc(ctx, uuid, metric, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// LocateMetricRemote records the call and calls LocateMetricRemoteFunc, if
+// set.
+func (f *Client) LocateMetricRemote(uuid, metric string,
+	node *gosnowth.SnowthNode,
+) ([]gosnowth.TopologyNode, error) {
+	f.record("LocateMetricRemote", uuid, metric, node)
+
+	if f.LocateMetricRemoteFunc != nil {
+		return f.LocateMetricRemoteFunc(context.Background(), uuid, metric,
+			node,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// LocateMetricRemoteContext records the call and calls
+// LocateMetricRemoteFunc, if set.
+func (f *Client) LocateMetricRemoteContext(ctx context.Context,
+	uuid, metric string, node *gosnowth.SnowthNode,
+	opts ...gosnowth.CallOption,
+) ([]gosnowth.TopologyNode, error) {
+	f.record("LocateMetricRemoteContext", uuid, metric, node)
+
+	if f.LocateMetricRemoteFunc != nil {
+		return f.LocateMetricRemoteFunc(ctx, uuid, metric, node, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// GetStats records the call and calls GetStatsFunc, if set.
+func (f *Client) GetStats(nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.Stats, error) {
+	f.record("GetStats")
+
+	if f.GetStatsFunc != nil {
+		return f.GetStatsFunc(context.Background(), nodeOptions(nodes)...)
+	}
+
+	return nil, f.Err
+}
+
+// GetStatsContext records the call and calls GetStatsFunc, if set.
+func (f *Client) GetStatsContext(ctx context.Context,
+	opts ...gosnowth.CallOption,
+) (*gosnowth.Stats, error) {
+	f.record("GetStatsContext")
+
+	if f.GetStatsFunc != nil {
+		return f.GetStatsFunc(ctx, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// GetStatsNodeContext records the call and calls GetStatsNodeContextFunc, if
+// set.
+func (f *Client) GetStatsNodeContext(ctx context.Context,
+	node *gosnowth.SnowthNode, opts ...gosnowth.CallOption,
+) (*gosnowth.Stats, error) {
+	f.record("GetStatsNodeContext", node)
+
+	if f.GetStatsNodeContextFunc != nil {
+		return f.GetStatsNodeContextFunc(ctx, node, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// GetNodeState records the call and calls GetNodeStateFunc, if set.
+func (f *Client) GetNodeState(nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.NodeState, error) {
+	f.record("GetNodeState")
+
+	if f.GetNodeStateFunc != nil {
+		return f.GetNodeStateFunc(context.Background(), nodeOptions(nodes)...)
+	}
+
+	return nil, f.Err
+}
+
+// GetNodeStateContext records the call and calls GetNodeStateFunc, if set.
+func (f *Client) GetNodeStateContext(ctx context.Context,
+	opts ...gosnowth.CallOption,
+) (*gosnowth.NodeState, error) {
+	f.record("GetNodeStateContext")
+
+	if f.GetNodeStateFunc != nil {
+		return f.GetNodeStateFunc(ctx, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// GetGossipInfo records the call and calls GetGossipInfoFunc, if set.
+func (f *Client) GetGossipInfo(nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.Gossip, error) {
+	f.record("GetGossipInfo")
+
+	if f.GetGossipInfoFunc != nil {
+		return f.GetGossipInfoFunc(context.Background(), nodeOptions(nodes)...)
+	}
+
+	return nil, f.Err
+}
+
+// GetGossipInfoContext records the call and calls GetGossipInfoFunc, if set.
+func (f *Client) GetGossipInfoContext(ctx context.Context,
+	opts ...gosnowth.CallOption,
+) (*gosnowth.Gossip, error) {
+	f.record("GetGossipInfoContext")
+
+	if f.GetGossipInfoFunc != nil {
+		return f.GetGossipInfoFunc(ctx, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// ListActiveNodes records the call and calls ListActiveNodesFunc, if set.
+func (f *Client) ListActiveNodes() []*gosnowth.SnowthNode {
+	f.record("ListActiveNodes")
+
+	if f.ListActiveNodesFunc != nil {
+		return f.ListActiveNodesFunc()
+	}
+
+	return nil
+}
+
+// ListInactiveNodes records the call and calls ListInactiveNodesFunc, if
+// set.
+func (f *Client) ListInactiveNodes() []*gosnowth.SnowthNode {
+	f.record("ListInactiveNodes")
+
+	if f.ListInactiveNodesFunc != nil {
+		return f.ListInactiveNodesFunc()
+	}
+
+	return nil
+}
+
+// GetActiveNode records the call and calls GetActiveNodeFunc, if set.
+func (f *Client) GetActiveNode(idsets ...[]string) *gosnowth.SnowthNode {
+	f.record("GetActiveNode", idsets)
+
+	if f.GetActiveNodeFunc != nil {
+		return f.GetActiveNodeFunc(idsets...)
+	}
+
+	return nil
+}
+
+// AddNodes records the call and calls AddNodesFunc, if set.
+func (f *Client) AddNodes(nodes ...*gosnowth.SnowthNode) {
+	f.record("AddNodes", nodes)
+
+	if f.AddNodesFunc != nil {
+		f.AddNodesFunc(nodes...)
+	}
+}
+
+// ActivateNodes records the call and calls ActivateNodesFunc, if set.
+func (f *Client) ActivateNodes(nodes ...*gosnowth.SnowthNode) {
+	f.record("ActivateNodes", nodes)
+
+	if f.ActivateNodesFunc != nil {
+		f.ActivateNodesFunc(nodes...)
+	}
+}
+
+// DeactivateNodes records the call and calls DeactivateNodesFunc, if set.
+func (f *Client) DeactivateNodes(nodes ...*gosnowth.SnowthNode) {
+	f.record("DeactivateNodes", nodes)
+
+	if f.DeactivateNodesFunc != nil {
+		f.DeactivateNodesFunc(nodes...)
+	}
+}
+
+// PromQLInstantQuery records the call and calls PromQLInstantQueryFunc, if
+// set.
+func (f *Client) PromQLInstantQuery(query *gosnowth.PromQLInstantQuery,
+	nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLInstantQuery", query)
+
+	if f.PromQLInstantQueryFunc != nil {
+		return f.PromQLInstantQueryFunc(context.Background(), query,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// PromQLInstantQueryContext records the call and calls
+// PromQLInstantQueryFunc, if set.
+func (f *Client) PromQLInstantQueryContext(ctx context.Context,
+	query *gosnowth.PromQLInstantQuery, opts ...gosnowth.CallOption,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLInstantQueryContext", query)
+
+	if f.PromQLInstantQueryFunc != nil {
+		return f.PromQLInstantQueryFunc(ctx, query, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// PromQLRangeQuery records the call and calls PromQLRangeQueryFunc, if set.
+func (f *Client) PromQLRangeQuery(query *gosnowth.PromQLRangeQuery,
+	nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLRangeQuery", query)
+
+	if f.PromQLRangeQueryFunc != nil {
+		return f.PromQLRangeQueryFunc(context.Background(), query,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// PromQLRangeQueryContext records the call and calls PromQLRangeQueryFunc,
+// if set.
+func (f *Client) PromQLRangeQueryContext(ctx context.Context,
+	query *gosnowth.PromQLRangeQuery, opts ...gosnowth.CallOption,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLRangeQueryContext", query)
+
+	if f.PromQLRangeQueryFunc != nil {
+		return f.PromQLRangeQueryFunc(ctx, query, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// PromQLSeriesQuery records the call and calls PromQLSeriesQueryFunc, if
+// set.
+func (f *Client) PromQLSeriesQuery(query *gosnowth.PromQLSeriesQuery,
+	nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLSeriesQuery", query)
+
+	if f.PromQLSeriesQueryFunc != nil {
+		return f.PromQLSeriesQueryFunc(context.Background(), query,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// PromQLSeriesQueryContext records the call and calls PromQLSeriesQueryFunc,
+// if set.
+func (f *Client) PromQLSeriesQueryContext(ctx context.Context,
+	query *gosnowth.PromQLSeriesQuery, opts ...gosnowth.CallOption,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLSeriesQueryContext", query)
+
+	if f.PromQLSeriesQueryFunc != nil {
+		return f.PromQLSeriesQueryFunc(ctx, query, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// PromQLLabelQuery records the call and calls PromQLLabelQueryFunc, if set.
+func (f *Client) PromQLLabelQuery(query *gosnowth.PromQLLabelQuery,
+	nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLLabelQuery", query)
+
+	if f.PromQLLabelQueryFunc != nil {
+		return f.PromQLLabelQueryFunc(context.Background(), query,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// PromQLLabelQueryContext records the call and calls PromQLLabelQueryFunc,
+// if set.
+func (f *Client) PromQLLabelQueryContext(ctx context.Context,
+	query *gosnowth.PromQLLabelQuery, opts ...gosnowth.CallOption,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLLabelQueryContext", query)
+
+	if f.PromQLLabelQueryFunc != nil {
+		return f.PromQLLabelQueryFunc(ctx, query, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// PromQLLabelValuesQuery records the call and calls
+// PromQLLabelValuesQueryFunc, if set.
+func (f *Client) PromQLLabelValuesQuery(label string,
+	query *gosnowth.PromQLLabelQuery, nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLLabelValuesQuery", label, query)
+
+	if f.PromQLLabelValuesQueryFunc != nil {
+		return f.PromQLLabelValuesQueryFunc(context.Background(), label, query,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// PromQLLabelValuesQueryContext records the call and calls
+// PromQLLabelValuesQueryFunc, if set.
+func (f *Client) PromQLLabelValuesQueryContext(ctx context.Context,
+	label string, query *gosnowth.PromQLLabelQuery,
+	opts ...gosnowth.CallOption,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLLabelValuesQueryContext", label, query)
+
+	if f.PromQLLabelValuesQueryFunc != nil {
+		return f.PromQLLabelValuesQueryFunc(ctx, label, query, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// PromQLMetadataQuery records the call and calls PromQLMetadataQueryFunc, if
+// set.
+func (f *Client) PromQLMetadataQuery(query *gosnowth.PromQLMetadataQuery,
+	nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLMetadataQuery", query)
+
+	if f.PromQLMetadataQueryFunc != nil {
+		return f.PromQLMetadataQueryFunc(context.Background(), query,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// PromQLMetadataQueryContext records the call and calls
+// PromQLMetadataQueryFunc, if set.
+func (f *Client) PromQLMetadataQueryContext(ctx context.Context,
+	query *gosnowth.PromQLMetadataQuery, opts ...gosnowth.CallOption,
+) (*gosnowth.PromQLResponse, error) {
+	f.record("PromQLMetadataQueryContext", query)
+
+	if f.PromQLMetadataQueryFunc != nil {
+		return f.PromQLMetadataQueryFunc(ctx, query, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// GetCAQLQuery records the call and calls GetCAQLQueryFunc, if set.
+func (f *Client) GetCAQLQuery(q *gosnowth.CAQLQuery,
+	nodes ...*gosnowth.SnowthNode,
+) (*gosnowth.DF4Response, error) {
+	f.record("GetCAQLQuery", q)
+
+	if f.GetCAQLQueryFunc != nil {
+		return f.GetCAQLQueryFunc(context.Background(), q,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// GetCAQLQueryContext records the call and calls GetCAQLQueryFunc, if set.
+func (f *Client) GetCAQLQueryContext(ctx context.Context,
+	q *gosnowth.CAQLQuery, opts ...gosnowth.CallOption,
+) (*gosnowth.DF4Response, error) {
+	f.record("GetCAQLQueryContext", q)
+
+	if f.GetCAQLQueryFunc != nil {
+		return f.GetCAQLQueryFunc(ctx, q, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// GetLuaExtensions records the call and calls GetLuaExtensionsFunc, if set.
+func (f *Client) GetLuaExtensions(nodes ...*gosnowth.SnowthNode,
+) (gosnowth.LuaExtensions, error) {
+	f.record("GetLuaExtensions")
+
+	if f.GetLuaExtensionsFunc != nil {
+		return f.GetLuaExtensionsFunc(context.Background(),
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// GetLuaExtensionsContext records the call and calls GetLuaExtensionsFunc,
+// if set.
+func (f *Client) GetLuaExtensionsContext(ctx context.Context,
+	opts ...gosnowth.CallOption,
+) (gosnowth.LuaExtensions, error) {
+	f.record("GetLuaExtensionsContext")
+
+	if f.GetLuaExtensionsFunc != nil {
+		return f.GetLuaExtensionsFunc(ctx, opts...)
+	}
+
+	return nil, f.Err
+}
+
+// ExecLuaExtension records the call and calls ExecLuaExtensionFunc, if set.
+func (f *Client) ExecLuaExtension(name string, params []gosnowth.ExtParam,
+	nodes ...*gosnowth.SnowthNode,
+) (map[string]interface{}, error) {
+	f.record("ExecLuaExtension", name, params)
+
+	if f.ExecLuaExtensionFunc != nil {
+		return f.ExecLuaExtensionFunc(context.Background(), name, params,
+			nodeOptions(nodes)...,
+		)
+	}
+
+	return nil, f.Err
+}
+
+// ExecLuaExtensionContext records the call and calls ExecLuaExtensionFunc,
+// if set.
+func (f *Client) ExecLuaExtensionContext(ctx context.Context, name string,
+	params []gosnowth.ExtParam, opts ...gosnowth.CallOption,
+) (map[string]interface{}, error) {
+	f.record("ExecLuaExtensionContext", name, params)
+
+	if f.ExecLuaExtensionFunc != nil {
+		return f.ExecLuaExtensionFunc(ctx, name, params, opts...)
+	}
+
+	return nil, f.Err
+}
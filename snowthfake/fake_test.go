@@ -0,0 +1,115 @@
+package snowthfake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/gosnowth"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	f := New()
+
+	var c gosnowth.Client = f
+
+	f.FetchValuesFunc = func(ctx context.Context, q *gosnowth.FetchQuery,
+		opts ...gosnowth.CallOption,
+	) (*gosnowth.DF4Response, error) {
+		return &gosnowth.DF4Response{
+			Head: gosnowth.DF4Head{Count: q.Count},
+		}, nil
+	}
+
+	f.FindTagsFunc = func(ctx context.Context, accountID int64, query string,
+		options *gosnowth.FindTagsOptions, opts ...gosnowth.CallOption,
+	) (*gosnowth.FindTagsResult, error) {
+		return &gosnowth.FindTagsResult{
+			Items: []gosnowth.FindTagsItem{{MetricName: query}},
+		}, nil
+	}
+
+	var written []byte
+
+	f.WriteRawFunc = func(ctx context.Context, data io.Reader, fb bool,
+		dataPoints uint64, opts ...gosnowth.CallOption,
+	) (*gosnowth.IRONdbPutResponse, error) {
+		b, err := io.ReadAll(data)
+		written = b
+
+		return &gosnowth.IRONdbPutResponse{
+			Records: dataPoints,
+		}, err
+	}
+
+	res, err := c.FetchValues(&gosnowth.FetchQuery{
+		Start:  time.Unix(0, 0),
+		Period: time.Minute,
+		Count:  5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Head.Count != 5 {
+		t.Errorf("Expected count: 5, got: %v", res.Head.Count)
+	}
+
+	node := &gosnowth.SnowthNode{}
+
+	ftr, err := c.FindTagsContext(context.Background(), 1, "test",
+		nil, gosnowth.WithNode(node))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ftr.Items) != 1 || ftr.Items[0].MetricName != "test" {
+		t.Errorf("Expected metric: test, got: %+v", ftr.Items)
+	}
+
+	pr, err := c.WriteRaw(bytes.NewBufferString("data"), true, 1, node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pr.Records != 1 || string(written) != "data" {
+		t.Errorf("Expected records: 1 with data, got: %v, %v", pr.Records,
+			string(written))
+	}
+
+	if n := f.CallCount("FindTagsContext"); n != 1 {
+		t.Errorf("Expected calls: 1, got: %v", n)
+	}
+
+	calls := f.CallsTo("FindTagsContext")
+	if len(calls) != 1 || calls[0].Args[0] != int64(1) ||
+		calls[0].Args[1] != "test" {
+		t.Errorf("Expected FindTagsContext arguments, got: %+v", calls)
+	}
+
+	if n := len(f.Calls()); n != 3 {
+		t.Errorf("Expected calls: 3, got: %v", n)
+	}
+
+	f.Reset()
+
+	if n := len(f.Calls()); n != 0 {
+		t.Errorf("Expected calls: 0, got: %v", n)
+	}
+
+	f.Err = errors.New("test error")
+
+	if _, err := c.GetCAQLQuery(&gosnowth.CAQLQuery{}); !errors.Is(err,
+		f.Err) {
+		t.Errorf("Expected error: %v, got: %v", f.Err, err)
+	}
+
+	if ids := c.FindMetricNodeIDs("uuid", "metric"); ids != nil {
+		t.Errorf("Expected no node IDs, got: %v", ids)
+	}
+}
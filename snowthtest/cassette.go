@@ -0,0 +1,350 @@
+package snowthtest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// CassetteVersion is the version of the cassette file format written by
+// Recorder values.
+const CassetteVersion = 1
+
+// Redacted is the value which replaces sanitized header values in cassettes.
+const Redacted = "REDACTED"
+
+// DefaultSanitizeHeaders contains the names of the headers whose values are
+// redacted by Recorder values by default.
+var DefaultSanitizeHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+	"X-Circonus-Auth-Token",
+}
+
+// Cassette values contain recorded HTTP request and response pairs.
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction values contain one recorded HTTP request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest values contain a recorded HTTP request. Requests are
+// matched on replay by method, path, query and body hash. The body itself is
+// recorded for readability only.
+type RecordedRequest struct {
+	Method   string      `json:"method"`
+	Path     string      `json:"path"`
+	Query    string      `json:"query,omitempty"`
+	Header   http.Header `json:"header,omitempty"`
+	BodyHash string      `json:"body_hash"`
+	Body     Body        `json:"body,omitempty"`
+}
+
+// RecordedResponse values contain a recorded HTTP response.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body values contain recorded HTTP bodies. Bodies which are valid UTF-8 are
+// stored in cassette files as strings, so that they can be edited by hand.
+// Other bodies, such as flatbuffers, are stored as {"base64": "..."} objects.
+type Body []byte
+
+// MarshalJSON encodes a body as a string or a base64 object.
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+
+	return json.Marshal(struct {
+		Base64 string `json:"base64"`
+	}{Base64: base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON decodes a body from a string or a base64 object.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+
+		return nil
+	}
+
+	v := struct {
+		Base64 string `json:"base64"`
+	}{}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("invalid cassette body: %w", err)
+	}
+
+	d, err := base64.StdEncoding.DecodeString(v.Base64)
+	if err != nil {
+		return fmt.Errorf("invalid cassette body: %w", err)
+	}
+
+	*b = d
+
+	return nil
+}
+
+// LoadCassette reads a cassette from a file.
+func LoadCassette(name string) (*Cassette, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cassette: %w", err)
+	}
+
+	c := &Cassette{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("unable to decode cassette: %w", err)
+	}
+
+	if c.Version != CassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version: %d", c.Version)
+	}
+
+	return c, nil
+}
+
+// Save writes the cassette to a file.
+func (c *Cassette) Save(name string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode cassette: %w", err)
+	}
+
+	if err := os.WriteFile(name, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("unable to write cassette: %w", err)
+	}
+
+	return nil
+}
+
+// bodyHash returns the hex encoded SHA-256 hash of a body.
+func bodyHash(b []byte) string {
+	h := sha256.Sum256(b)
+
+	return hex.EncodeToString(h[:])
+}
+
+// requestKey returns the key used to match a request on replay.
+func requestKey(method, path, query, hash string) string {
+	return method + " " + path + "?" + query + " " + hash
+}
+
+// readBody reads and replaces a request or response body, so that it can be
+// read again.
+func readBody(rc *io.ReadCloser) ([]byte, error) {
+	if *rc == nil || *rc == http.NoBody {
+		return nil, nil
+	}
+
+	b, err := io.ReadAll(*rc)
+	_ = (*rc).Close()
+
+	*rc = io.NopCloser(bytes.NewReader(b))
+
+	return b, err
+}
+
+// Recorder values are HTTP transports which send requests using another
+// transport, and record the requests and their responses into a cassette.
+// A Recorder can be used as the Transport of a gosnowth.Config, to capture
+// the traffic between the client and a real IRONdb cluster.
+type Recorder struct {
+	sync.Mutex
+	transport http.RoundTripper
+	sanitize  []string
+	cassette  *Cassette
+}
+
+// NewRecorder creates a new recorder which sends requests using a transport.
+// If the transport is nil, http.DefaultTransport is used. The values of the
+// DefaultSanitizeHeaders, and of any additional headers named, are redacted in
+// the recorded requests and responses.
+func NewRecorder(transport http.RoundTripper, sanitize ...string) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Recorder{
+		transport: transport,
+		sanitize: append(append([]string{}, DefaultSanitizeHeaders...),
+			sanitize...),
+		cassette: &Cassette{Version: CassetteVersion},
+	}
+}
+
+// RoundTrip sends a request and records it and its response.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read request body: %w", err)
+	}
+
+	res, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := readBody(&res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response body: %w", err)
+	}
+
+	in := Interaction{
+		Request: RecordedRequest{
+			Method:   req.Method,
+			Path:     req.URL.Path,
+			Query:    req.URL.Query().Encode(),
+			Header:   r.sanitizeHeader(req.Header),
+			BodyHash: bodyHash(reqBody),
+			Body:     reqBody,
+		},
+		Response: RecordedResponse{
+			StatusCode: res.StatusCode,
+			Header:     r.sanitizeHeader(res.Header),
+			Body:       resBody,
+		},
+	}
+
+	r.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	r.Unlock()
+
+	return res, nil
+}
+
+// sanitizeHeader returns a copy of a header with sensitive values redacted.
+func (r *Recorder) sanitizeHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+
+	c := h.Clone()
+
+	for _, k := range r.sanitize {
+		if v, ok := c[http.CanonicalHeaderKey(k)]; ok {
+			for i := range v {
+				v[i] = Redacted
+			}
+		}
+	}
+
+	return c
+}
+
+// Cassette returns a copy of the cassette containing the interactions recorded
+// so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.Lock()
+	defer r.Unlock()
+
+	return &Cassette{
+		Version: r.cassette.Version,
+		Interactions: append([]Interaction{},
+			r.cassette.Interactions...),
+	}
+}
+
+// Save writes the interactions recorded so far to a cassette file.
+func (r *Recorder) Save(name string) error {
+	return r.Cassette().Save(name)
+}
+
+// Replayer values are HTTP transports which serve the responses recorded in a
+// cassette, without sending any requests. Requests are matched to recorded
+// interactions by method, path, query and body hash. Repeated matching
+// requests are served the matching interactions in recorded order, and the
+// last matching interaction once all have been served.
+type Replayer struct {
+	sync.Mutex
+	interactions map[string][]Interaction
+	served       map[string]int
+}
+
+// NewReplayer creates a new replayer serving the interactions in a cassette.
+func NewReplayer(c *Cassette) *Replayer {
+	r := &Replayer{
+		interactions: map[string][]Interaction{},
+		served:       map[string]int{},
+	}
+
+	for _, in := range c.Interactions {
+		k := requestKey(in.Request.Method, in.Request.Path, in.Request.Query,
+			in.Request.BodyHash)
+		r.interactions[k] = append(r.interactions[k], in)
+	}
+
+	return r
+}
+
+// RoundTrip returns the recorded response matching a request. An error is
+// returned if no recorded interaction matches the request.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	b, err := readBody(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read request body: %w", err)
+	}
+
+	k := requestKey(req.Method, req.URL.Path, req.URL.Query().Encode(),
+		bodyHash(b))
+
+	r.Lock()
+
+	ins := r.interactions[k]
+	if len(ins) == 0 {
+		r.Unlock()
+
+		return nil, fmt.Errorf("no recorded interaction for request: %s %s",
+			req.Method, req.URL.String())
+	}
+
+	i := r.served[k]
+	if i >= len(ins) {
+		i = len(ins) - 1
+	} else {
+		r.served[k]++
+	}
+
+	in := ins[i]
+
+	r.Unlock()
+
+	h := in.Response.Header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+
+	return &http.Response{
+		Status: strconv.Itoa(in.Response.StatusCode) + " " +
+			http.StatusText(in.Response.StatusCode),
+		StatusCode:    in.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(in.Response.Body)),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+	}, nil
+}
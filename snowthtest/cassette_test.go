@@ -0,0 +1,130 @@
+package snowthtest
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/gosnowth"
+	"github.com/circonus-labs/gosnowth/fb/noit"
+)
+
+func TestCassette(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewCluster(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := NewRecorder(nil)
+	cfg := gosnowth.NewConfig(c.URLs()...)
+	cfg.Transport = rec
+
+	sc, err := gosnowth.NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	ml := &noit.MetricListT{Metrics: []*noit.MetricT{{
+		Timestamp: 60000,
+		CheckUuid: testUUID,
+		AccountId: 1,
+		Value: &noit.MetricValueT{
+			Name:      "raw",
+			Timestamp: 60000,
+			Value: &noit.MetricValueUnionT{
+				Type:  noit.MetricValueUnionDoubleValue,
+				Value: &noit.DoubleValueT{Value: 1.5},
+			},
+		},
+	}}}
+
+	if _, err := sc.WriteRawMetricListContext(context.Background(), ml, nil,
+		gosnowth.WithHeader("Authorization", "secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	vals, err := sc.ReadRawNumericValues(time.Unix(0, 0), time.Unix(120, 0),
+		testUUID, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = sc.Close(context.Background())
+
+	c.Close()
+
+	name := filepath.Join(t.TempDir(), "cassette.json")
+	if err := rec.Save(name); err != nil {
+		t.Fatal(err)
+	}
+
+	cas, err := LoadCassette(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+
+	for _, in := range cas.Interactions {
+		if in.Request.Path != "/raw" || in.Request.Method != http.MethodPost {
+			continue
+		}
+
+		found = true
+
+		if v := in.Request.Header.Get("Authorization"); v != Redacted {
+			t.Errorf("Expected header: %v, got: %v", Redacted, v)
+		}
+
+		if len(in.Request.Body) == 0 {
+			t.Error("Expected recorded flatbuffer request body")
+		}
+	}
+
+	if !found {
+		t.Fatal("Expected recorded raw write")
+	}
+
+	// The cluster is closed, so all responses are served from the cassette.
+	cfg = gosnowth.NewConfig(c.URLs()...)
+	cfg.Transport = NewReplayer(cas)
+
+	sc, err = gosnowth.NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create replay snowth client", err)
+	}
+
+	defer func() {
+		_ = sc.Close(context.Background())
+	}()
+
+	res, err := sc.WriteRawMetricList(ml, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Records != 1 {
+		t.Errorf("Expected records: 1, got: %v", res.Records)
+	}
+
+	rv, err := sc.ReadRawNumericValues(time.Unix(0, 0), time.Unix(120, 0),
+		testUUID, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rv) != len(vals) || len(rv) != 1 || rv[0].Value != vals[0].Value {
+		t.Errorf("Expected values: %+v, got: %+v", vals, rv)
+	}
+
+	// Requests with a different body are not matched.
+	ml.Metrics[0].Value.Value.Value = &noit.DoubleValueT{Value: 2.5}
+
+	if _, err := sc.WriteRawMetricList(ml, nil); err == nil {
+		t.Error("Expected error for unrecorded request")
+	}
+}
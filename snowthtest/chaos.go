@@ -0,0 +1,278 @@
+package snowthtest
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ChaosRule values describe the faults a Chaos transport injects into
+// matching requests. A rule matches requests sent to a node, to an endpoint,
+// or both. The faults of the first matching rule are applied.
+type ChaosRule struct {
+	// Node, if set, restricts the rule to requests sent to a node, given as a
+	// host:port address or a URL.
+	Node string
+
+	// Endpoint, if set, restricts the rule to requests for URL paths with this
+	// prefix, such as /raw or /read.
+	Endpoint string
+
+	// Method, if set, restricts the rule to requests using this HTTP method.
+	Method string
+
+	// Probability is the chance, from 0 to 1, that the rule is applied to a
+	// matching request. A zero value applies the rule to every request.
+	Probability float64
+
+	// Limit, if greater than zero, is the number of times the rule is applied
+	// before it stops matching requests.
+	Limit int
+
+	// Refuse causes the request to fail with a connection refused error,
+	// without being sent.
+	Refuse bool
+
+	// Latency is added before the request is sent, with up to Jitter more.
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// StatusCode, if set, is returned in place of the node response, without
+	// the request being sent.
+	StatusCode int
+
+	// Truncate causes the response body to end early, at a random offset,
+	// with an io.ErrUnexpectedEOF error.
+	Truncate bool
+
+	// TopologyHash, if set, replaces the X-Topo-0 header of the response.
+	TopologyHash string
+}
+
+// matches returns whether the rule matches a request.
+func (r *ChaosRule) matches(req *http.Request) bool {
+	if r.Node != "" && chaosHost(r.Node) != req.URL.Host {
+		return false
+	}
+
+	if r.Endpoint != "" && !strings.HasPrefix(req.URL.Path, r.Endpoint) {
+		return false
+	}
+
+	return r.Method == "" || strings.EqualFold(r.Method, req.Method)
+}
+
+// chaosHost returns the host:port address of a node given as an address or
+// a URL.
+func chaosHost(node string) string {
+	if !strings.Contains(node, "://") {
+		return node
+	}
+
+	u, err := url.Parse(node)
+	if err != nil {
+		return node
+	}
+
+	return u.Host
+}
+
+// chaosRule values contain a rule and the number of times it was applied.
+type chaosRule struct {
+	ChaosRule
+	applied int
+}
+
+// Chaos values are HTTP transports which inject faults into requests sent
+// using another transport, according to a list of rules. A Chaos transport can
+// be used as the Transport of a gosnowth.Config, to test how the client
+// handles node failures. All random choices are made using a seeded source,
+// so that tests sending the same requests in the same order see the same
+// faults.
+type Chaos struct {
+	sync.Mutex
+	transport http.RoundTripper
+	rand      *rand.Rand
+	rules     []*chaosRule
+	injected  int
+}
+
+// NewChaos creates a new chaos transport which sends requests using a
+// transport, and makes random choices using a source with the given seed. If
+// the transport is nil, http.DefaultTransport is used.
+func NewChaos(transport http.RoundTripper, seed int64) *Chaos {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Chaos{
+		transport: transport,
+		rand:      rand.New(rand.NewSource(seed)), //nolint:gosec
+	}
+}
+
+// AddRule adds a rule to the transport. Rules are matched in the order they
+// were added.
+func (c *Chaos) AddRule(r ChaosRule) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.rules = append(c.rules, &chaosRule{ChaosRule: r})
+}
+
+// ClearRules removes all rules from the transport, so that requests are sent
+// unchanged.
+func (c *Chaos) ClearRules() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.rules = nil
+}
+
+// Injected returns the number of requests which faults were injected into.
+func (c *Chaos) Injected() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.injected
+}
+
+// rule returns the rule to apply to a request, if any, with the added latency
+// and truncation fraction chosen for the request.
+func (c *Chaos) rule(req *http.Request,
+) (*ChaosRule, time.Duration, float64) {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, r := range c.rules {
+		if !r.matches(req) || (r.Limit > 0 && r.applied >= r.Limit) {
+			continue
+		}
+
+		if r.Probability > 0 && c.rand.Float64() >= r.Probability {
+			continue
+		}
+
+		r.applied++
+		c.injected++
+
+		latency := r.Latency
+		if r.Jitter > 0 {
+			latency += time.Duration(c.rand.Int63n(int64(r.Jitter)))
+		}
+
+		rule := r.ChaosRule
+
+		return &rule, latency, c.rand.Float64()
+	}
+
+	return nil, 0, 0
+}
+
+// RoundTrip sends a request, injecting the faults of the first matching rule.
+func (c *Chaos) RoundTrip(req *http.Request) (*http.Response, error) {
+	r, latency, frac := c.rule(req)
+	if r == nil {
+		return c.transport.RoundTrip(req)
+	}
+
+	if latency > 0 {
+		t := time.NewTimer(latency)
+
+		select {
+		case <-req.Context().Done():
+			t.Stop()
+
+			return nil, req.Context().Err()
+		case <-t.C:
+		}
+	}
+
+	if r.Refuse {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+
+		return nil, &net.OpError{
+			Op:  "dial",
+			Net: "tcp",
+			Err: os.NewSyscallError("connect", syscall.ECONNREFUSED),
+		}
+	}
+
+	var res *http.Response
+
+	if r.StatusCode != 0 {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+
+		body := []byte("chaos: injected " + strconv.Itoa(r.StatusCode) +
+			" response\n")
+
+		res = &http.Response{
+			Status: strconv.Itoa(r.StatusCode) + " " +
+				http.StatusText(r.StatusCode),
+			StatusCode:    r.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"text/plain"}},
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}
+	} else {
+		var err error
+
+		if res, err = c.transport.RoundTrip(req); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.TopologyHash != "" {
+		if res.Header == nil {
+			res.Header = http.Header{}
+		}
+
+		res.Header.Set("X-Topo-0", r.TopologyHash)
+	}
+
+	if r.Truncate {
+		b, err := readBody(&res.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Body = io.NopCloser(&truncatedReader{
+			r: bytes.NewReader(b[:int(float64(len(b))*frac)]),
+		})
+	}
+
+	return res, nil
+}
+
+// truncatedReader values return an io.ErrUnexpectedEOF error in place of
+// io.EOF at the end of the data read.
+type truncatedReader struct {
+	r io.Reader
+}
+
+// Read reads data, returning io.ErrUnexpectedEOF at the end of the data.
+func (t *truncatedReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
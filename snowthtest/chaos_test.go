@@ -0,0 +1,179 @@
+package snowthtest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/gosnowth"
+)
+
+func TestChaos(t *testing.T) {
+	t.Parallel()
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		w.Header().Set("X-Topo-0", "abc")
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+
+	defer ms.Close()
+
+	get := func(c *Chaos, path string) (*http.Response, []byte, error) {
+		req, err := http.NewRequest(http.MethodGet, ms.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := c.RoundTrip(req)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+
+		return res, b, err
+	}
+
+	c := NewChaos(nil, 1)
+	c.AddRule(ChaosRule{Node: ms.URL, Endpoint: "/refuse", Refuse: true})
+	c.AddRule(ChaosRule{Endpoint: "/fail", StatusCode: 503, Limit: 1})
+	c.AddRule(ChaosRule{Endpoint: "/trunc", Truncate: true})
+	c.AddRule(ChaosRule{Endpoint: "/topo", TopologyHash: "def",
+		Latency: 10 * time.Millisecond})
+	c.AddRule(ChaosRule{Node: "127.0.0.2:1", Refuse: true})
+
+	if _, _, err := get(c, "/refuse"); !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("Expected error: %v, got: %v", syscall.ECONNREFUSED, err)
+	}
+
+	res, _, err := get(c, "/fail")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != 503 {
+		t.Errorf("Expected status: 503, got: %v", res.StatusCode)
+	}
+
+	// The /fail rule is limited to one request.
+	if res, _, err = get(c, "/fail"); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != 200 {
+		t.Errorf("Expected status: 200, got: %v", res.StatusCode)
+	}
+
+	_, b, err := get(c, "/trunc")
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected error: %v, got: %v", io.ErrUnexpectedEOF, err)
+	}
+
+	if len(b) >= 10 {
+		t.Errorf("Expected truncated body, got: %s", b)
+	}
+
+	start := time.Now()
+
+	if res, _, err = get(c, "/topo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if h := res.Header.Get("X-Topo-0"); h != "def" {
+		t.Errorf("Expected topology header: def, got: %v", h)
+	}
+
+	if d := time.Since(start); d < 10*time.Millisecond {
+		t.Errorf("Expected latency: 10ms, got: %v", d)
+	}
+
+	if _, b, err = get(c, "/other"); err != nil || string(b) != "0123456789" {
+		t.Errorf("Expected unchanged response, got: %s, %v", b, err)
+	}
+
+	if n := c.Injected(); n != 4 {
+		t.Errorf("Expected injected: 4, got: %v", n)
+	}
+
+	// Transports with the same seed inject the same faults.
+	faults := func() []bool {
+		c := NewChaos(nil, 42)
+		c.AddRule(ChaosRule{StatusCode: 500, Probability: 0.5})
+
+		r := []bool{}
+
+		for i := 0; i < 20; i++ {
+			res, _, err := get(c, "/")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r = append(r, res.StatusCode == 500)
+		}
+
+		return r
+	}
+
+	a, b2 := faults(), faults()
+
+	for i := range a {
+		if a[i] != b2[i] {
+			t.Fatalf("Expected same faults: %v, got: %v", a, b2)
+		}
+	}
+
+	c.ClearRules()
+
+	if _, _, err := get(c, "/refuse"); err != nil {
+		t.Errorf("Expected no error after clearing rules, got: %v", err)
+	}
+}
+
+func TestChaosClient(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewCluster(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	chaos := NewChaos(nil, 1)
+	cfg := gosnowth.NewConfig(c.URLs()...)
+	cfg.Transport = chaos
+
+	sc, err := gosnowth.NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	defer func() {
+		_ = sc.Close(context.Background())
+	}()
+
+	chaos.AddRule(ChaosRule{Node: c.URLs()[0], Endpoint: "/read",
+		StatusCode: http.StatusServiceUnavailable})
+
+	if _, err := sc.ReadNumericValuesContext(context.Background(),
+		time.Unix(0, 0), time.Unix(600, 0), 60, "average", testUUID, "test",
+		gosnowth.WithRetries(0)); err == nil {
+		t.Error("Expected error for injected 503 response")
+	}
+
+	chaos.ClearRules()
+
+	if _, err := sc.ReadNumericValues(time.Unix(0, 0), time.Unix(600, 0), 60,
+		"average", testUUID, "test"); err != nil {
+		t.Error(err)
+	}
+}
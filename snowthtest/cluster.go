@@ -0,0 +1,438 @@
+// Package snowthtest provides an in-memory IRONdb cluster, served by
+// httptest servers, which can be used to test code using the gosnowth client
+// end to end without a real IRONdb cluster.
+//
+// Each node of a Cluster serves the node status, gossip, topology and locate
+// endpoints, and the write and read endpoints for numeric, NNT, text,
+// histogram and raw data, and the find tags endpoint. Written data is stored
+// by the node receiving it and can be read from any running node. Nodes can
+// be killed and restarted, and the topology of the cluster can be changed,
+// to test node discovery, failover and write routing.
+package snowthtest
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/gosnowth"
+	"github.com/google/uuid"
+)
+
+// DefaultAccountID is the account ID used for the find tags index of data
+// written without an account ID, such as numeric, NNT and text data.
+const DefaultAccountID = 1
+
+// SemVer is the version reported by the nodes of a cluster.
+const SemVer = "1.0.0"
+
+// Node values represent the nodes of a cluster.
+type Node struct {
+	id      string
+	addr    string
+	cluster *Cluster
+	store   *store
+
+	mu       sync.Mutex
+	srv      *httptest.Server
+	killedAt time.Time
+	requests int64
+}
+
+// ID returns the identifier of the node.
+func (n *Node) ID() string {
+	return n.id
+}
+
+// URL returns the URL of the node.
+func (n *Node) URL() string {
+	return "http://" + n.addr
+}
+
+// Running returns whether the node is running.
+func (n *Node) Running() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.srv != nil
+}
+
+// Requests returns the number of requests received by the node.
+func (n *Node) Requests() int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.requests
+}
+
+// Kill stops the node. Connections to the node are refused until it is
+// restarted. The data written to the node is kept.
+func (n *Node) Kill() {
+	n.mu.Lock()
+	srv := n.srv
+	n.srv = nil
+	n.killedAt = time.Now()
+	n.mu.Unlock()
+
+	if srv != nil {
+		srv.CloseClientConnections()
+		srv.Close()
+	}
+}
+
+// Restart starts a killed node again, using the same address.
+func (n *Node) Restart() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.srv != nil {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("unable to restart node %s: %w", n.id, err)
+	}
+
+	n.srv = n.newServer(ln)
+	n.killedAt = time.Time{}
+
+	return nil
+}
+
+// newServer starts a server for the node using a listener.
+func (n *Node) newServer(ln net.Listener) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(n.serveHTTP))
+	_ = srv.Listener.Close()
+	srv.Listener = ln
+	srv.Start()
+
+	return srv
+}
+
+// gossipAge returns the gossip age of the node, as seen by the other nodes.
+func (n *Node) gossipAge() float64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.srv != nil {
+		return 0
+	}
+
+	return time.Since(n.killedAt).Seconds()
+}
+
+// close stops the node without recording it as killed.
+func (n *Node) close() {
+	n.mu.Lock()
+	srv := n.srv
+	n.srv = nil
+	n.mu.Unlock()
+
+	if srv != nil {
+		srv.Close()
+	}
+}
+
+// Cluster values are in-memory IRONdb clusters.
+type Cluster struct {
+	mu          sync.RWMutex
+	nodes       []*Node
+	current     *gosnowth.Topology
+	currentXML  string
+	next        *gosnowth.Topology
+	nextXML     string
+	topologies  map[string]string
+	writeCopies uint8
+}
+
+// NewCluster starts a cluster containing a number of nodes, all of which are
+// in the topology of the cluster. Data is written to up to two nodes.
+func NewCluster(nodes int) (*Cluster, error) {
+	if nodes < 1 {
+		return nil, errors.New("a cluster requires at least one node")
+	}
+
+	c := &Cluster{
+		topologies:  map[string]string{},
+		writeCopies: 2,
+	}
+
+	if nodes < 2 {
+		c.writeCopies = 1
+	}
+
+	for i := 0; i < nodes; i++ {
+		if _, err := c.AddNode(); err != nil {
+			c.Close()
+
+			return nil, err
+		}
+	}
+
+	if _, err := c.SetTopology(c.Nodes()...); err != nil {
+		c.Close()
+
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// AddNode starts a new node in the cluster. The node is not added to the
+// topology of the cluster until SetTopology() or SetNextTopology() is called.
+func (c *Cluster) AddNode() (*Node, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("unable to start node: %w", err)
+	}
+
+	n := &Node{
+		id:      uuid.New().String(),
+		addr:    ln.Addr().String(),
+		cluster: c,
+		store:   newStore(),
+	}
+
+	n.srv = n.newServer(ln)
+
+	c.mu.Lock()
+	c.nodes = append(c.nodes, n)
+	c.mu.Unlock()
+
+	return n, nil
+}
+
+// Close stops all of the nodes of the cluster.
+func (c *Cluster) Close() {
+	for _, n := range c.Nodes() {
+		n.close()
+	}
+}
+
+// Nodes returns the nodes of the cluster.
+func (c *Cluster) Nodes() []*Node {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return append([]*Node{}, c.nodes...)
+}
+
+// Node returns the node of the cluster with an identifier, or nil.
+func (c *Cluster) Node(id string) *Node {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, n := range c.nodes {
+		if n.id == id {
+			return n
+		}
+	}
+
+	return nil
+}
+
+// URLs returns the URLs of the nodes of the cluster, which can be used as the
+// servers of a gosnowth client configuration.
+func (c *Cluster) URLs() []string {
+	urls := []string{}
+
+	for _, n := range c.Nodes() {
+		urls = append(urls, n.URL())
+	}
+
+	return urls
+}
+
+// Topology returns the current topology of the cluster.
+func (c *Cluster) Topology() *gosnowth.Topology {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.current
+}
+
+// SetWriteCopies sets the number of nodes owning each metric in topologies
+// created after it is called.
+func (c *Cluster) SetWriteCopies(n uint8) {
+	c.mu.Lock()
+	c.writeCopies = n
+	c.mu.Unlock()
+}
+
+// SetTopology replaces the current topology of the cluster with a topology
+// containing the provided nodes, and clears any next topology. The hash of the
+// new topology is returned. Clients learn of the new topology from the
+// X-Topo-0 header of subsequent responses.
+func (c *Cluster) SetTopology(nodes ...*Node) (string, error) {
+	topo, s, err := c.newTopology(nodes)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.current, c.currentXML = topo, s
+	c.next, c.nextXML = nil, ""
+	c.topologies[topo.Hash] = s
+	c.mu.Unlock()
+
+	return topo.Hash, nil
+}
+
+// SetNextTopology sets the next topology of the cluster, to which the cluster
+// is being rebalanced, to a topology containing the provided nodes. The hash
+// of the new topology is returned. Calling SetNextTopology() with no nodes
+// clears the next topology.
+func (c *Cluster) SetNextTopology(nodes ...*Node) (string, error) {
+	if len(nodes) == 0 {
+		c.mu.Lock()
+		c.next, c.nextXML = nil, ""
+		c.mu.Unlock()
+
+		return "", nil
+	}
+
+	topo, s, err := c.newTopology(nodes)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.next, c.nextXML = topo, s
+	c.topologies[topo.Hash] = s
+	c.mu.Unlock()
+
+	return topo.Hash, nil
+}
+
+// ActivateNextTopology makes the next topology of the cluster the current
+// topology. The hash of the new current topology is returned.
+func (c *Cluster) ActivateNextTopology() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next == nil {
+		return "", errors.New("no next topology")
+	}
+
+	c.current, c.currentXML = c.next, c.nextXML
+	c.next, c.nextXML = nil, ""
+
+	return c.current.Hash, nil
+}
+
+// newTopology creates and compiles a topology containing nodes.
+func (c *Cluster) newTopology(nodes []*Node) (*gosnowth.Topology, string,
+	error,
+) {
+	if len(nodes) == 0 {
+		return nil, "", errors.New("a topology requires at least one node")
+	}
+
+	c.mu.RLock()
+	wc := c.writeCopies
+	c.mu.RUnlock()
+
+	if int(wc) > len(nodes) {
+		wc = uint8(len(nodes))
+	}
+
+	t := &gosnowth.Topology{OldWriteCopies: wc, WriteCopies: wc}
+
+	for _, n := range nodes {
+		host, ps, err := net.SplitHostPort(n.addr)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid node address: %w", err)
+		}
+
+		port, err := strconv.ParseUint(ps, 10, 16)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid node port: %w", err)
+		}
+
+		t.Nodes = append(t.Nodes, gosnowth.TopologyNode{
+			ID:      n.id,
+			Address: host,
+			Port:    uint16(port),
+			APIPort: uint16(port),
+			Weight:  32,
+		})
+	}
+
+	b, err := xml.Marshal(t)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to encode topology: %w", err)
+	}
+
+	topo, err := gosnowth.TopologyLoadXML(string(b))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return topo, string(b), nil
+}
+
+// topologyState returns the current and next topology hashes of the cluster.
+// The next topology hash is "-" if there is none.
+func (c *Cluster) topologyState() (string, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	current, next := "", "-"
+
+	if c.current != nil {
+		current = c.current.Hash
+	}
+
+	if c.next != nil {
+		next = c.next.Hash
+	}
+
+	return current, next
+}
+
+// owns returns whether a node owns a metric in the current topology, or in
+// the next topology, if there is one.
+func (c *Cluster) owns(n *Node, id, metric string) bool {
+	c.mu.RLock()
+	topos := []*gosnowth.Topology{c.current, c.next}
+	c.mu.RUnlock()
+
+	for _, topo := range topos {
+		if topo == nil {
+			continue
+		}
+
+		owners, err := topo.FindMetric(id, metric)
+		if err != nil {
+			continue
+		}
+
+		for _, o := range owners {
+			if o.ID == n.id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// runningNodes returns the nodes of the cluster which are running.
+func (c *Cluster) runningNodes() []*Node {
+	nodes := []*Node{}
+
+	for _, n := range c.Nodes() {
+		if n.Running() {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return nodes
+}
@@ -0,0 +1,315 @@
+package snowthtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/gosnowth"
+	"github.com/circonus-labs/gosnowth/fb/noit"
+	"github.com/openhistogram/circonusllhist"
+)
+
+const testUUID = "3aa57ac2-28de-4ec4-aa3d-ed0ddd48fa4d"
+
+// waitFor waits for a condition to become true.
+func waitFor(t *testing.T, msg string, f func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for: " + msg)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewCluster(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	cfg := gosnowth.NewConfig(c.URLs()[0])
+	cfg.WatchInterval = 50 * time.Millisecond
+	cfg.Timeout = time.Second
+
+	sc, err := gosnowth.NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	defer func() {
+		_ = sc.Close(context.Background())
+	}()
+
+	sc.WatchAndUpdate(context.Background())
+
+	waitFor(t, "node discovery", func() bool {
+		return len(sc.ListActiveNodes()) == 3
+	})
+
+	topo, err := sc.Topology()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if topo.Hash != c.Topology().Hash {
+		t.Errorf("Expected topology: %v, got: %v", c.Topology().Hash,
+			topo.Hash)
+	}
+
+	// Numeric writes are sent to an owner of the metric.
+	if err := sc.WriteNumeric([]gosnowth.NumericWrite{{
+		ID:     testUUID,
+		Metric: "test|ST[env:prod]",
+		Offset: 120,
+		Value:  42,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	owners := sc.FindMetricNodeIDs(testUUID, "test|ST[env:prod]")
+	found := 0
+
+	for _, n := range c.Nodes() {
+		if len(n.NumericWrites()) == 0 {
+			continue
+		}
+
+		found++
+
+		if n.ID() != owners[0] && n.ID() != owners[1] {
+			t.Errorf("Expected write to owners: %v, got: %v", owners, n.ID())
+		}
+	}
+
+	if found != 1 {
+		t.Errorf("Expected writes to 1 node, got: %v", found)
+	}
+
+	vals, err := sc.ReadNumericValues(time.Unix(0, 0), time.Unix(600, 0), 60,
+		"average", testUUID, "test|ST[env:prod]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vals) != 1 || vals[0].Value != 42 || vals[0].Time.Unix() != 120 {
+		t.Errorf("Expected value: 42 at 120, got: %+v", vals)
+	}
+
+	if err := sc.WriteText([]gosnowth.TextData{{
+		ID:     testUUID,
+		Metric: "version",
+		Offset: "180",
+		Value:  "1.0",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tv, err := sc.ReadTextValues(testUUID, "version", time.Unix(0, 0),
+		time.Unix(600, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tv) != 1 || tv[0].Value == nil || *tv[0].Value != "1.0" {
+		t.Errorf("Expected text value: 1.0, got: %+v", tv)
+	}
+
+	h := circonusllhist.New()
+	_ = h.RecordValue(1)
+	_ = h.RecordValue(1)
+
+	if err := sc.WriteHistogram([]gosnowth.HistogramData{{
+		ID:        testUUID,
+		Metric:    "latency",
+		Offset:    60,
+		Period:    60,
+		Histogram: h,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	hv, err := sc.ReadHistogramValues(testUUID, "latency", time.Minute,
+		time.Unix(0, 0), time.Unix(600, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hv) != 1 || hv[0].Data["1.0e+00"] != 2 {
+		t.Errorf("Expected histogram bin count: 2, got: %+v", hv)
+	}
+
+	res, err := sc.FindTags(DefaultAccountID, "and(env:prod)",
+		&gosnowth.FindTagsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Count != 1 || res.Items[0].MetricName != "test|ST[env:prod]" {
+		t.Errorf("Expected found metric: test|ST[env:prod], got: %+v",
+			res.Items)
+	}
+
+	// Killed nodes are deactivated, and reads fail over to the other owner.
+	killed := c.Node(owners[0])
+	if len(killed.NumericWrites()) > 0 {
+		killed = c.Node(owners[1])
+	}
+
+	killed.Kill()
+
+	waitFor(t, "node deactivation", func() bool {
+		return len(sc.ListActiveNodes()) == 2
+	})
+
+	vals, err = sc.ReadNumericValues(time.Unix(0, 0), time.Unix(600, 0), 60,
+		"average", testUUID, "test|ST[env:prod]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vals) != 1 || vals[0].Value != 42 {
+		t.Errorf("Expected value after failover: 42, got: %+v", vals)
+	}
+
+	if err := killed.Restart(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "node activation", func() bool {
+		return len(sc.ListActiveNodes()) == 3
+	})
+
+	// Topology changes are seen by the client.
+	hash, err := c.SetTopology(c.Nodes()[:2]...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "topology change", func() bool {
+		return sc.TopologyCacheStatus().Hash == hash
+	})
+}
+
+func TestClusterRaw(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewCluster(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	c.SetWriteCopies(1)
+
+	if _, err := c.SetTopology(c.Nodes()...); err != nil {
+		t.Fatal(err)
+	}
+
+	sc, err := gosnowth.NewClient(context.Background(),
+		gosnowth.NewConfig(c.URLs()...))
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	defer func() {
+		_ = sc.Close(context.Background())
+	}()
+
+	waitFor(t, "node activation", func() bool {
+		return len(sc.ListActiveNodes()) == 2
+	})
+
+	owners, err := c.Topology().FindMetric(testUUID, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ml := &noit.MetricListT{Metrics: []*noit.MetricT{{
+		Timestamp: 60000,
+		CheckUuid: testUUID,
+		AccountId: 1,
+		Value: &noit.MetricValueT{
+			Name:      "raw",
+			Timestamp: 60000,
+			Value: &noit.MetricValueUnionT{
+				Type:  noit.MetricValueUnionDoubleValue,
+				Value: &noit.DoubleValueT{Value: 1.5},
+			},
+		},
+	}}}
+
+	for _, n := range c.Nodes() {
+		res, err := sc.WriteRawMetricListContext(context.Background(), ml,
+			nil, nodeFor(t, sc, n), gosnowth.WithMisdirectRetries(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n.ID() == owners[0].ID {
+			if res.Records != 1 || res.Misdirected != 0 {
+				t.Errorf("Expected stored record, got: %+v", res)
+			}
+		} else if res.Records != 0 || res.Misdirected != 1 {
+			t.Errorf("Expected misdirected record, got: %+v", res)
+		}
+	}
+
+	// Misdirected records are re-sent to the owner by default.
+	for _, n := range c.Nodes() {
+		if n.ID() == owners[0].ID {
+			continue
+		}
+
+		res, err := sc.WriteRawMetricList(ml, nil, nodeFor(t, sc, n))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.Records != 1 || res.Misdirected != 0 || res.Rerouted != 1 {
+			t.Errorf("Expected re-routed record, got: %+v", res)
+		}
+	}
+
+	if p := c.Node(owners[0].ID).RawPoints(); len(p) != 2 {
+		t.Errorf("Expected owner raw points: 2, got: %v", len(p))
+	}
+
+	vals, err := sc.ReadRawNumericValues(time.Unix(0, 0), time.Unix(120, 0),
+		testUUID, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vals) != 1 || vals[0].Value != 1.5 || vals[0].Time.Unix() != 60 {
+		t.Errorf("Expected raw value: 1.5 at 60, got: %+v", vals)
+	}
+}
+
+// nodeFor returns the client node for a cluster node.
+func nodeFor(t *testing.T, sc *gosnowth.SnowthClient,
+	n *Node,
+) *gosnowth.SnowthNode {
+	t.Helper()
+
+	for _, sn := range append(sc.ListActiveNodes(),
+		sc.ListInactiveNodes()...) {
+		if sn.GetURL().String() == n.URL() {
+			return sn
+		}
+	}
+
+	t.Fatalf("Unable to find client node: %v", n.URL())
+
+	return nil
+}
@@ -0,0 +1,629 @@
+package snowthtest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/gosnowth"
+	"github.com/circonus-labs/gosnowth/fb/noit"
+)
+
+// serveHTTP handles the requests received by a node.
+func (n *Node) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	n.requests++
+	n.mu.Unlock()
+
+	current, _ := n.cluster.topologyState()
+	w.Header().Set("X-Topo-0", current)
+
+	p := r.URL.Path
+
+	switch {
+	case p == "/stats.json" && r.Method == http.MethodGet:
+		n.serveStats(w)
+	case p == "/state" && r.Method == http.MethodGet:
+		n.serveState(w)
+	case p == "/gossip/json" && r.Method == http.MethodGet:
+		n.serveGossip(w)
+	case strings.HasPrefix(p, "/topology/xml/") && r.Method == http.MethodGet:
+		n.serveTopology(w, strings.TrimPrefix(p, "/topology/xml/"))
+	case strings.HasPrefix(p, "/locate/xml/") && r.Method == http.MethodGet:
+		n.serveLocate(w, strings.TrimPrefix(p, "/locate/xml/"))
+	case p == "/write/numeric" && r.Method == http.MethodPost:
+		n.serveWriteNumeric(w, r)
+	case p == "/write/nnt" && r.Method == http.MethodPost:
+		n.serveWriteNNT(w, r)
+	case p == "/write/text" && r.Method == http.MethodPost:
+		n.serveWriteText(w, r)
+	case p == "/histogram/write" && r.Method == http.MethodPost:
+		n.serveWriteHistogram(w, r)
+	case p == "/raw" && r.Method == http.MethodPost:
+		n.serveWriteRaw(w, r)
+	case strings.HasPrefix(p, "/read/") && r.Method == http.MethodGet:
+		n.serveRead(w, strings.TrimPrefix(p, "/read/"))
+	case strings.HasPrefix(p, "/histogram/") && r.Method == http.MethodGet:
+		n.serveReadHistogram(w, strings.TrimPrefix(p, "/histogram/"))
+	case strings.HasPrefix(p, "/raw/") && r.Method == http.MethodGet:
+		n.serveReadRaw(w, r, strings.TrimPrefix(p, "/raw/"))
+	case strings.HasPrefix(p, "/find/") && strings.HasSuffix(p, "/tags") &&
+		r.Method == http.MethodGet:
+		n.serveFindTags(w, r, strings.TrimSuffix(
+			strings.TrimPrefix(p, "/find/"), "/tags"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeError writes an error response.
+func writeError(w http.ResponseWriter, status int, format string,
+	args ...interface{},
+) {
+	http.Error(w, fmt.Sprintf(format, args...), status)
+}
+
+// statsValue returns a value in the format used by the stats endpoint.
+func statsValue(v string) map[string]string {
+	return map[string]string{"_type": "s", "_value": v}
+}
+
+// serveStats handles requests for the node stats.
+func (n *Node) serveStats(w http.ResponseWriter) {
+	current, next := n.cluster.topologyState()
+
+	writeJSON(w, map[string]interface{}{
+		"application": statsValue("snowth"),
+		"identity":    statsValue(n.id),
+		"version":     statsValue("snowthtest/" + SemVer),
+		"semver":      statsValue(SemVer),
+		"topology": map[string]interface{}{
+			"current": statsValue(current),
+			"next":    statsValue(next),
+		},
+	})
+}
+
+// serveState handles requests for the node state.
+func (n *Node) serveState(w http.ResponseWriter) {
+	current, next := n.cluster.topologyState()
+
+	writeJSON(w, map[string]interface{}{
+		"identity":    n.id,
+		"current":     current,
+		"next":        next,
+		"base_rollup": 60,
+		"rollups":     []int64{60},
+		"application": "snowth",
+		"version":     "snowthtest/" + SemVer,
+		"features": map[string]string{
+			"text:store":      "1",
+			"histogram:store": "1",
+			"nnt:store":       "1",
+		},
+	})
+}
+
+// serveGossip handles requests for the node gossip information.
+func (n *Node) serveGossip(w http.ResponseWriter) {
+	current, next := n.cluster.topologyState()
+	nodes := n.cluster.Nodes()
+	now := float64(time.Now().UnixNano()) / float64(time.Second)
+	r := []gosnowth.GossipDetail{}
+
+	for _, gn := range nodes {
+		latency := gosnowth.GossipLatency{}
+
+		for _, o := range nodes {
+			if o != gn {
+				latency[o.id] = "0"
+			}
+		}
+
+		age := gn.gossipAge()
+
+		r = append(r, gosnowth.GossipDetail{
+			ID:          gn.id,
+			Time:        now - age,
+			Age:         age,
+			CurrentTopo: current,
+			NextTopo:    next,
+			TopoState:   "n/a",
+			Latency:     latency,
+		})
+	}
+
+	writeJSON(w, r)
+}
+
+// serveTopology handles requests for a topology by hash.
+func (n *Node) serveTopology(w http.ResponseWriter, hash string) {
+	n.cluster.mu.RLock()
+	s, ok := n.cluster.topologies[hash]
+	n.cluster.mu.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown topology: %s", hash)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = io.WriteString(w, s)
+}
+
+// serveLocate handles requests for the owners of a metric.
+func (n *Node) serveLocate(w http.ResponseWriter, p string) {
+	parts := strings.SplitN(p, "/", 2)
+	if len(parts) != 2 {
+		writeError(w, http.StatusBadRequest, "invalid locate request")
+
+		return
+	}
+
+	topo := n.cluster.Topology()
+
+	owners, err := topo.FindMetric(parts[0], parts[1])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+
+	b, err := xml.Marshal(&gosnowth.Topology{
+		OldWriteCopies: topo.WriteCopies,
+		WriteCopies:    topo.WriteCopies,
+		Nodes:          owners,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = w.Write(b)
+}
+
+// decodeBody decodes a JSON request body.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "unable to decode request: %v",
+			err)
+
+		return false
+	}
+
+	return true
+}
+
+// numericWrite values are numeric data write records. The rollup parts of
+// the record are not stored.
+type numericWrite struct {
+	gosnowth.NumericWrite
+	Parts json.RawMessage `json:"parts"`
+}
+
+// nntWrite values are NNT data write records. The rollup parts of the record
+// are not stored.
+type nntWrite struct {
+	gosnowth.NNTData
+	Parts json.RawMessage `json:"parts"`
+}
+
+// serveWriteNumeric handles numeric data writes.
+func (n *Node) serveWriteNumeric(w http.ResponseWriter, r *http.Request) {
+	data := []numericWrite{}
+	if !decodeBody(w, r, &data) {
+		return
+	}
+
+	n.store.Lock()
+
+	for _, d := range data {
+		n.store.numeric = append(n.store.numeric, d.NumericWrite)
+		n.store.index(DefaultAccountID, d.ID, d.Metric, "numeric")
+	}
+
+	n.store.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveWriteNNT handles NNT data writes.
+func (n *Node) serveWriteNNT(w http.ResponseWriter, r *http.Request) {
+	data := []nntWrite{}
+	if !decodeBody(w, r, &data) {
+		return
+	}
+
+	n.store.Lock()
+
+	for _, d := range data {
+		n.store.nnt = append(n.store.nnt, d.NNTData)
+		n.store.index(DefaultAccountID, d.ID, d.Metric, "numeric")
+	}
+
+	n.store.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveWriteText handles text data writes.
+func (n *Node) serveWriteText(w http.ResponseWriter, r *http.Request) {
+	data := []gosnowth.TextData{}
+	if !decodeBody(w, r, &data) {
+		return
+	}
+
+	n.store.Lock()
+
+	for _, d := range data {
+		n.store.text = append(n.store.text, d)
+		n.store.index(DefaultAccountID, d.ID, d.Metric, "text")
+	}
+
+	n.store.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveWriteHistogram handles histogram data writes.
+func (n *Node) serveWriteHistogram(w http.ResponseWriter, r *http.Request) {
+	data := []gosnowth.HistogramData{}
+	if !decodeBody(w, r, &data) {
+		return
+	}
+
+	n.store.Lock()
+
+	for _, d := range data {
+		account := d.AccountID
+		if account == 0 {
+			account = DefaultAccountID
+		}
+
+		n.store.histogram = append(n.store.histogram, d)
+		n.store.index(account, d.ID, d.Metric, "histogram")
+	}
+
+	n.store.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveWriteRaw handles raw data writes. Metric list flatbuffers are decoded
+// and stored, and records for metrics not owned by the node are counted as
+// misdirected. Other data is counted, but not stored.
+func (n *Node) serveWriteRaw(w http.ResponseWriter, r *http.Request) {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "unable to read request: %v",
+			err)
+
+		return
+	}
+
+	res := &gosnowth.IRONdbPutResponse{}
+
+	if r.Header.Get("Content-Type") != gosnowth.MetriclistFlatbufferContentType {
+		res.Records, _ = strconv.ParseUint(
+			r.Header.Get("X-Snowth-Datapoints"), 10, 64)
+		writeJSON(w, res)
+
+		return
+	}
+
+	ml, err := decodeMetricList(b)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+
+		return
+	}
+
+	points := []RawPoint{}
+
+	for _, m := range ml.Metrics {
+		p, ok := rawPoint(m)
+		if !ok {
+			res.Errors++
+
+			continue
+		}
+
+		if !n.cluster.owns(n, p.CheckUUID, p.Metric) {
+			res.Misdirected++
+
+			continue
+		}
+
+		points = append(points, p)
+	}
+
+	n.store.Lock()
+
+	for _, p := range points {
+		account := int64(p.AccountID)
+		if account == 0 {
+			account = DefaultAccountID
+		}
+
+		n.store.raw = append(n.store.raw, p)
+		n.store.index(account, p.CheckUUID, p.Metric, rawType(p.Type))
+	}
+
+	n.store.Unlock()
+
+	res.Records = uint64(len(points))
+	res.Updated = res.Records
+
+	writeJSON(w, res)
+}
+
+// decodeMetricList decodes a metric list flatbuffer.
+func decodeMetricList(b []byte) (ml *noit.MetricListT, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			ml, err = nil, fmt.Errorf("invalid metric list: %v", r)
+		}
+	}()
+
+	if len(b) < 8 {
+		return nil, fmt.Errorf("invalid metric list: too short")
+	}
+
+	return noit.GetRootAsMetricList(b, 0).UnPack(), nil
+}
+
+// rawPoint converts a metric list entry into a raw point.
+func rawPoint(m *noit.MetricT) (RawPoint, bool) {
+	if m == nil || m.Value == nil {
+		return RawPoint{}, false
+	}
+
+	name := m.Value.Name
+	if len(m.Value.StreamTags) > 0 {
+		name += "|ST[" + strings.Join(m.Value.StreamTags, ",") + "]"
+	}
+
+	if mn, err := gosnowth.ParseMetricName(name); err == nil {
+		name = mn.CanonicalName
+	}
+
+	p := RawPoint{
+		AccountID: m.AccountId,
+		CheckUUID: strings.ToLower(m.CheckUuid),
+		CheckName: m.CheckName,
+		Metric:    name,
+		Timestamp: m.Value.Timestamp,
+	}
+
+	if p.Timestamp == 0 {
+		p.Timestamp = m.Timestamp
+	}
+
+	if m.Value.Value != nil {
+		p.Type = m.Value.Value.Type
+		p.Value = m.Value.Value.Value
+	}
+
+	return p, true
+}
+
+// readRange parses the start and end timestamps of a read request.
+func readRange(start, end string) (int64, int64, error) {
+	s, err := parseSeconds(start)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	e, err := parseSeconds(end)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return s, e, nil
+}
+
+// parseSeconds parses a timestamp in seconds, which may have a fractional
+// part.
+func parseSeconds(s string) (int64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp: %s", s)
+	}
+
+	return int64(f), nil
+}
+
+// serveRead handles numeric, NNT and text data reads. Numeric reads use the
+// /read/<start>/<end>/<period>/<uuid>/<type>/<metric> form, and text reads use
+// the /read/<start>/<end>/<uuid>/<metric> form.
+func (n *Node) serveRead(w http.ResponseWriter, p string) {
+	parts := strings.SplitN(p, "/", 6)
+
+	if len(parts) == 6 {
+		if period, err := strconv.ParseInt(parts[2], 10, 64); err == nil {
+			n.serveReadNumeric(w, parts[0], parts[1], period, parts[3],
+				parts[4], parts[5])
+
+			return
+		}
+	}
+
+	parts = strings.SplitN(p, "/", 4)
+	if len(parts) != 4 {
+		writeError(w, http.StatusBadRequest, "invalid read request")
+
+		return
+	}
+
+	start, end, err := readRange(parts[0], parts[1])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+
+		return
+	}
+
+	writeJSON(w, n.cluster.textValues(parts[2], parts[3], start, end))
+}
+
+// numericTypes maps the data types of numeric reads to the names of the
+// stored values.
+var numericTypes = map[string]string{
+	"count":             "count",
+	"average":           "value",
+	"value":             "value",
+	"stddev":            "stddev",
+	"derive":            "derivative",
+	"derivative":        "derivative",
+	"derive_stddev":     "derivative_stddev",
+	"derivative_stddev": "derivative_stddev",
+	"counter":           "counter",
+	"counter_stddev":    "counter_stddev",
+}
+
+// serveReadNumeric handles numeric and NNT data reads.
+func (n *Node) serveReadNumeric(w http.ResponseWriter, start, end string,
+	period int64, id, typ, metric string,
+) {
+	s, e, err := readRange(start, end)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+
+		return
+	}
+
+	values := n.cluster.numericValues(id, metric, s, e, period)
+	r := [][]interface{}{}
+
+	for _, v := range values {
+		if typ == "all" {
+			r = append(r, []interface{}{v.ts, v.values})
+
+			continue
+		}
+
+		k, ok := numericTypes[typ]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid data type: %s", typ)
+
+			return
+		}
+
+		r = append(r, []interface{}{v.ts, v.values[k]})
+	}
+
+	writeJSON(w, r)
+}
+
+// serveReadHistogram handles histogram data reads, using the
+// /histogram/<start>/<end>/<period>/<uuid>/<metric> form.
+func (n *Node) serveReadHistogram(w http.ResponseWriter, p string) {
+	parts := strings.SplitN(p, "/", 5)
+	if len(parts) != 5 {
+		writeError(w, http.StatusBadRequest, "invalid histogram request")
+
+		return
+	}
+
+	start, end, err := readRange(parts[0], parts[1])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+
+		return
+	}
+
+	period, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid period: %s", parts[2])
+
+		return
+	}
+
+	metric, err := url.QueryUnescape(parts[4])
+	if err != nil {
+		metric = parts[4]
+	}
+
+	writeJSON(w, n.cluster.histogramValues(parts[3], metric, start, end,
+		period))
+}
+
+// serveReadRaw handles raw numeric data reads, using the
+// /raw/<uuid>/<metric>?start_ts=<start>&end_ts=<end> form.
+func (n *Node) serveReadRaw(w http.ResponseWriter, r *http.Request,
+	p string,
+) {
+	parts := strings.SplitN(p, "/", 2)
+	if len(parts) != 2 {
+		writeError(w, http.StatusBadRequest, "invalid raw request")
+
+		return
+	}
+
+	start, end, err := readRange(r.URL.Query().Get("start_ts"),
+		r.URL.Query().Get("end_ts"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+
+		return
+	}
+
+	writeJSON(w, n.cluster.rawValues(parts[0], parts[1], start*1000,
+		end*1000))
+}
+
+// serveFindTags handles find tags requests.
+func (n *Node) serveFindTags(w http.ResponseWriter, r *http.Request,
+	account string,
+) {
+	id, err := strconv.ParseInt(account, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid account: %s", account)
+
+		return
+	}
+
+	q, err := parseTagQuery(r.URL.Query().Get("query"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+
+		return
+	}
+
+	items := n.cluster.findMetrics(id, q)
+	count := len(items)
+
+	if r.URL.Query().Get("count_only") == "1" {
+		writeJSON(w, &gosnowth.FindTagsResult{Count: int64(count)})
+
+		return
+	}
+
+	if v := r.Header.Get("X-Snowth-Advisory-Limit"); v != "" {
+		if limit, err := strconv.Atoi(v); err == nil && limit >= 0 &&
+			limit < len(items) {
+			items = items[:limit]
+		}
+	}
+
+	w.Header().Set("X-Snowth-Search-Result-Count", strconv.Itoa(count))
+	w.Header().Set("X-Snowth-Search-Result-Count-Is-Estimate", "false")
+	writeJSON(w, items)
+}
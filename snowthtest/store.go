@@ -0,0 +1,410 @@
+package snowthtest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/circonus-labs/gosnowth"
+	"github.com/circonus-labs/gosnowth/fb/noit"
+)
+
+// RawPoint values contain the data points written to a node as raw metric
+// list flatbuffers.
+type RawPoint struct {
+	AccountID int32
+	CheckUUID string
+	CheckName string
+	Metric    string
+	Timestamp uint64
+	Type      noit.MetricValueUnion
+	Value     interface{}
+}
+
+// metricKey values identify the metrics indexed by a node.
+type metricKey struct {
+	account int64
+	id      string
+	metric  string
+}
+
+// store values contain the data written to a node.
+type store struct {
+	sync.RWMutex
+	numeric   []gosnowth.NumericWrite
+	nnt       []gosnowth.NNTData
+	text      []gosnowth.TextData
+	histogram []gosnowth.HistogramData
+	raw       []RawPoint
+	metrics   map[metricKey]string
+}
+
+// newStore creates a new empty store.
+func newStore() *store {
+	return &store{metrics: map[metricKey]string{}}
+}
+
+// index adds a metric to the find tags index of the store. The store must be
+// locked by the caller.
+func (s *store) index(account int64, id, metric, typ string) {
+	s.metrics[metricKey{account: account, id: strings.ToLower(id),
+		metric: metric}] = typ
+}
+
+// NumericWrites returns the numeric data written to the node.
+func (n *Node) NumericWrites() []gosnowth.NumericWrite {
+	n.store.RLock()
+	defer n.store.RUnlock()
+
+	return append([]gosnowth.NumericWrite{}, n.store.numeric...)
+}
+
+// NNTWrites returns the NNT data written to the node.
+func (n *Node) NNTWrites() []gosnowth.NNTData {
+	n.store.RLock()
+	defer n.store.RUnlock()
+
+	return append([]gosnowth.NNTData{}, n.store.nnt...)
+}
+
+// TextWrites returns the text data written to the node.
+func (n *Node) TextWrites() []gosnowth.TextData {
+	n.store.RLock()
+	defer n.store.RUnlock()
+
+	return append([]gosnowth.TextData{}, n.store.text...)
+}
+
+// HistogramWrites returns the histogram data written to the node.
+func (n *Node) HistogramWrites() []gosnowth.HistogramData {
+	n.store.RLock()
+	defer n.store.RUnlock()
+
+	return append([]gosnowth.HistogramData{}, n.store.histogram...)
+}
+
+// RawPoints returns the raw data points written to the node. Points which were
+// misdirected to the node are not stored.
+func (n *Node) RawPoints() []RawPoint {
+	n.store.RLock()
+	defer n.store.RUnlock()
+
+	return append([]RawPoint{}, n.store.raw...)
+}
+
+// Reset discards the data written to the node.
+func (n *Node) Reset() {
+	n.store.Lock()
+	defer n.store.Unlock()
+
+	n.store.numeric = nil
+	n.store.nnt = nil
+	n.store.text = nil
+	n.store.histogram = nil
+	n.store.raw = nil
+	n.store.metrics = map[metricKey]string{}
+}
+
+// numericPoint values contain the numeric values read from a cluster.
+type numericPoint struct {
+	ts     int64
+	values map[string]int64
+}
+
+// numericValues returns the numeric and NNT values of a metric stored by the
+// running nodes of the cluster, by period aligned timestamp.
+func (c *Cluster) numericValues(id, metric string, start, end,
+	period int64,
+) []numericPoint {
+	if period <= 0 {
+		period = 1
+	}
+
+	m := map[int64]map[string]int64{}
+
+	add := func(offset int64, v map[string]int64) {
+		ts := offset - offset%period
+		if ts < start || ts >= end {
+			return
+		}
+
+		m[ts] = v
+	}
+
+	for _, n := range c.runningNodes() {
+		n.store.RLock()
+
+		for _, d := range n.store.numeric {
+			if strings.EqualFold(d.ID, id) && d.Metric == metric {
+				add(d.Offset, numericMap(d.Count, d.Value, d.StdDev,
+					d.Derivative, d.DerivativeStdDev, d.Counter,
+					d.CounterStdDev))
+			}
+		}
+
+		for _, d := range n.store.nnt {
+			if strings.EqualFold(d.ID, id) && d.Metric == metric {
+				add(d.Offset, numericMap(d.Count, d.Value, d.StdDev,
+					d.Derivative, d.DerivativeStdDev, d.Counter,
+					d.CounterStdDev))
+			}
+		}
+
+		n.store.RUnlock()
+	}
+
+	return sortedNumeric(m)
+}
+
+// numericMap converts numeric data into a map of values by data type.
+func numericMap(count, value, stddev, derivative, derivativeStdDev, counter,
+	counterStdDev int64,
+) map[string]int64 {
+	return map[string]int64{
+		"count":             count,
+		"value":             value,
+		"stddev":            stddev,
+		"derivative":        derivative,
+		"derivative_stddev": derivativeStdDev,
+		"counter":           counter,
+		"counter_stddev":    counterStdDev,
+	}
+}
+
+// sortedNumeric converts a map of numeric values into a slice ordered by
+// timestamp.
+func sortedNumeric(m map[int64]map[string]int64) []numericPoint {
+	r := make([]numericPoint, 0, len(m))
+
+	for ts, v := range m {
+		r = append(r, numericPoint{ts: ts, values: v})
+	}
+
+	sort.Slice(r, func(i, j int) bool { return r[i].ts < r[j].ts })
+
+	return r
+}
+
+// textValues returns the text values of a metric stored by the running nodes
+// of the cluster, ordered by timestamp.
+func (c *Cluster) textValues(id, metric string, start, end int64,
+) [][]interface{} {
+	m := map[int64]string{}
+
+	for _, n := range c.runningNodes() {
+		n.store.RLock()
+
+		for _, d := range n.store.text {
+			if !strings.EqualFold(d.ID, id) || d.Metric != metric {
+				continue
+			}
+
+			ts, err := parseSeconds(d.Offset)
+			if err != nil || ts < start || ts >= end {
+				continue
+			}
+
+			m[ts] = d.Value
+		}
+
+		n.store.RUnlock()
+	}
+
+	keys := make([]int64, 0, len(m))
+	for ts := range m {
+		keys = append(keys, ts)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	r := [][]interface{}{}
+	for _, ts := range keys {
+		r = append(r, []interface{}{ts, m[ts]})
+	}
+
+	return r
+}
+
+// histogramValues returns the histogram values of a metric stored by the
+// running nodes of the cluster, ordered by timestamp.
+func (c *Cluster) histogramValues(id, metric string, start, end,
+	period int64,
+) [][]interface{} {
+	if period <= 0 {
+		period = 1
+	}
+
+	m := map[int64]map[string]int64{}
+
+	for _, n := range c.runningNodes() {
+		n.store.RLock()
+
+		for _, d := range n.store.histogram {
+			if !strings.EqualFold(d.ID, id) || d.Metric != metric ||
+				d.Histogram == nil {
+				continue
+			}
+
+			ts := d.Offset - d.Offset%period
+			if ts < start || ts >= end {
+				continue
+			}
+
+			if m[ts] == nil {
+				m[ts] = map[string]int64{}
+			}
+
+			for _, s := range d.Histogram.DecStrings() {
+				k, v := parseBin(s)
+				if k != "" {
+					m[ts][k] += v
+				}
+			}
+		}
+
+		n.store.RUnlock()
+	}
+
+	r := [][]interface{}{}
+	for _, p := range sortedNumeric(m) {
+		r = append(r, []interface{}{p.ts, period, p.values})
+	}
+
+	return r
+}
+
+// rawValues returns the raw numeric values of a metric stored by the running
+// nodes of the cluster, ordered by timestamp in milliseconds.
+func (c *Cluster) rawValues(id, metric string, start, end int64,
+) [][]interface{} {
+	m := map[int64]float64{}
+
+	for _, n := range c.runningNodes() {
+		n.store.RLock()
+
+		for _, p := range n.store.raw {
+			if !strings.EqualFold(p.CheckUUID, id) || p.Metric != metric {
+				continue
+			}
+
+			ts := int64(p.Timestamp)
+			if ts < start || ts >= end {
+				continue
+			}
+
+			if v, ok := rawNumeric(p.Value); ok {
+				m[ts] = v
+			}
+		}
+
+		n.store.RUnlock()
+	}
+
+	keys := make([]int64, 0, len(m))
+	for ts := range m {
+		keys = append(keys, ts)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	r := [][]interface{}{}
+	for _, ts := range keys {
+		r = append(r, []interface{}{ts, m[ts]})
+	}
+
+	return r
+}
+
+// rawNumeric converts a raw metric value into a float64, if it is numeric.
+func rawNumeric(v interface{}) (float64, bool) {
+	switch tv := v.(type) {
+	case *noit.IntValueT:
+		return float64(tv.Value), true
+	case *noit.UintValueT:
+		return float64(tv.Value), true
+	case *noit.LongValueT:
+		return float64(tv.Value), true
+	case *noit.UlongValueT:
+		return float64(tv.Value), true
+	case *noit.DoubleValueT:
+		return tv.Value, true
+	default:
+		return 0, false
+	}
+}
+
+// rawType returns the find tags type of a raw metric value type.
+func rawType(t noit.MetricValueUnion) string {
+	switch t {
+	case noit.MetricValueUnionStringValue,
+		noit.MetricValueUnionAbsentStringValue:
+		return "text"
+	case noit.MetricValueUnionHistogram,
+		noit.MetricValueUnionAbsentHistogramValue:
+		return "histogram"
+	default:
+		return "numeric"
+	}
+}
+
+// findMetrics returns the metrics indexed by the running nodes of the cluster
+// for an account which match a tag query, ordered by metric name.
+func (c *Cluster) findMetrics(account int64,
+	q *tagQuery,
+) []gosnowth.FindTagsItem {
+	m := map[metricKey]string{}
+
+	for _, n := range c.runningNodes() {
+		n.store.RLock()
+
+		for k, typ := range n.store.metrics {
+			if k.account == account && q.match(k.id, k.metric) {
+				m[k] = typ
+			}
+		}
+
+		n.store.RUnlock()
+	}
+
+	r := make([]gosnowth.FindTagsItem, 0, len(m))
+
+	for k, typ := range m {
+		r = append(r, gosnowth.FindTagsItem{
+			UUID:       k.id,
+			MetricName: k.metric,
+			Type:       typ,
+			AccountID:  k.account,
+		})
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].MetricName == r[j].MetricName {
+			return r[i].UUID < r[j].UUID
+		}
+
+		return r[i].MetricName < r[j].MetricName
+	})
+
+	return r
+}
+
+// parseBin parses a histogram bin in the H[<value>]=<count> format returned
+// by circonusllhist DecStrings(), returning the bin value and count.
+func parseBin(s string) (string, int64) {
+	if !strings.HasPrefix(s, "H[") {
+		return "", 0
+	}
+
+	i := strings.Index(s, "]=")
+	if i < 0 {
+		return "", 0
+	}
+
+	count, err := strconv.ParseInt(s[i+2:], 10, 64)
+	if err != nil {
+		return "", 0
+	}
+
+	return s[2:i], count
+}
@@ -0,0 +1,224 @@
+package snowthtest
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/circonus-labs/gosnowth"
+)
+
+// tagQuery values are parsed IRONdb tag queries. A query is either an
+// and(), or() or not() operation on a list of queries, or a category:value
+// term. Values may be exact, contain * wildcards, or be /regular expressions/.
+// Categories and values may be base64 encoded as b"...". The __name category
+// matches the metric name and the __check_uuid category matches the check
+// UUID.
+type tagQuery struct {
+	op       string
+	args     []*tagQuery
+	category string
+	value    string
+	re       *regexp.Regexp
+}
+
+// parseTagQuery parses an IRONdb tag query.
+func parseTagQuery(s string) (*tagQuery, error) {
+	q, rest, err := parseTagTerm(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("invalid tag query: unexpected %q", rest)
+	}
+
+	return q, nil
+}
+
+// parseTagTerm parses one tag query term, returning the remaining text.
+func parseTagTerm(s string) (*tagQuery, string, error) {
+	for _, op := range []string{"and", "or", "not"} {
+		if !strings.HasPrefix(s, op+"(") {
+			continue
+		}
+
+		q := &tagQuery{op: op}
+		rest := s[len(op)+1:]
+
+		for {
+			arg, r, err := parseTagTerm(strings.TrimSpace(rest))
+			if err != nil {
+				return nil, "", err
+			}
+
+			q.args = append(q.args, arg)
+			r = strings.TrimSpace(r)
+
+			switch {
+			case strings.HasPrefix(r, ","):
+				rest = r[1:]
+
+				continue
+			case strings.HasPrefix(r, ")"):
+				if op == "not" && len(q.args) != 1 {
+					return nil, "", errors.New(
+						"invalid tag query: not() requires one argument")
+				}
+
+				return q, r[1:], nil
+			default:
+				return nil, "", fmt.Errorf("invalid tag query: "+
+					"unterminated %s()", op)
+			}
+		}
+	}
+
+	end := termEnd(s)
+	term := s[:end]
+
+	if term == "" {
+		return nil, "", errors.New("invalid tag query: empty term")
+	}
+
+	q := &tagQuery{}
+	cat, val := splitTerm(term)
+
+	var err error
+
+	if q.category, err = decodeTagPart(cat); err != nil {
+		return nil, "", err
+	}
+
+	if len(val) > 1 && strings.HasPrefix(val, "/") &&
+		strings.HasSuffix(val, "/") {
+		if q.re, err = regexp.Compile(val[1 : len(val)-1]); err != nil {
+			return nil, "", fmt.Errorf("invalid tag query regex: %w", err)
+		}
+	} else if q.value, err = decodeTagPart(val); err != nil {
+		return nil, "", err
+	}
+
+	return q, s[end:], nil
+}
+
+// termEnd returns the index of the end of a category:value term. Commas and
+// parentheses inside quotes and regular expressions do not end a term.
+func termEnd(s string) int {
+	quote, re := false, false
+
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quote = !quote
+		case c == '/' && !quote:
+			re = !re
+		case (c == ',' || c == ')') && !quote && !re:
+			return i
+		}
+	}
+
+	return len(s)
+}
+
+// splitTerm splits a term into its category and value, ignoring colons
+// inside quotes.
+func splitTerm(s string) (string, string) {
+	quote := false
+
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quote = !quote
+		case c == ':' && !quote:
+			return s[:i], s[i+1:]
+		}
+	}
+
+	return s, ""
+}
+
+// decodeTagPart decodes a b"..." base64 encoded tag category or value.
+func decodeTagPart(s string) (string, error) {
+	if !strings.HasPrefix(s, `b"`) || !strings.HasSuffix(s, `"`) ||
+		len(s) < 3 {
+		return s, nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s[2 : len(s)-1])
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 tag query value: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// match returns whether a metric matches the query.
+func (q *tagQuery) match(id, metric string) bool {
+	switch q.op {
+	case "and":
+		for _, a := range q.args {
+			if !a.match(id, metric) {
+				return false
+			}
+		}
+
+		return true
+	case "or":
+		for _, a := range q.args {
+			if a.match(id, metric) {
+				return true
+			}
+		}
+
+		return false
+	case "not":
+		return !q.args[0].match(id, metric)
+	}
+
+	switch q.category {
+	case "__name":
+		name := metric
+
+		if mn, err := gosnowth.ParseMetricName(metric); err == nil {
+			name = mn.Name
+		}
+
+		return q.matchValue(name)
+	case "__check_uuid":
+		return q.matchValue(id)
+	}
+
+	mn, err := gosnowth.ParseMetricName(metric)
+	if err != nil {
+		return false
+	}
+
+	for _, t := range mn.StreamTags {
+		if t.Category == q.category && q.matchValue(t.Value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchValue returns whether a value matches the value of a query term.
+func (q *tagQuery) matchValue(v string) bool {
+	if q.re != nil {
+		return q.re.MatchString(v)
+	}
+
+	if q.value == "" || q.value == "*" {
+		return true
+	}
+
+	if ok, err := path.Match(q.value, v); err == nil && ok {
+		return true
+	}
+
+	return q.value == v
+}
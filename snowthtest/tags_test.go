@@ -0,0 +1,45 @@
+package snowthtest
+
+import "testing"
+
+func TestTagQuery(t *testing.T) {
+	t.Parallel()
+
+	metric := "cpu|ST[env:prod,host:web1]"
+
+	tests := []struct {
+		query string
+		match bool
+	}{
+		{"env:prod", true},
+		{"env:dev", false},
+		{"and(__name:cpu,host:web*)", true},
+		{"and(__name:cpu,host:db*)", false},
+		{"or(env:dev,host:web1)", true},
+		{"not(env:prod)", false},
+		{"and(not(env:dev),host:/^web[0-9]$/)", true},
+		{`b"ZW52":b"cHJvZA=="`, true},
+		{"__check_uuid:" + testUUID, true},
+		{"env:", true},
+		{"zone:", false},
+	}
+
+	for _, tt := range tests {
+		q, err := parseTagQuery(tt.query)
+		if err != nil {
+			t.Fatalf("Unable to parse query %v: %v", tt.query, err)
+		}
+
+		if m := q.match(testUUID, metric); m != tt.match {
+			t.Errorf("Expected match for %v: %v, got: %v", tt.query,
+				tt.match, m)
+		}
+	}
+
+	for _, s := range []string{"", "and(env:prod", "not(a:b,c:d)",
+		"env:prod)", "a:/[/"} {
+		if _, err := parseTagQuery(s); err == nil {
+			t.Errorf("Expected error for query: %v", s)
+		}
+	}
+}
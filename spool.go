@@ -0,0 +1,949 @@
+package gosnowth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/gosnowth/fb/nntbs"
+)
+
+// Default spool settings, used when the SpoolConfig values are zero.
+const (
+	DefaultSpoolSegmentSize = 16 << 20
+	DefaultSpoolMaxSize     = 1 << 30
+	DefaultSpoolMaxAge      = 24 * time.Hour
+
+	DefaultSpoolReplayInterval = 30 * time.Second
+)
+
+// SpoolKind values identify the type of payload contained in a spool record.
+type SpoolKind uint8
+
+// SpoolKind values used by spool records.
+const (
+	// SpoolMetricList records contain flatbuffer encoded noit MetricList
+	// data, as written by WriteRawContext.
+	SpoolMetricList SpoolKind = 1
+
+	// SpoolNNTBS records contain flatbuffer encoded NNTMerge data, as written
+	// by WriteNNTBSFlatbufferContext.
+	SpoolNNTBS SpoolKind = 2
+)
+
+var (
+	// ErrSpoolFull is returned when a record cannot be added to a spool
+	// without exceeding its maximum size.
+	ErrSpoolFull = errors.New("spool is full")
+
+	// ErrSpoolClosed is returned when a record is added to a closed spool.
+	ErrSpoolClosed = errors.New("spool is closed")
+
+	// errSpoolCorrupt is returned when a spool record is incomplete or fails
+	// its checksum.
+	errSpoolCorrupt = errors.New("corrupt spool record")
+
+	// errSpoolRejected is returned by replay send functions when a record
+	// was rejected and must be dropped rather than sent again.
+	errSpoolRejected = errors.New("spooled write rejected")
+)
+
+const (
+	// spoolHeaderSize is the size of a spool record header: the payload
+	// length, checksum, kind, timestamp and data point count.
+	spoolHeaderSize = 4 + 4 + 1 + 8 + 8
+
+	// spoolMaxRecord is the largest payload accepted when reading a record.
+	spoolMaxRecord = 1 << 30
+
+	// spoolSegmentExt is the file name extension of spool segment files.
+	spoolSegmentExt = ".spool"
+
+	// spoolCursorFile is the name of the file recording replay progress.
+	spoolCursorFile = "cursor"
+)
+
+// spoolTable is the CRC-32 table used for spool record checksums.
+var spoolTable = crc32.MakeTable(crc32.Castagnoli)
+
+// SpoolConfig values represent the configuration of a durable write spool.
+type SpoolConfig struct {
+	// Dir is the directory containing the spool segment files. It is
+	// created if it does not exist, and must not be shared by spools.
+	Dir string `json:"dir"`
+
+	// SegmentSize is the size at which a new segment file is started.
+	SegmentSize int64 `json:"segment_size,omitempty"`
+
+	// MaxSize is the maximum total size of the segment files. When adding a
+	// record would exceed it, the oldest segments are dropped.
+	MaxSize int64 `json:"max_size,omitempty"`
+
+	// MaxAge is the maximum age of a record. Older records are dropped
+	// rather than replayed.
+	MaxAge time.Duration `json:"max_age,omitempty"`
+
+	// ReplayInterval is the interval at which a client replays the spool
+	// while it contains records, in addition to when nodes are activated.
+	ReplayInterval time.Duration `json:"replay_interval,omitempty"`
+}
+
+// SpoolStats values contain the state of a spool.
+type SpoolStats struct {
+	Segments int    `json:"segments"`
+	Bytes    int64  `json:"bytes"`
+	Records  int64  `json:"records"`
+	Spooled  uint64 `json:"spooled"`
+	Replayed uint64 `json:"replayed"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+// spoolRecord values contain a record read from a spool segment.
+type spoolRecord struct {
+	kind       SpoolKind
+	time       time.Time
+	dataPoints uint64
+	payload    []byte
+}
+
+// spoolSegment values describe a spool segment file.
+type spoolSegment struct {
+	seq     uint64
+	size    int64
+	records int64
+}
+
+// spoolCursor values record the position of the next record to replay.
+type spoolCursor struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// Spool values are durable, segmented, on-disk logs of encoded write
+// payloads. Each record is written with a checksum and synced to disk before
+// Append returns. On open, records left by a previous process are recovered,
+// and incomplete records at the end of a segment, left by a crash, are
+// ignored. Records are replayed in the order they were added, and replay
+// progress is recorded, so that records are not sent again after a restart.
+type Spool struct {
+	mu        sync.Mutex
+	cfg       SpoolConfig
+	segs      []spoolSegment
+	active    *os.File
+	activeSeq uint64
+	nextSeq   uint64
+	size      int64
+	cursor    spoolCursor
+	reading   uint64
+	isReading bool
+	replaying bool
+	closed    bool
+	spooled   uint64
+	replayed  uint64
+	dropped   uint64
+	nowFunc   func() time.Time
+}
+
+// OpenSpool opens the spool in the configured directory, creating it if it
+// does not exist, and recovers any records it contains. Segments older than
+// the maximum age are removed.
+func OpenSpool(cfg *SpoolConfig) (*Spool, error) {
+	if cfg == nil || cfg.Dir == "" {
+		return nil, fmt.Errorf("unable to open spool: no directory")
+	}
+
+	s := &Spool{cfg: *cfg, nowFunc: time.Now}
+
+	if s.cfg.SegmentSize <= 0 {
+		s.cfg.SegmentSize = DefaultSpoolSegmentSize
+	}
+
+	if s.cfg.MaxSize <= 0 {
+		s.cfg.MaxSize = DefaultSpoolMaxSize
+	}
+
+	if s.cfg.MaxAge <= 0 {
+		s.cfg.MaxAge = DefaultSpoolMaxAge
+	}
+
+	if s.cfg.ReplayInterval <= 0 {
+		s.cfg.ReplayInterval = DefaultSpoolReplayInterval
+	}
+
+	if err := os.MkdirAll(s.cfg.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("unable to open spool: %w", err)
+	}
+
+	if err := s.loadCursor(); err != nil {
+		return nil, fmt.Errorf("unable to open spool: %w", err)
+	}
+
+	seqs, err := s.segmentFiles()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open spool: %w", err)
+	}
+
+	s.nextSeq = s.cursor.Segment
+
+	for _, seq := range seqs {
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+
+		name := s.segmentName(seq)
+
+		if seq < s.cursor.Segment {
+			// The segment was replayed, but not removed before exiting.
+			if err := os.Remove(name); err != nil {
+				return nil, fmt.Errorf("unable to open spool: %w", err)
+			}
+
+			continue
+		}
+
+		fi, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open spool: %w", err)
+		}
+
+		offset := int64(0)
+		if seq == s.cursor.Segment {
+			offset = s.cursor.Offset
+		}
+
+		records, err := countSpoolRecords(name, offset)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open spool: %w", err)
+		}
+
+		if s.nowFunc().Sub(fi.ModTime()) > s.cfg.MaxAge {
+			if err := os.Remove(name); err != nil {
+				return nil, fmt.Errorf("unable to open spool: %w", err)
+			}
+
+			s.dropped += uint64(records)
+
+			continue
+		}
+
+		s.segs = append(s.segs, spoolSegment{
+			seq:     seq,
+			size:    fi.Size(),
+			records: records,
+		})
+
+		s.size += fi.Size()
+	}
+
+	return s, nil
+}
+
+// Append adds a record containing an encoded payload to the spool. The record
+// is synced to disk before Append returns. If adding the record would exceed
+// the maximum spool size, the oldest segments are dropped. ErrSpoolFull is
+// returned if the record is larger than the maximum spool size.
+func (s *Spool) Append(kind SpoolKind, payload []byte,
+	dataPoints uint64,
+) error {
+	rec := encodeSpoolRecord(kind, s.nowFunc(), dataPoints, payload)
+	n := int64(len(rec))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSpoolClosed
+	}
+
+	if n > s.cfg.MaxSize {
+		return ErrSpoolFull
+	}
+
+	for s.size+n > s.cfg.MaxSize {
+		if !s.dropOldest() {
+			return ErrSpoolFull
+		}
+	}
+
+	if s.active == nil || (s.activeSize() > 0 &&
+		s.activeSize()+n > s.cfg.SegmentSize) {
+		if err := s.rotate(); err != nil {
+			return fmt.Errorf("unable to append spool record: %w", err)
+		}
+	}
+
+	if _, err := s.active.Write(rec); err != nil {
+		// Seal the segment, so that a partially written record is only
+		// found at the end of a segment.
+		_ = s.seal()
+
+		return fmt.Errorf("unable to append spool record: %w", err)
+	}
+
+	if err := s.active.Sync(); err != nil {
+		_ = s.seal()
+
+		return fmt.Errorf("unable to append spool record: %w", err)
+	}
+
+	seg := &s.segs[len(s.segs)-1]
+	seg.size += n
+	seg.records++
+	s.size += n
+	s.spooled++
+
+	return nil
+}
+
+// Stats returns the current state of the spool.
+func (s *Spool) Stats() SpoolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := SpoolStats{
+		Segments: len(s.segs),
+		Bytes:    s.size,
+		Spooled:  s.spooled,
+		Replayed: s.replayed,
+		Dropped:  s.dropped,
+	}
+
+	for _, seg := range s.segs {
+		st.Records += seg.records
+	}
+
+	return st
+}
+
+// Close closes the spool. Records which have not been replayed remain on
+// disk, and are recovered when the spool is opened again.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	s.closed = true
+
+	return s.seal()
+}
+
+// pending returns whether the spool contains records to replay.
+func (s *Spool) pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, seg := range s.segs {
+		if seg.records > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replay sends the records in the spool, in the order they were added, using
+// the provided function. Records added while replaying are sent unless they
+// are in the segment being written when replay completes. Replay stops at the
+// first error returned by the send function, leaving the remaining records in
+// the spool, unless the error is errSpoolRejected, in which case the record is
+// dropped. Only one replay runs at a time; calls made while a replay is
+// running return immediately.
+func (s *Spool) replay(send func(rec *spoolRecord) error) error {
+	s.mu.Lock()
+
+	if s.closed || s.replaying {
+		s.mu.Unlock()
+
+		return nil
+	}
+
+	s.replaying = true
+
+	err := s.seal()
+
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.replaying, s.isReading = false, false
+		s.mu.Unlock()
+	}()
+
+	if err != nil {
+		return fmt.Errorf("unable to replay spool: %w", err)
+	}
+
+	for {
+		s.mu.Lock()
+
+		if s.closed || len(s.segs) == 0 ||
+			(s.active != nil && s.segs[0].seq == s.activeSeq) {
+			s.mu.Unlock()
+
+			return nil
+		}
+
+		seq := s.segs[0].seq
+		offset := int64(0)
+
+		if s.cursor.Segment == seq {
+			offset = s.cursor.Offset
+		}
+
+		s.reading, s.isReading = seq, true
+
+		s.mu.Unlock()
+
+		if err := s.replaySegment(seq, offset, send); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+
+		s.removeSegment(0)
+		s.cursor = spoolCursor{Segment: seq + 1}
+		s.isReading = false
+		err := s.saveCursor()
+
+		s.mu.Unlock()
+
+		if err != nil {
+			return fmt.Errorf("unable to replay spool: %w", err)
+		}
+	}
+}
+
+// replaySegment sends the records of a segment, starting at an offset. The
+// end of the segment is reached at the end of the file, or at the first
+// incomplete or corrupt record.
+func (s *Spool) replaySegment(seq uint64, offset int64,
+	send func(rec *spoolRecord) error,
+) error {
+	f, err := os.Open(s.segmentName(seq))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("unable to replay spool: %w", err)
+	}
+
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("unable to replay spool: %w", err)
+	}
+
+	r := bufio.NewReader(f)
+
+	for {
+		rec, n, err := readSpoolRecord(r)
+		if err != nil {
+			// Records following a corrupt record are dropped with the
+			// segment.
+			return nil
+		}
+
+		sent := true
+
+		if s.nowFunc().Sub(rec.time) > s.cfg.MaxAge {
+			sent = false
+		} else if err := send(rec); err != nil {
+			if !errors.Is(err, errSpoolRejected) {
+				return err
+			}
+
+			sent = false
+		}
+
+		offset += n
+
+		s.mu.Lock()
+
+		s.cursor = spoolCursor{Segment: seq, Offset: offset}
+
+		if len(s.segs) > 0 && s.segs[0].seq == seq {
+			s.segs[0].records--
+		}
+
+		if sent {
+			s.replayed++
+		} else {
+			s.dropped++
+		}
+
+		err = s.saveCursor()
+
+		s.mu.Unlock()
+
+		if err != nil {
+			return fmt.Errorf("unable to replay spool: %w", err)
+		}
+	}
+}
+
+// activeSize returns the size of the segment being written. The caller must
+// hold the spool lock.
+func (s *Spool) activeSize() int64 {
+	if s.active == nil || len(s.segs) == 0 {
+		return 0
+	}
+
+	return s.segs[len(s.segs)-1].size
+}
+
+// rotate seals the segment being written, and starts a new segment. The
+// caller must hold the spool lock.
+func (s *Spool) rotate() error {
+	if err := s.seal(); err != nil {
+		return err
+	}
+
+	seq := s.nextSeq
+
+	f, err := os.OpenFile(s.segmentName(seq),
+		os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+
+	if err := syncDir(s.cfg.Dir); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	s.nextSeq++
+	s.active, s.activeSeq = f, seq
+	s.segs = append(s.segs, spoolSegment{seq: seq})
+
+	return nil
+}
+
+// seal closes the segment being written, if any, so that no more records are
+// added to it. The caller must hold the spool lock.
+func (s *Spool) seal() error {
+	if s.active == nil {
+		return nil
+	}
+
+	err := s.active.Close()
+	s.active = nil
+
+	return err
+}
+
+// dropOldest removes the oldest segment which is not being replayed, counting
+// its records as dropped. It returns false if no segment could be removed.
+// The caller must hold the spool lock.
+func (s *Spool) dropOldest() bool {
+	for i, seg := range s.segs {
+		if s.isReading && seg.seq == s.reading {
+			continue
+		}
+
+		if s.active != nil && seg.seq == s.activeSeq {
+			if err := s.seal(); err != nil {
+				return false
+			}
+		}
+
+		s.removeSegment(i)
+
+		return true
+	}
+
+	return false
+}
+
+// removeSegment removes a segment file, counting any records it contains
+// which were not replayed as dropped. The caller must hold the spool lock.
+func (s *Spool) removeSegment(i int) {
+	seg := s.segs[i]
+
+	_ = os.Remove(s.segmentName(seg.seq))
+
+	s.dropped += uint64(seg.records)
+	s.size -= seg.size
+	s.segs = append(s.segs[:i], s.segs[i+1:]...)
+}
+
+// segmentName returns the file name of a segment.
+func (s *Spool) segmentName(seq uint64) string {
+	return filepath.Join(s.cfg.Dir, fmt.Sprintf("%016x%s", seq,
+		spoolSegmentExt))
+}
+
+// segmentFiles returns the sequence numbers of the segment files in the
+// spool directory, in ascending order.
+func (s *Spool) segmentFiles() ([]uint64, error) {
+	entries, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	seqs := []uint64{}
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, spoolSegmentExt) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name,
+			spoolSegmentExt), 16, 64)
+		if err != nil {
+			continue
+		}
+
+		seqs = append(seqs, seq)
+	}
+
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	return seqs, nil
+}
+
+// loadCursor reads the replay position from the spool directory. A missing
+// cursor file means that replay starts at the first segment.
+func (s *Spool) loadCursor() error {
+	b, err := os.ReadFile(filepath.Join(s.cfg.Dir, spoolCursorFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	if err := json.Unmarshal(b, &s.cursor); err != nil {
+		return fmt.Errorf("invalid spool cursor: %w", err)
+	}
+
+	return nil
+}
+
+// saveCursor atomically writes the replay position to the spool directory.
+// The caller must hold the spool lock.
+func (s *Spool) saveCursor() error {
+	b, err := json.Marshal(s.cursor)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(s.cfg.Dir, spoolCursorFile)
+
+	f, err := os.CreateTemp(s.cfg.Dir, spoolCursorFile+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+
+		return err
+	}
+
+	return os.Rename(f.Name(), name)
+}
+
+// syncDir syncs a directory, so that files created in it are durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = d.Sync()
+
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// encodeSpoolRecord returns the encoded form of a spool record. The checksum
+// covers the record header following it, and the payload.
+func encodeSpoolRecord(kind SpoolKind, t time.Time, dataPoints uint64,
+	payload []byte,
+) []byte {
+	b := make([]byte, spoolHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(payload)))
+	b[8] = byte(kind)
+	binary.BigEndian.PutUint64(b[9:17], uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(b[17:25], dataPoints)
+	copy(b[spoolHeaderSize:], payload)
+	binary.BigEndian.PutUint32(b[4:8], crc32.Checksum(b[8:], spoolTable))
+
+	return b
+}
+
+// readSpoolRecord reads a record from a segment, returning the record and its
+// encoded size. It returns io.EOF at the end of the segment, and
+// errSpoolCorrupt if the record is incomplete or fails its checksum.
+func readSpoolRecord(r io.Reader) (*spoolRecord, int64, error) {
+	hdr := make([]byte, spoolHeaderSize)
+
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, io.EOF
+		}
+
+		return nil, 0, errSpoolCorrupt
+	}
+
+	n := binary.BigEndian.Uint32(hdr[0:4])
+	if n > spoolMaxRecord {
+		return nil, 0, errSpoolCorrupt
+	}
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, 0, errSpoolCorrupt
+	}
+
+	sum := crc32.Update(crc32.Checksum(hdr[8:], spoolTable), spoolTable,
+		payload)
+	if sum != binary.BigEndian.Uint32(hdr[4:8]) {
+		return nil, 0, errSpoolCorrupt
+	}
+
+	return &spoolRecord{
+		kind:       SpoolKind(hdr[8]),
+		time:       time.Unix(0, int64(binary.BigEndian.Uint64(hdr[9:17]))),
+		dataPoints: binary.BigEndian.Uint64(hdr[17:25]),
+		payload:    payload,
+	}, int64(spoolHeaderSize) + int64(n), nil
+}
+
+// countSpoolRecords returns the number of valid records in a segment file,
+// starting at an offset.
+func countSpoolRecords(name string, offset int64) (int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	r := bufio.NewReader(f)
+	count := int64(0)
+
+	for {
+		if _, _, err := readSpoolRecord(r); err != nil {
+			return count, nil
+		}
+
+		count++
+	}
+}
+
+// WithoutSpool prevents the data of a write from being added to the client
+// spool when it cannot be sent, so that the error is returned instead.
+func WithoutSpool() CallOption {
+	return callOptionFunc(func(co *callOptions) {
+		co.noSpool = true
+	})
+}
+
+// Spool returns the durable write spool of the client, configured by the
+// Config Spool setting, or nil if the client has no spool.
+func (sc *SnowthClient) Spool() *Spool {
+	return sc.spool
+}
+
+// writeSpool returns the spool used for a write call, or nil if the client
+// has no spool or spooling is disabled for the call.
+func (sc *SnowthClient) writeSpool(ctx context.Context) *Spool {
+	if sc.spool == nil || callOptionsFromContext(ctx).noSpool {
+		return nil
+	}
+
+	return sc.spool
+}
+
+// spoolable returns whether a write failed because no node was able to
+// receive it, so that it can be sent again when nodes become active. Writes
+// rejected by a node are not spooled, since sending them again could fail in
+// the same way, and block the replay of the records following them.
+func spoolable(err error) bool {
+	return errors.Is(err, ErrNoActiveNode) || isConnectError(err)
+}
+
+// spoolWrite adds the payload of a failed write to a spool. It returns the
+// error of the write if the payload could not be added.
+func (sc *SnowthClient) spoolWrite(sp *Spool, kind SpoolKind, data []byte,
+	dataPoints uint64, werr error,
+) error {
+	if err := sp.Append(kind, data, dataPoints); err != nil {
+		sc.LogWarnf("unable to spool failed write: %s: %s", err.Error(),
+			werr.Error())
+
+		return werr
+	}
+
+	sc.LogDebugf("spooled failed write of %d bytes: %s", len(data),
+		werr.Error())
+
+	return nil
+}
+
+// watchSpool replays the client spool at the configured replay interval,
+// until the client is closed, so that records left after a failed replay are
+// sent without waiting for a node to be activated.
+func (sc *SnowthClient) watchSpool() {
+	if sc.spool == nil || !sc.startBackground() {
+		return
+	}
+
+	ctx, cancel := sc.backgroundContext(context.Background())
+
+	go func() {
+		defer sc.endBackground()
+		defer cancel()
+
+		tick := time.NewTicker(sc.spool.cfg.ReplayInterval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				if len(sc.ListActiveNodes()) > 0 {
+					sc.replaySpool()
+				}
+			}
+		}
+	}()
+}
+
+// replaySpool starts sending the records in the client spool in the
+// background, if there are any.
+func (sc *SnowthClient) replaySpool() {
+	if sc.spool == nil || !sc.spool.pending() || !sc.startBackground() {
+		return
+	}
+
+	go func() {
+		defer sc.endBackground()
+
+		ctx, cancel := sc.backgroundContext(context.Background())
+		defer cancel()
+
+		if err := sc.spool.replay(func(rec *spoolRecord) error {
+			return sc.sendSpooled(ctx, rec)
+		}); err != nil {
+			sc.LogWarnf("unable to replay spooled writes: %s", err.Error())
+		}
+	}()
+}
+
+// sendSpooled sends a spool record. Records which cannot be decoded, or which
+// are rejected by a node with an error which is not retryable, such as a 4xx
+// response, are reported with errSpoolRejected, so that they are dropped.
+// Records which fail with a retryable error, such as a node responding 503
+// while starting up, are kept for the next replay.
+func (sc *SnowthClient) sendSpooled(ctx context.Context,
+	rec *spoolRecord,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var err error
+
+	switch rec.kind {
+	case SpoolMetricList:
+		_, err = sc.WriteRawContext(ctx, bytes.NewReader(rec.payload), true,
+			rec.dataPoints, WithoutSpool())
+	case SpoolNNTBS:
+		merge := unpackNNTMerge(rec.payload)
+		if merge == nil {
+			return fmt.Errorf("%w: invalid nntbs data", errSpoolRejected)
+		}
+
+		err = sc.WriteNNTBSFlatbufferContext(ctx, merge, nil, WithoutSpool())
+	default:
+		return fmt.Errorf("%w: unknown record kind: %d", errSpoolRejected,
+			rec.kind)
+	}
+
+	if err != nil && ctx.Err() == nil && !spoolable(err) &&
+		!sc.replayRetryable(err) {
+		sc.LogWarnf("dropping spooled write: %s", err.Error())
+
+		return fmt.Errorf("%w: %s", errSpoolRejected, err.Error())
+	}
+
+	return err
+}
+
+// replayRetryable reports whether a spooled write which failed with the
+// provided error may succeed if it is replayed again later, according to the
+// client retry policy.
+func (sc *SnowthClient) replayRetryable(err error) bool {
+	status := 0
+
+	var ie *IRONdbError
+	if errors.As(err, &ie) {
+		if ie.Retryable {
+			return true
+		}
+
+		status = ie.StatusCode
+	}
+
+	return sc.RetryPolicy().Retryable(status, err)
+}
+
+// unpackNNTMerge decodes flatbuffer NNTBS data, returning nil if the data
+// cannot be decoded.
+func unpackNNTMerge(data []byte) (merge *nntbs.NNTMergeT) {
+	defer func() {
+		if r := recover(); r != nil {
+			merge = nil
+		}
+	}()
+
+	if len(data) < 8 {
+		return nil
+	}
+
+	return nntbs.GetRootAsNNTMerge(data, 0).UnPack()
+}
@@ -0,0 +1,493 @@
+package gosnowth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/gosnowth/fb/nntbs"
+	"github.com/circonus-labs/gosnowth/fb/noit"
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// spoolTestReplay replays a spool, returning the payloads sent. The send
+// fails after the provided number of records, unless it is negative.
+func spoolTestReplay(t *testing.T, s *Spool, limit int) ([]string, error) {
+	t.Helper()
+
+	sent := []string{}
+
+	err := s.replay(func(rec *spoolRecord) error {
+		if limit >= 0 && len(sent) == limit {
+			return ErrNoActiveNode
+		}
+
+		sent = append(sent, string(rec.payload))
+
+		return nil
+	})
+
+	return sent, err
+}
+
+func TestSpool(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfg := &SpoolConfig{Dir: dir, SegmentSize: 100}
+
+	s, err := OpenSpool(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 6; i++ {
+		if err := s.Append(SpoolMetricList, []byte(fmt.Sprintf("record%d", i)),
+			1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := s.Stats()
+	if st.Records != 6 || st.Spooled != 6 || st.Segments != 2 {
+		t.Errorf("Expected records: 6, segments: 2, got: %+v", st)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Append(SpoolMetricList, []byte("x"), 1); !errors.Is(err,
+		ErrSpoolClosed) {
+		t.Errorf("Expected error: %v, got: %v", ErrSpoolClosed, err)
+	}
+
+	// Simulate a crash while writing a record to the last segment.
+	seqs, err := s.segmentFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(s.segmentName(seqs[len(seqs)-1]),
+		os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := encodeSpoolRecord(SpoolMetricList, time.Now(), 1, []byte("torn"))
+	if _, err := f.Write(rec[:len(rec)-2]); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = f.Close()
+
+	if s, err = OpenSpool(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if st := s.Stats(); st.Records != 6 {
+		t.Errorf("Expected recovered records: 6, got: %+v", st)
+	}
+
+	sent, err := spoolTestReplay(t, s, 2)
+	if !errors.Is(err, ErrNoActiveNode) {
+		t.Errorf("Expected error: %v, got: %v", ErrNoActiveNode, err)
+	}
+
+	if strings.Join(sent, ",") != "record0,record1" {
+		t.Errorf("Expected sent: record0,record1, got: %v", sent)
+	}
+
+	if err := s.Append(SpoolMetricList, []byte("record6"), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = s.Close()
+
+	// Replay progress is recovered after a restart.
+	if s, err = OpenSpool(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	if st := s.Stats(); st.Records != 5 {
+		t.Errorf("Expected recovered records: 5, got: %+v", st)
+	}
+
+	if sent, err = spoolTestReplay(t, s, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "record2,record3,record4,record5,record6"
+	if strings.Join(sent, ",") != exp {
+		t.Errorf("Expected sent: %v, got: %v", exp, sent)
+	}
+
+	st = s.Stats()
+	if st.Records != 0 || st.Segments != 0 || st.Bytes != 0 ||
+		st.Replayed != 5 {
+		t.Errorf("Expected empty spool, got: %+v", st)
+	}
+}
+
+func TestSpoolLimits(t *testing.T) {
+	t.Parallel()
+
+	rec := int64(spoolHeaderSize + 8)
+
+	s, err := OpenSpool(&SpoolConfig{
+		Dir:         t.TempDir(),
+		SegmentSize: 2 * rec,
+		MaxSize:     4 * rec,
+		MaxAge:      time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	if err := s.Append(SpoolNNTBS, make([]byte, 5*rec), 1); !errors.Is(err,
+		ErrSpoolFull) {
+		t.Errorf("Expected error: %v, got: %v", ErrSpoolFull, err)
+	}
+
+	// The oldest segments are dropped when the spool is full.
+	for i := 0; i < 6; i++ {
+		if err := s.Append(SpoolNNTBS, []byte(fmt.Sprintf("record%02d", i)),
+			1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := s.Stats()
+	if st.Records != 4 || st.Dropped != 2 || st.Bytes != 4*rec {
+		t.Errorf("Expected records: 4, dropped: 2, got: %+v", st)
+	}
+
+	// Records older than the maximum age are dropped when replayed.
+	s.mu.Lock()
+	s.nowFunc = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	s.mu.Unlock()
+
+	sent, err := spoolTestReplay(t, s, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent) != 0 {
+		t.Errorf("Expected sent: 0, got: %v", sent)
+	}
+
+	if st := s.Stats(); st.Records != 0 || st.Dropped != 6 {
+		t.Errorf("Expected records: 0, dropped: 6, got: %+v", st)
+	}
+}
+
+func TestSpoolClient(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu   sync.Mutex
+		fail bool
+	)
+
+	received := []string{}
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		switch r.RequestURI {
+		case "/stats.json":
+			_, _ = w.Write([]byte(statsTestData))
+		case "/raw":
+			b, _ := io.ReadAll(r.Body)
+			ml := noit.GetRootAsMetricList(b, 0).UnPack()
+
+			mu.Lock()
+			if fail {
+				mu.Unlock()
+				w.WriteHeader(http.StatusInternalServerError)
+
+				return
+			}
+
+			for _, m := range ml.Metrics {
+				received = append(received, m.Value.Name)
+			}
+			mu.Unlock()
+
+			_, _ = fmt.Fprintf(w, `{"records":%d,"updated":%d,`+
+				`"misdirected":0,"errors":0}`, len(ml.Metrics),
+				len(ml.Metrics))
+		case "/nntbs":
+			b, _ := io.ReadAll(r.Body)
+			m := nntbs.GetRootAsNNTMerge(b, 0).UnPack()
+
+			mu.Lock()
+			received = append(received,
+				m.Ops[0].Metric.MetricLocator.MetricName)
+			mu.Unlock()
+
+			_, _ = w.Write([]byte(`{"records":1,"updated":1,` +
+				`"misdirected":0,"errors":0}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer ms.Close()
+
+	sc, err := NewClient(context.Background(), &Config{
+		Servers: []string{ms.URL},
+		Spool: &SpoolConfig{
+			Dir:            t.TempDir(),
+			ReplayInterval: 10 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	defer func() {
+		_ = sc.Close(context.Background())
+	}()
+
+	node := sc.ListActiveNodes()[0]
+	sc.DeactivateNodes(node)
+
+	ml := &noit.MetricListT{Metrics: []*noit.MetricT{
+		batchTestMetric("a"), batchTestMetric("b"),
+	}}
+
+	res, err := sc.WriteRawMetricList(ml, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !res.Spooled {
+		t.Errorf("Expected spooled response, got: %+v", res)
+	}
+
+	merge := &nntbs.NNTMergeT{Ops: []*nntbs.NNTMergeOpT{{
+		Metric: &nntbs.MetricInfoT{
+			MetricLocator: &nntbs.MetricLocatorT{
+				CheckUuid:  []byte(shardTestUUID),
+				MetricName: "c",
+			},
+			AccountId: 1,
+		},
+	}}}
+
+	if err := sc.WriteNNTBSFlatbuffer(merge, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sc.WriteRawMetricListContext(context.Background(), ml, nil,
+		WithoutSpool()); !errors.Is(err, ErrNoActiveNode) {
+		t.Errorf("Expected error: %v, got: %v", ErrNoActiveNode, err)
+	}
+
+	if st := sc.Spool().Stats(); st.Records != 2 {
+		t.Errorf("Expected spooled records: 2, got: %+v", st)
+	}
+
+	// Spooled writes are replayed in order when the node is activated.
+	sc.ActivateNodes(node)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sc.Spool().Stats().Replayed != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if st := sc.Spool().Stats(); st.Replayed != 2 || st.Records != 0 {
+		t.Errorf("Expected replayed: 2, got: %+v", st)
+	}
+
+	mu.Lock()
+
+	if strings.Join(received, ",") != "a,b,c" {
+		t.Errorf("Expected received: a,b,c, got: %v", received)
+	}
+
+	fail = true
+	mu.Unlock()
+
+	// Writes rejected by an active node are not spooled.
+	var ie *IRONdbError
+	if _, err := sc.WriteRawMetricList(ml, nil); !errors.As(err, &ie) {
+		t.Errorf("Expected IRONdbError, got: %v", err)
+	}
+
+	if st := sc.Spool().Stats(); st.Spooled != 2 {
+		t.Errorf("Expected spooled: 2, got: %+v", st)
+	}
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+
+	// Spooled records are replayed periodically while nodes are active.
+	builder := flatbuffers.NewBuilder(1024)
+	builder.Finish(noit.MetricListPack(builder, &noit.MetricListT{
+		Metrics: []*noit.MetricT{batchTestMetric("d")},
+	}))
+
+	if err := sc.Spool().Append(SpoolMetricList, builder.FinishedBytes(),
+		1); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for sc.Spool().Stats().Replayed != 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if st := sc.Spool().Stats(); st.Replayed != 3 || st.Records != 0 {
+		t.Errorf("Expected replayed: 3, got: %+v", st)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if strings.Join(received, ",") != "a,b,c,d" {
+		t.Errorf("Expected received: a,b,c,d, got: %v", received)
+	}
+}
+
+func TestSpoolReplayRetryable(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		status = http.StatusServiceUnavailable
+	)
+
+	attempts, received := 0, 0
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		switch r.RequestURI {
+		case "/stats.json":
+			_, _ = w.Write([]byte(statsTestData))
+		case "/raw":
+			mu.Lock()
+			defer mu.Unlock()
+
+			attempts++
+
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+
+				return
+			}
+
+			received++
+
+			_, _ = w.Write([]byte(`{"records":1,"updated":1,` +
+				`"misdirected":0,"errors":0}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer ms.Close()
+
+	sc, err := NewClient(context.Background(), &Config{
+		Servers: []string{ms.URL},
+		Spool: &SpoolConfig{
+			Dir:            t.TempDir(),
+			ReplayInterval: time.Hour,
+		},
+	})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	defer func() {
+		_ = sc.Close(context.Background())
+	}()
+
+	node := sc.ListActiveNodes()[0]
+
+	replay := func() SpoolStats {
+		t.Helper()
+
+		sc.DeactivateNodes(node)
+
+		ml := &noit.MetricListT{Metrics: []*noit.MetricT{
+			batchTestMetric("a"),
+		}}
+
+		if _, err := sc.WriteRawMetricList(ml, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		mu.Lock()
+		prev := attempts
+		mu.Unlock()
+
+		// Activating the node replays the spool in the background.
+		sc.ActivateNodes(node)
+
+		deadline := time.Now().Add(5 * time.Second)
+
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			sent := attempts > prev
+			mu.Unlock()
+
+			sc.spool.mu.Lock()
+			replaying := sc.spool.replaying
+			sc.spool.mu.Unlock()
+
+			if sent && !replaying {
+				break
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+
+		return sc.Spool().Stats()
+	}
+
+	// Records are kept when a node is not yet able to receive them.
+	if st := replay(); st.Records != 1 || st.Replayed != 0 ||
+		st.Dropped != 0 {
+		t.Errorf("Expected spooled records: 1, got: %+v", st)
+	}
+
+	mu.Lock()
+	status = http.StatusOK
+	mu.Unlock()
+
+	if st := replay(); st.Records != 0 || st.Replayed != 2 {
+		t.Errorf("Expected replayed: 2, got: %+v", st)
+	}
+
+	// Records rejected by a node are dropped.
+	mu.Lock()
+	status = http.StatusBadRequest
+	mu.Unlock()
+
+	if st := replay(); st.Records != 0 || st.Dropped != 1 {
+		t.Errorf("Expected dropped: 1, got: %+v", st)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if received != 2 {
+		t.Errorf("Expected received: 2, got: %v", received)
+	}
+}
@@ -9,22 +9,22 @@ import (
 
 // GetNodeState retrieves the state of an IRONdb node.
 func (sc *SnowthClient) GetNodeState(nodes ...*SnowthNode) (*NodeState, error) {
-	return sc.GetNodeStateContext(context.Background(), nodes...)
+	return sc.GetNodeStateContext(context.Background(), nodeOptions(nodes)...)
 }
 
 // GetNodeStateContext is the context aware version of GetNodeState.
 func (sc *SnowthClient) GetNodeStateContext(ctx context.Context,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*NodeState, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	r := &NodeState{}
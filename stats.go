@@ -7,22 +7,22 @@ import (
 
 // GetStats retrieves the metrics about the status of an IRONdb node.
 func (sc *SnowthClient) GetStats(nodes ...*SnowthNode) (*Stats, error) {
-	return sc.GetStatsContext(context.Background(), nodes...)
+	return sc.GetStatsContext(context.Background(), nodeOptions(nodes)...)
 }
 
 // GetStatsContext is the context aware version of GetStats.
 func (sc *SnowthClient) GetStatsContext(ctx context.Context,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*Stats, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	r := &Stats{}
@@ -36,13 +36,28 @@ func (sc *SnowthClient) GetStatsContext(ctx context.Context,
 		return nil, fmt.Errorf("unable to decode IRONdb response: %w", err)
 	}
 
+	sc.setNextTopology(r.NextTopology())
+
 	return r, nil
 }
 
 // GetStatsNodeContext gets the status metrics for a single specified node.
 func (sc *SnowthClient) GetStatsNodeContext(ctx context.Context,
-	node *SnowthNode,
+	node *SnowthNode, opts ...CallOption,
 ) (*Stats, error) {
+	ctx, n, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if n != nil {
+		node = n
+	}
+
+	if err := sc.beginRequest(); err != nil {
+		return nil, err
+	}
+
+	defer sc.endRequest()
+
 	r := &Stats{}
 
 	body, _, _, err := sc.do(ctx, node, "GET", "/stats.json", nil, nil, "")
@@ -54,6 +69,8 @@ func (sc *SnowthClient) GetStatsNodeContext(ctx context.Context,
 		return nil, fmt.Errorf("unable to decode IRONdb response: %w", err)
 	}
 
+	sc.setNextTopology(r.NextTopology())
+
 	return r, nil
 }
 
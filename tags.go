@@ -199,23 +199,23 @@ func (sc *SnowthClient) FindTags(accountID int64, query string,
 	options *FindTagsOptions, nodes ...*SnowthNode,
 ) (*FindTagsResult, error) {
 	return sc.FindTagsContext(context.Background(), accountID, query,
-		options, nodes...)
+		options, nodeOptions(nodes)...)
 }
 
 // FindTagsContext is the context aware version of FindTags.
 func (sc *SnowthClient) FindTagsContext(ctx context.Context, accountID int64,
 	query string, options *FindTagsOptions,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*FindTagsResult, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	u := fmt.Sprintf("%s?query=%s",
@@ -306,22 +306,22 @@ func (sc *SnowthClient) FindTagCats(accountID int64, query string,
 	nodes ...*SnowthNode,
 ) ([]string, error) {
 	return sc.FindTagCatsContext(context.Background(), accountID, query,
-		nodes...)
+		nodeOptions(nodes)...)
 }
 
 // FindTagCatsContext is the context aware version of FindTagCats.
 func (sc *SnowthClient) FindTagCatsContext(ctx context.Context,
-	accountID int64, query string, nodes ...*SnowthNode,
+	accountID int64, query string, opts ...CallOption,
 ) ([]string, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	u := fmt.Sprintf("%s?query=%s",
@@ -348,23 +348,23 @@ func (sc *SnowthClient) FindTagVals(accountID int64, query, category string,
 	nodes ...*SnowthNode,
 ) ([]string, error) {
 	return sc.FindTagValsContext(context.Background(), accountID, query,
-		category, nodes...)
+		category, nodeOptions(nodes)...)
 }
 
 // FindTagValsContext is the context aware version of FindTagVals.
 func (sc *SnowthClient) FindTagValsContext(ctx context.Context,
 	accountID int64, query, category string,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) ([]string, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	u := fmt.Sprintf("%s?query=%s&category=%s",
@@ -398,26 +398,26 @@ type ModifyTags struct {
 func (sc *SnowthClient) GetCheckTags(checkUUID string,
 	nodes ...*SnowthNode,
 ) (CheckTags, error) {
-	return sc.GetCheckTagsContext(context.Background(), checkUUID, nodes...)
+	return sc.GetCheckTagsContext(context.Background(), checkUUID, nodeOptions(nodes)...)
 }
 
 // GetCheckTagsContext is the context aware version of GetCheckTags.
 func (sc *SnowthClient) GetCheckTagsContext(ctx context.Context,
-	checkUUID string, nodes ...*SnowthNode,
+	checkUUID string, opts ...CallOption,
 ) (CheckTags, error) {
 	if _, err := uuid.Parse(checkUUID); err != nil {
 		return nil, fmt.Errorf("invalid check uuid: %w", err)
 	}
 
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	u := fmt.Sprintf("/meta/check/tag/%s", checkUUID)
@@ -440,26 +440,26 @@ func (sc *SnowthClient) GetCheckTagsContext(ctx context.Context,
 func (sc *SnowthClient) DeleteCheckTags(checkUUID string,
 	nodes ...*SnowthNode,
 ) error {
-	return sc.DeleteCheckTagsContext(context.Background(), checkUUID, nodes...)
+	return sc.DeleteCheckTagsContext(context.Background(), checkUUID, nodeOptions(nodes)...)
 }
 
 // DeleteCheckTagsContext is the context aware version of DeleteCheckTags.
 func (sc *SnowthClient) DeleteCheckTagsContext(ctx context.Context,
-	checkUUID string, nodes ...*SnowthNode,
+	checkUUID string, opts ...CallOption,
 ) error {
 	if _, err := uuid.Parse(checkUUID); err != nil {
 		return fmt.Errorf("invalid check uuid: %w", err)
 	}
 
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return fmt.Errorf("unable to get active node")
+		return ErrNoActiveNode
 	}
 
 	u := fmt.Sprintf("/meta/check/tag/%s", checkUUID)
@@ -480,26 +480,26 @@ func (sc *SnowthClient) UpdateCheckTags(checkUUID string,
 	tags []string, nodes ...*SnowthNode,
 ) (int64, error) {
 	return sc.UpdateCheckTagsContext(context.Background(), checkUUID, tags,
-		nodes...)
+		nodeOptions(nodes)...)
 }
 
 // UpdateCheckTagsContext is the context aware version of UpdateCheckTags.
 func (sc *SnowthClient) UpdateCheckTagsContext(ctx context.Context,
-	checkUUID string, tags []string, nodes ...*SnowthNode,
+	checkUUID string, tags []string, opts ...CallOption,
 ) (int64, error) {
 	if _, err := uuid.Parse(checkUUID); err != nil {
 		return 0, fmt.Errorf("invalid check uuid: %w", err)
 	}
 
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return 0, fmt.Errorf("unable to get active node")
+		return 0, ErrNoActiveNode
 	}
 
 	old, err := sc.GetCheckTagsContext(ctx, checkUUID, node)
@@ -50,30 +50,34 @@ func (sc *SnowthClient) ReadTextValues(uuid, metric string,
 	start, end time.Time, nodes ...*SnowthNode,
 ) ([]TextValue, error) {
 	return sc.ReadTextValuesContext(context.Background(), uuid, metric,
-		start, end, nodes...)
+		start, end, nodeOptions(nodes)...)
 }
 
 // ReadTextValuesContext is the context aware version of ReadTextValues.
 func (sc *SnowthClient) ReadTextValuesContext(ctx context.Context,
 	uuid, metric string, start, end time.Time,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) ([]TextValue, error) {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(uuid, metric))
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	var owners []string
+
+	if node == nil {
+		owners = sc.FindMetricNodeIDs(uuid, metric)
+		node = sc.GetActiveNode(owners)
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	r := TextValueResponse{}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "GET", path.Join("/read",
-		strconv.FormatInt(start.Unix(), 10),
-		strconv.FormatInt(end.Unix(), 10), uuid, metric), nil, nil)
+	body, _, err := sc.hedgedRequestContext(ctx, node, owners, "GET",
+		path.Join("/read",
+			strconv.FormatInt(start.Unix(), 10),
+			strconv.FormatInt(end.Unix(), 10), uuid, metric), nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -95,31 +99,35 @@ type TextData struct {
 
 // WriteText writes text data to an IRONdb node.
 func (sc *SnowthClient) WriteText(data []TextData, nodes ...*SnowthNode) error {
-	return sc.WriteTextContext(context.Background(), data, nodes...)
+	return sc.WriteTextContext(context.Background(), data, nodeOptions(nodes)...)
 }
 
-// WriteTextContext is the context aware version of WriteText.
+// WriteTextContext is the context aware version of WriteText. Unless a node
+// is set by the call options, the data is split into batches by the nodes
+// owning each metric, which are sent in parallel. If any batch fails, a
+// *WriteError containing the result of each batch is returned.
 func (sc *SnowthClient) WriteTextContext(ctx context.Context,
-	data []TextData, nodes ...*SnowthNode,
+	data []TextData, opts ...CallOption,
 ) error {
-	var node *SnowthNode
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else if len(data) > 0 {
-		node = sc.GetActiveNode(sc.FindMetricNodeIDs(data[0].ID,
-			data[0].Metric))
-	}
-
-	if node == nil {
-		return fmt.Errorf("unable to get active node")
-	}
-
-	buf := new(bytes.Buffer)
-	if err := json.NewEncoder(buf).Encode(data); err != nil {
-		return fmt.Errorf("failed to encode TextData for write: %w", err)
-	}
-
-	_, _, err := sc.DoRequestContext(ctx, node, "POST", "/write/text", buf, nil)
-
-	return err
+	return sc.shardWriteContext(ctx, opts, len(data),
+		func(i int) (string, string) {
+			return data[i].ID, data[i].Metric
+		},
+		func(ctx context.Context, node *SnowthNode, idx []int) error {
+			batch := make([]TextData, len(idx))
+			for i, j := range idx {
+				batch[i] = data[j]
+			}
+
+			buf := new(bytes.Buffer)
+			if err := json.NewEncoder(buf).Encode(batch); err != nil {
+				return fmt.Errorf("failed to encode TextData for write: %w",
+					err)
+			}
+
+			_, _, err := sc.DoRequestContext(ctx, node, "POST", "/write/text",
+				buf, nil)
+
+			return err
+		})
 }
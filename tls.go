@@ -0,0 +1,148 @@
+package gosnowth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Scheme policy values used by TLSConfig to determine the URL scheme used to
+// connect with IRONdb nodes.
+const (
+	// SchemePolicyInherit causes nodes discovered through the cluster
+	// topology to use the same scheme as the seed servers.
+	SchemePolicyInherit = "inherit"
+
+	// SchemePolicyHTTP causes all nodes to be contacted using plain HTTP.
+	SchemePolicyHTTP = "http"
+
+	// SchemePolicyHTTPS causes all nodes to be contacted using HTTPS.
+	SchemePolicyHTTPS = "https"
+)
+
+// TLSConfig values represent the TLS settings used by SnowthClient values
+// when connecting to IRONdb nodes. These settings apply to both the seed
+// servers and any nodes discovered through the cluster topology.
+type TLSConfig struct {
+	// CAFile is the path to a PEM format CA bundle used to verify the
+	// certificates presented by IRONdb nodes. If empty, the system roots
+	// are used.
+	CAFile string `json:"ca_file,omitempty"`
+
+	// CertFile and KeyFile are the paths to a PEM format client certificate
+	// and private key. When both are set, the certificate is presented to
+	// IRONdb nodes requiring mutual TLS.
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+
+	// ServerName overrides the name used to verify node certificates. This
+	// is needed when nodes are discovered by IP address through the
+	// topology, or from server host names resolving to several addresses,
+	// but present certificates issued for a shared name.
+	ServerName string `json:"server_name,omitempty"`
+
+	// InsecureSkipVerify disables verification of node certificates.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+
+	// SchemePolicy determines the scheme used for nodes. It can be one of
+	// SchemePolicyInherit (the default), SchemePolicyHTTP or
+	// SchemePolicyHTTPS.
+	SchemePolicy string `json:"scheme_policy,omitempty"`
+}
+
+// ClientConfig creates a *tls.Config value from the TLS configuration. A nil
+// TLSConfig returns a nil *tls.Config and no error.
+func (tc *TLSConfig) ClientConfig() (*tls.Config, error) {
+	if tc == nil {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         tc.ServerName,
+		InsecureSkipVerify: tc.InsecureSkipVerify, //nolint:gosec
+	}
+
+	if tc.CAFile != "" {
+		b, err := os.ReadFile(tc.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file %s: %w",
+				tc.CAFile, err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no valid certificates found in CA file: %s",
+				tc.CAFile)
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	if tc.CertFile != "" || tc.KeyFile != "" {
+		if tc.CertFile == "" || tc.KeyFile == "" {
+			return nil, fmt.Errorf("both a client certificate and key " +
+				"must be provided")
+		}
+
+		cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %w",
+				err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// nodeScheme returns the scheme that should be used to connect to nodes,
+// based on the scheme policy and the list of seed servers.
+func (tc *TLSConfig) nodeScheme(servers []string) (string, error) {
+	policy := SchemePolicyInherit
+	if tc != nil && tc.SchemePolicy != "" {
+		policy = strings.ToLower(tc.SchemePolicy)
+	}
+
+	switch policy {
+	case SchemePolicyHTTP, SchemePolicyHTTPS:
+		return policy, nil
+	case SchemePolicyInherit:
+	default:
+		return "", fmt.Errorf("invalid scheme policy: %s", policy)
+	}
+
+	for _, addr := range servers {
+		u, err := url.Parse(addr)
+		if err != nil {
+			continue
+		}
+
+		scheme := strings.TrimPrefix(strings.ToLower(u.Scheme),
+			SchemePrefixSRV)
+		if scheme == SchemePolicyHTTPS {
+			return SchemePolicyHTTPS, nil
+		}
+	}
+
+	return SchemePolicyHTTP, nil
+}
+
+// forcesScheme returns whether the scheme policy overrides the scheme of the
+// seed servers.
+func (tc *TLSConfig) forcesScheme() bool {
+	if tc == nil {
+		return false
+	}
+
+	switch strings.ToLower(tc.SchemePolicy) {
+	case SchemePolicyHTTP, SchemePolicyHTTPS:
+		return true
+	}
+
+	return false
+}
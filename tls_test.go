@@ -0,0 +1,263 @@
+package gosnowth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func writeTestPEM(t *testing.T, name, typ string, b []byte) string {
+	t.Helper()
+
+	fn := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(fn, pem.EncodeToMemory(&pem.Block{
+		Type:  typ,
+		Bytes: b,
+	}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return fn
+}
+
+func createTestClientCert(t *testing.T) (*x509.Certificate, string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gosnowth-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl,
+		&key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kb, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, writeTestPEM(t, "client.crt", "CERTIFICATE", der),
+		writeTestPEM(t, "client.key", "EC PRIVATE KEY", kb)
+}
+
+func TestTLSConfigClientConfig(t *testing.T) {
+	t.Parallel()
+
+	var tc *TLSConfig
+
+	cfg, err := tc.ClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg != nil {
+		t.Errorf("Expected nil TLS config, got: %+v", cfg)
+	}
+
+	tc = &TLSConfig{ServerName: "test"}
+
+	cfg, err = tc.ClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.ServerName != "test" {
+		t.Errorf("Expected server name: test, got: %v", cfg.ServerName)
+	}
+
+	tc = &TLSConfig{CertFile: "client.crt"}
+
+	if _, err = tc.ClientConfig(); err == nil {
+		t.Error("Expected error for missing key file")
+	}
+
+	tc = &TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}
+
+	if _, err = tc.ClientConfig(); err == nil {
+		t.Error("Expected error for missing CA file")
+	}
+}
+
+func TestTLSConfigNodeScheme(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		tc      *TLSConfig
+		servers []string
+		exp     string
+		err     bool
+	}{
+		{"nil http", nil, []string{"http://localhost:8112"}, "http", false},
+		{"nil https", nil, []string{"https://localhost:8112"}, "https", false},
+		{
+			"srv https", nil, []string{"srv+https://_irondb._tcp.example"},
+			"https", false,
+		},
+		{
+			"inherit", &TLSConfig{SchemePolicy: SchemePolicyInherit},
+			[]string{"https://localhost:8112"}, "https", false,
+		},
+		{
+			"force https", &TLSConfig{SchemePolicy: SchemePolicyHTTPS},
+			[]string{"http://localhost:8112"}, "https", false,
+		},
+		{
+			"force http", &TLSConfig{SchemePolicy: "HTTP"},
+			[]string{"https://localhost:8112"}, "http", false,
+		},
+		{"invalid", &TLSConfig{SchemePolicy: "ftp"}, nil, "", true},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := test.tc.nodeScheme(test.servers)
+			if test.err {
+				if err == nil {
+					t.Error("Expected error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s != test.exp {
+				t.Errorf("Expected scheme: %v, got: %v", test.exp, s)
+			}
+		})
+	}
+}
+
+func TestNewClientMutualTLS(t *testing.T) {
+	t.Parallel()
+
+	clientCert, certFile, keyFile := createTestClientCert(t)
+
+	ms := httptest.NewUnstartedServer(http.HandlerFunc(func(
+		w http.ResponseWriter, r *http.Request,
+	) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCert)
+
+	ms.TLS = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+
+	ms.StartTLS()
+	defer ms.Close()
+
+	caFile := writeTestPEM(t, "ca.crt", "CERTIFICATE", ms.Certificate().Raw)
+
+	u, err := url.Parse(ms.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	cfg := NewConfig(ms.URL)
+	cfg.TLS = &TLSConfig{
+		CAFile:   caFile,
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}
+
+	sc, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	if _, err = sc.GetStats(); err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := strconv.ParseUint(u.Port(), 10, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc.Lock()
+	sc.activeNodes = []*SnowthNode{}
+	sc.Unlock()
+
+	sc.populateNodeInfo(context.Background(), "test", TopologyNode{
+		ID:      "bb6f7162-4828-11df-bab8-6bac200dcc2a",
+		Address: u.Hostname(),
+		APIPort: uint16(port),
+	})
+
+	nodes := sc.ListActiveNodes()
+	if len(nodes) != 1 {
+		t.Fatalf("Expected active nodes: 1, got: %v", len(nodes))
+	}
+
+	if nodes[0].GetURL().Scheme != "https" {
+		t.Errorf("Expected scheme: https, got: %v", nodes[0].GetURL().Scheme)
+	}
+
+	cfg = NewConfig(ms.URL)
+	cfg.TLS = &TLSConfig{CAFile: caFile}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if _, err = NewClient(ctx, cfg); err == nil {
+		t.Error("Expected error connecting without a client certificate")
+	}
+}
@@ -0,0 +1,253 @@
+package gosnowth
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// topologyRetryInterval is the minimum time between background attempts to
+// retrieve a topology which could not be retrieved.
+const topologyRetryInterval = time.Second
+
+// TopologyCacheStatus values describe the state of the compiled topology
+// cached by a SnowthClient.
+type TopologyCacheStatus struct {
+	// Hash is the hash of the cached topology. It may differ from the
+	// current cluster topology while a new topology is being retrieved.
+	Hash string
+
+	// Version is incremented each time the cached topology is replaced. It
+	// is zero when no topology has been cached.
+	Version uint64
+
+	// Refreshed is the time the cached topology was last replaced.
+	Refreshed time.Time
+
+	// Pending is the hash of a topology being retrieved in the background,
+	// if any.
+	Pending string
+}
+
+// topologyEntry values contain a compiled topology stored in the cache. They
+// are never modified once stored.
+type topologyEntry struct {
+	hash      string
+	topo      *Topology
+	version   uint64
+	refreshed time.Time
+}
+
+// topologyCache values contain the last good compiled topology retrieved by
+// a client. The entry is replaced atomically, so lookups never block while a
+// new topology is retrieved.
+type topologyCache struct {
+	entry atomic.Value
+
+	mu       sync.Mutex
+	pending  string
+	queued   string
+	failed   string
+	failedAt time.Time
+}
+
+// load returns the cached entry, or nil if no topology has been cached.
+func (tc *topologyCache) load() *topologyEntry {
+	e, _ := tc.entry.Load().(*topologyEntry)
+
+	return e
+}
+
+// get returns the cached topology if it has the provided hash.
+func (tc *topologyCache) get(hash string) *Topology {
+	if e := tc.load(); e != nil && e.hash == hash {
+		return e.topo
+	}
+
+	return nil
+}
+
+// store replaces the cached topology.
+func (tc *topologyCache) store(hash string, topo *Topology) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	version := uint64(1)
+	if e := tc.load(); e != nil {
+		version = e.version + 1
+	}
+
+	tc.entry.Store(&topologyEntry{
+		hash:      hash,
+		topo:      topo,
+		version:   version,
+		refreshed: time.Now(),
+	})
+}
+
+// begin reports whether a background retrieval of the topology with the
+// provided hash should be started, and marks it as pending if so. If another
+// topology is being retrieved, the hash is queued to be retrieved next,
+// replacing any hash queued before it.
+func (tc *topologyCache) begin(hash string) bool {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if tc.pending == hash || tc.get(hash) != nil {
+		return false
+	}
+
+	if tc.pending != "" {
+		tc.queued = hash
+
+		return false
+	}
+
+	if tc.failed == hash && time.Since(tc.failedAt) < topologyRetryInterval {
+		return false
+	}
+
+	tc.pending = hash
+
+	return true
+}
+
+// end records the result of a background retrieval, and returns the hash
+// queued to be retrieved next, if any.
+func (tc *topologyCache) end(hash string, err error) string {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tc.pending = ""
+
+	if err != nil {
+		tc.failed, tc.failedAt = hash, time.Now()
+	}
+
+	queued := tc.queued
+	tc.queued = ""
+
+	if queued == hash {
+		return ""
+	}
+
+	return queued
+}
+
+// TopologyCacheStatus returns the state of the compiled topology cached by the
+// client.
+func (sc *SnowthClient) TopologyCacheStatus() TopologyCacheStatus {
+	sc.topology.mu.Lock()
+	pending := sc.topology.pending
+	sc.topology.mu.Unlock()
+
+	s := TopologyCacheStatus{Pending: pending}
+
+	if e := sc.topology.load(); e != nil {
+		s.Hash, s.Version, s.Refreshed = e.hash, e.version, e.refreshed
+	}
+
+	return s
+}
+
+// fetchTopology retrieves and compiles the topology with the provided hash
+// from a node.
+func (sc *SnowthClient) fetchTopology(ctx context.Context, node *SnowthNode,
+	hash string,
+) (*Topology, error) {
+	r := &Topology{}
+
+	body, _, err := sc.DoRequestContext(ctx, node, "GET",
+		path.Join("/topology/xml", hash), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decodeXML(body, &r); err != nil {
+		return nil, fmt.Errorf("unable to decode IRONdb response: %w", err)
+	}
+
+	if err = r.compile(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// refreshTopology retrieves the topology with the provided hash in the
+// background, if it is not already cached or being retrieved, and replaces
+// the cached topology with it if it is still the current topology. Lookups
+// continue to use the previously cached topology until then. A hash requested
+// while another is being retrieved is retrieved next. If node is nil, an
+// active node is used.
+func (sc *SnowthClient) refreshTopology(node *SnowthNode, hash string) {
+	if hash == "" || !sc.topology.begin(hash) {
+		return
+	}
+
+	if !sc.startBackground() {
+		sc.topology.end(hash, nil)
+
+		return
+	}
+
+	sc.RLock()
+	to := sc.timeout
+	sc.RUnlock()
+
+	if to <= 0 {
+		to = 10 * time.Second
+	}
+
+	go func() {
+		var err error
+
+		defer func() {
+			next := sc.topology.end(hash, err)
+
+			sc.endBackground()
+
+			if next != "" {
+				sc.refreshTopology(nil, next)
+			}
+		}()
+
+		ctx, cancel := sc.backgroundContext(context.Background())
+		defer cancel()
+
+		ctx, tCancel := context.WithTimeout(ctx, to)
+		defer tCancel()
+
+		n := node
+		if n == nil {
+			n = sc.GetActiveNode()
+		}
+
+		if n == nil {
+			err = ErrNoActiveNode
+
+			return
+		}
+
+		var topo *Topology
+
+		if topo, err = sc.fetchTopology(ctx, n, hash); err != nil {
+			sc.LogWarnf("unable to retrieve topology %s: %s", hash,
+				err.Error())
+
+			return
+		}
+
+		sc.RLock()
+		current := sc.currentTopology
+		sc.RUnlock()
+
+		if current == hash {
+			sc.topology.store(hash, topo)
+			sc.LogDebugf("updated topology: %s", hash)
+		}
+	}()
+}
@@ -0,0 +1,189 @@
+package gosnowth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTopologyCache(t *testing.T) {
+	t.Parallel()
+
+	oldTopo, err := TopologyLoadXML(topologyXMLTestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newXML := strings.Replace(topologyXMLTestData, `weight="51"`,
+		`weight="50"`, 1)
+
+	newTopo, err := TopologyLoadXML(newXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lastXML := strings.Replace(topologyXMLTestData, `weight="51"`,
+		`weight="49"`, 1)
+
+	lastTopo, err := TopologyLoadXML(lastXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := `{"identity":{"_type":"s","_value":"test"},` +
+		`"semver":{"_type":"s","_value":"1.0.0"},` +
+		`"topology":{"current":{"_type":"s","_value":"` + oldTopo.Hash +
+		`"},"next":{"_type":"s","_value":"-"}}}`
+
+	var (
+		mu      sync.Mutex
+		stateID = newTopo.Hash
+	)
+
+	release := make(chan struct{})
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		switch r.RequestURI {
+		case "/stats.json":
+			_, _ = w.Write([]byte(stats))
+		case "/topology/xml/" + oldTopo.Hash:
+			_, _ = w.Write([]byte(topologyXMLTestData))
+		case "/topology/xml/" + newTopo.Hash:
+			<-release
+			_, _ = w.Write([]byte(newXML))
+		case "/topology/xml/" + lastTopo.Hash:
+			_, _ = w.Write([]byte(lastXML))
+		case "/state":
+			mu.Lock()
+			w.Header().Set("X-Topo-0", stateID)
+			mu.Unlock()
+			_, _ = w.Write([]byte(stateTestData))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer ms.Close()
+
+	sc, err := NewClient(context.Background(),
+		&Config{Servers: []string{ms.URL}})
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	u, err := url.Parse(ms.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	node := &SnowthNode{url: u, currentTopology: oldTopo.Hash}
+
+	if s := sc.TopologyCacheStatus(); s.Version != 0 {
+		t.Errorf("Expected version: 0, got: %v", s.Version)
+	}
+
+	if _, err := sc.GetTopologyInfo(node); err != nil {
+		t.Fatal(err)
+	}
+
+	s := sc.TopologyCacheStatus()
+	if s.Hash != oldTopo.Hash || s.Version != 1 || s.Refreshed.IsZero() {
+		t.Errorf("Expected cached topology: %v, got: %+v", oldTopo.Hash, s)
+	}
+
+	if _, err := sc.GetNodeState(node); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := sc.TopologyCacheStatus(); s.Pending != newTopo.Hash {
+		t.Errorf("Expected pending topology: %v, got: %v", newTopo.Hash,
+			s.Pending)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			topo, err := sc.Topology()
+			if err != nil || topo.Hash != oldTopo.Hash {
+				t.Errorf("Expected last good topology: %v, got: %v, %v",
+					oldTopo.Hash, topo, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// A topology change seen while a topology is being retrieved is queued.
+	mu.Lock()
+	stateID = lastTopo.Hash
+	mu.Unlock()
+
+	if _, err := sc.GetNodeState(node); err != nil {
+		t.Fatal(err)
+	}
+
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+
+	for sc.TopologyCacheStatus().Hash != lastTopo.Hash {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected cached topology: %v, got: %+v", lastTopo.Hash,
+				sc.TopologyCacheStatus())
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	s = sc.TopologyCacheStatus()
+	if s.Version != 2 || s.Pending != "" {
+		t.Errorf("Expected version: 2 with none pending, got: %+v", s)
+	}
+
+	topo, err := sc.Topology()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if topo.Hash != lastTopo.Hash {
+		t.Errorf("Expected topology: %v, got: %v", lastTopo.Hash, topo.Hash)
+	}
+
+	// Retrieving the topology of a node which has not seen the current
+	// topology does not replace it.
+	oldNode := &SnowthNode{url: u, currentTopology: oldTopo.Hash}
+
+	if topo, err = sc.GetTopologyInfo(oldNode); err != nil {
+		t.Fatal(err)
+	}
+
+	if topo.Hash != oldTopo.Hash {
+		t.Errorf("Expected topology: %v, got: %v", oldTopo.Hash, topo.Hash)
+	}
+
+	sc.RLock()
+	current := sc.currentTopology
+	sc.RUnlock()
+
+	if s := sc.TopologyCacheStatus(); current != lastTopo.Hash ||
+		s.Hash != lastTopo.Hash || s.Version != 2 {
+		t.Errorf("Expected current topology: %v, got: %v, %+v",
+			lastTopo.Hash, current, s)
+	}
+
+	if err := sc.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -336,7 +336,7 @@ func (sc *SnowthClient) GetTopologyInfo(nodes ...*SnowthNode) (*Topology, error)
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	return sc.GetTopologyInfoContext(context.Background(), node)
@@ -359,20 +359,17 @@ func TopologyLoadXML(xml string) (*Topology, error) {
 
 // GetTopologyInfoContext is the context aware version of GetTopologyInfo.
 func (sc *SnowthClient) GetTopologyInfoContext(ctx context.Context,
-	nodes ...*SnowthNode,
+	opts ...CallOption,
 ) (*Topology, error) {
-	r := &Topology{}
-
-	var node *SnowthNode
+	ctx, node, cancel := sc.callContext(ctx, opts)
+	defer cancel()
 
-	if len(nodes) > 0 && nodes[0] != nil {
-		node = nodes[0]
-	} else {
+	if node == nil {
 		node = sc.GetActiveNode()
 	}
 
 	if node == nil {
-		return nil, fmt.Errorf("unable to get active node")
+		return nil, ErrNoActiveNode
 	}
 
 	topologyID := node.GetCurrentTopology()
@@ -380,26 +377,34 @@ func (sc *SnowthClient) GetTopologyInfoContext(ctx context.Context,
 		return nil, fmt.Errorf("no active topology")
 	}
 
-	if topologyID == sc.currentTopology && sc.currentTopologyCompiled != nil {
-		return sc.currentTopologyCompiled, nil
+	if t := sc.topology.get(topologyID); t != nil {
+		return t, nil
 	}
 
-	body, _, err := sc.DoRequestContext(ctx, node, "GET",
-		path.Join("/topology/xml", node.GetCurrentTopology()), nil, nil)
+	r, err := sc.fetchTopology(ctx, node, topologyID)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := decodeXML(body, &r); err != nil {
-		return nil, fmt.Errorf("unable to decode IRONdb response: %w", err)
-	}
+	// The node may not have seen the current cluster topology yet, so the
+	// retrieved topology is only cached if it is the current topology, or
+	// becomes it when no topology is known.
+	sc.Lock()
 
-	if err = r.compile(); err != nil {
-		return nil, err
+	oldTopo := sc.currentTopology
+	if oldTopo == "" {
+		sc.currentTopology = topologyID
 	}
 
-	sc.currentTopology = topologyID
-	sc.currentTopologyCompiled = r
+	current := sc.currentTopology
+
+	sc.Unlock()
+
+	sc.emit(topologyEvent(node, oldTopo, current)...)
+
+	if current == topologyID {
+		sc.topology.store(topologyID, r)
+	}
 
 	return r, nil
 }
@@ -416,7 +421,7 @@ func (sc *SnowthClient) LoadTopology(hash string, t *Topology,
 	}
 
 	if node == nil {
-		return fmt.Errorf("unable to get active node")
+		return ErrNoActiveNode
 	}
 
 	return sc.LoadTopologyContext(context.Background(), hash, t, node)
@@ -424,8 +429,15 @@ func (sc *SnowthClient) LoadTopology(hash string, t *Topology,
 
 // LoadTopologyContext is the context aware version of LoadTopology.
 func (sc *SnowthClient) LoadTopologyContext(ctx context.Context, hash string,
-	t *Topology, node *SnowthNode,
+	t *Topology, node *SnowthNode, opts ...CallOption,
 ) error {
+	ctx, n, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if n != nil {
+		node = n
+	}
+
 	b, err := encodeXML(t)
 	if err != nil {
 		return fmt.Errorf("failed to encode request data: %w", err)
@@ -446,8 +458,15 @@ func (sc *SnowthClient) ActivateTopology(hash string, node *SnowthNode) error {
 // ActivateTopologyContext is the context aware version of ActivateTopology.
 // WARNING THIS IS DANGEROUS.
 func (sc *SnowthClient) ActivateTopologyContext(ctx context.Context,
-	hash string, node *SnowthNode,
+	hash string, node *SnowthNode, opts ...CallOption,
 ) error {
+	ctx, n, cancel := sc.callContext(ctx, opts)
+	defer cancel()
+
+	if n != nil {
+		node = n
+	}
+
 	_, _, err := sc.DoRequestContext(ctx, node, "GET",
 		path.Join("/activate", hash), nil, nil)
 
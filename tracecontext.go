@@ -0,0 +1,207 @@
+package gosnowth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// W3C trace context header names.
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// TraceFlagSampled is the trace context flag indicating that the caller may
+// have recorded the trace.
+const TraceFlagSampled byte = 0x01
+
+// TraceContext values contain a W3C trace context, identifying the span in a
+// distributed trace from which requests to IRONdb are made. When a trace
+// context is present in the context passed to a SnowthClient, requests are
+// sent with traceparent and tracestate headers identifying a new child span
+// for each request attempt.
+type TraceContext struct {
+	// TraceID is the 32 character hexadecimal trace ID.
+	TraceID string
+
+	// SpanID is the 16 character hexadecimal ID of the parent span.
+	SpanID string
+
+	// Flags contains the trace flags, such as TraceFlagSampled.
+	Flags byte
+
+	// State is the vendor specific tracestate header value, if any.
+	State string
+}
+
+// traceCtxKey is the context key used to store trace context values.
+type traceCtxKey struct{}
+
+// ContextWithTraceContext returns a copy of a context containing a trace
+// context, which will be propagated to IRONdb requests made with it.
+func ContextWithTraceContext(ctx context.Context,
+	tc TraceContext,
+) context.Context {
+	return context.WithValue(ctx, traceCtxKey{}, tc)
+}
+
+// TraceContextFromContext retrieves a trace context stored in a context by
+// ContextWithTraceContext().
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceCtxKey{}).(TraceContext)
+
+	return tc, ok
+}
+
+// TraceContextFromHeader retrieves a trace context from the traceparent and
+// tracestate headers of an incoming HTTP request.
+func TraceContextFromHeader(h http.Header) (TraceContext, error) {
+	tc, err := ParseTraceParent(h.Get(TraceParentHeader))
+	if err != nil {
+		return tc, err
+	}
+
+	tc.State = strings.Join(h.Values(TraceStateHeader), ",")
+
+	return tc, nil
+}
+
+// ParseTraceParent parses a W3C traceparent header value.
+func ParseTraceParent(s string) (TraceContext, error) {
+	tc := TraceContext{}
+
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		(parts[0] == "00" && len(parts) != 4) {
+		return tc, fmt.Errorf("invalid traceparent: %s", s)
+	}
+
+	if _, err := hex.DecodeString(parts[0]); err != nil {
+		return tc, fmt.Errorf("invalid traceparent version: %s", s)
+	}
+
+	tc.TraceID = parts[1]
+	tc.SpanID = parts[2]
+
+	if !tc.Valid() {
+		return TraceContext{}, fmt.Errorf("invalid traceparent IDs: %s", s)
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return TraceContext{}, fmt.Errorf("invalid traceparent flags: %s", s)
+	}
+
+	tc.Flags = flags[0]
+
+	return tc, nil
+}
+
+// Valid reports whether the trace context contains valid, non-zero trace and
+// span IDs.
+func (tc TraceContext) Valid() bool {
+	return validTraceID(tc.TraceID, 32) && validTraceID(tc.SpanID, 16)
+}
+
+// TraceParent returns the W3C traceparent header value for the trace context.
+func (tc TraceContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+// validTraceID reports whether a string is a non-zero lowercase hexadecimal
+// ID of the specified length.
+func validTraceID(id string, n int) bool {
+	if len(id) != n || strings.Trim(id, "0") == "" {
+		return false
+	}
+
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newTraceID returns a random hexadecimal ID of n bytes.
+func newTraceID(n int) string {
+	b := make([]byte, n)
+
+	if _, err := rand.Read(b); err != nil {
+		binary.BigEndian.PutUint64(b[n-8:], uint64(time.Now().UnixNano()))
+	}
+
+	if id := hex.EncodeToString(b); strings.Trim(id, "0") != "" {
+		return id
+	}
+
+	return newTraceID(n)
+}
+
+// requestTraceContext returns the trace context for a request. The trace
+// context is taken from the request context, or from the TraceContextFunc
+// of the client, if either are present. Otherwise a new trace is started,
+// without a parent span, so that the request attempts are its root spans.
+func (sc *SnowthClient) requestTraceContext(ctx context.Context) TraceContext {
+	if tc, ok := TraceContextFromContext(ctx); ok && tc.Valid() {
+		return tc
+	}
+
+	sc.RLock()
+	f := sc.traceContextFunc
+	sc.RUnlock()
+
+	if f != nil {
+		if tc, ok := f(ctx); ok && tc.Valid() {
+			return tc
+		}
+	}
+
+	return TraceContext{TraceID: newTraceID(16)}
+}
+
+// traceHeaders returns a copy of request headers with trace context headers
+// identifying a child span of the trace context. Trace context headers
+// already present in the request headers are not replaced.
+func traceHeaders(headers http.Header, tc TraceContext,
+	spanID string,
+) http.Header {
+	h := headers.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+
+	if h.Get(TraceParentHeader) != "" {
+		return h
+	}
+
+	child := tc
+	child.SpanID = spanID
+
+	h.Set(TraceParentHeader, child.TraceParent())
+
+	if tc.State != "" {
+		h.Set(TraceStateHeader, tc.State)
+	}
+
+	return h
+}
+
+// SetTraceContextFunc sets a function used to retrieve the trace context from
+// the contexts passed to SnowthClient methods. This allows trace contexts
+// created by tracing libraries, such as OpenTelemetry, to be propagated to
+// IRONdb requests.
+func (sc *SnowthClient) SetTraceContextFunc(
+	f func(ctx context.Context) (TraceContext, bool),
+) {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.traceContextFunc = f
+}
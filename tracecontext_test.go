@@ -0,0 +1,250 @@
+package gosnowth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in    string
+		valid bool
+	}{
+		{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", true},
+		{"01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00-x", true},
+		{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-x", false},
+		{"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", false},
+		{"00-00000000000000000000000000000000-b7ad6b7169203331-01", false},
+		{"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01", false},
+		{"00-0AF7651916CD43DD8448EB211C80319C-b7ad6b7169203331-01", false},
+		{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-zz", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		tc, err := ParseTraceParent(tt.in)
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected valid %s: %v, got error: %v", tt.in,
+				tt.valid, err)
+		}
+
+		if err == nil && !strings.HasPrefix(tt.in, "00") {
+			continue
+		}
+
+		if err == nil && tc.TraceParent() != tt.in {
+			t.Errorf("Expected traceparent: %v, got: %v", tt.in,
+				tc.TraceParent())
+		}
+	}
+
+	h := http.Header{}
+	h.Set(TraceParentHeader,
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	h.Add(TraceStateHeader, "a=1")
+	h.Add(TraceStateHeader, "b=2")
+
+	tc, err := TraceContextFromHeader(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tc.State != "a=1,b=2" || tc.Flags != TraceFlagSampled {
+		t.Errorf("Expected state and flags: a=1,b=2 01, got: %+v", tc)
+	}
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+
+	received := []string{}
+	receivedStates := []string{}
+
+	ms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request,
+	) {
+		if r.RequestURI == "/stats.json" {
+			_, _ = w.Write([]byte(statsTestData))
+
+			return
+		}
+
+		mu.Lock()
+		received = append(received, r.Header.Get(TraceParentHeader))
+		receivedStates = append(receivedStates,
+			r.Header.Get(TraceStateHeader))
+		n := len(received)
+		mu.Unlock()
+
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(stateTestData))
+	}))
+
+	defer ms.Close()
+
+	requests := func() ([]string, []string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string{}, received...),
+			append([]string{}, receivedStates...)
+	}
+
+	obs := &testObserver{}
+	cfg := NewConfig(ms.URL)
+	cfg.RequestObserver = obs
+	cfg.RetryPolicy = &DefaultRetryPolicy{}
+	cfg.Retries = 1
+
+	sc, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal("Unable to create snowth client", err)
+	}
+
+	u, err := url.Parse(ms.URL)
+	if err != nil {
+		t.Fatal("Invalid test URL")
+	}
+
+	node := &SnowthNode{url: u}
+	parent := TraceContext{
+		TraceID: "0af7651916cd43dd8448eb211c80319c",
+		SpanID:  "b7ad6b7169203331",
+		Flags:   TraceFlagSampled,
+		State:   "vendor=1",
+	}
+
+	ctx := ContextWithTraceContext(context.Background(), parent)
+
+	if _, err := sc.GetNodeStateContext(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+
+	parents, states := requests()
+
+	if len(parents) != 2 {
+		t.Fatalf("Expected requests: 2, got: %v", len(parents))
+	}
+
+	spans := map[string]bool{}
+
+	for i, p := range parents {
+		tc, err := ParseTraceParent(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tc.TraceID != parent.TraceID || tc.Flags != parent.Flags {
+			t.Errorf("Expected trace: %v, got: %v", parent.TraceID, p)
+		}
+
+		if tc.SpanID == parent.SpanID {
+			t.Error("Expected child span ID")
+		}
+
+		if states[i] != "vendor=1" {
+			t.Errorf("Expected tracestate: vendor=1, got: %v", states[i])
+		}
+
+		if obs.ends[i].SpanID != tc.SpanID {
+			t.Errorf("Expected event span ID: %v, got: %v", tc.SpanID,
+				obs.ends[i].SpanID)
+		}
+
+		if obs.ends[i].TraceID != parent.TraceID {
+			t.Errorf("Expected event trace ID: %v, got: %v",
+				parent.TraceID, obs.ends[i].TraceID)
+		}
+
+		spans[tc.SpanID] = true
+	}
+
+	if len(spans) != 2 {
+		t.Errorf("Expected a span ID per attempt, got: %v", parents)
+	}
+
+	sc.SetTraceContextFunc(func(ctx context.Context) (TraceContext, bool) {
+		return TraceContext{
+			TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:  "00f067aa0ba902b7",
+		}, true
+	})
+
+	if _, err := sc.GetNodeStateContext(context.Background(),
+		node); err != nil {
+		t.Fatal(err)
+	}
+
+	parents, _ = requests()
+
+	if !strings.HasPrefix(parents[2],
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Errorf("Expected trace from trace context func, got: %v",
+			parents[2])
+	}
+
+	sc.SetTraceContextFunc(nil)
+
+	if _, err := sc.GetNodeStateContext(context.Background(),
+		node); err != nil {
+		t.Fatal(err)
+	}
+
+	parents, states = requests()
+
+	tc, err := ParseTraceParent(parents[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tc.TraceID == parent.TraceID || states[3] != "" {
+		t.Errorf("Expected new trace, got: %v %v", parents[3], states[3])
+	}
+
+	if obs.ends[3].TraceID != tc.TraceID {
+		t.Errorf("Expected generated trace ID in events: %v, got: %v",
+			tc.TraceID, obs.ends[3].TraceID)
+	}
+}
+
+func TestTraceHeaders(t *testing.T) {
+	t.Parallel()
+
+	tc := TraceContext{
+		TraceID: "0af7651916cd43dd8448eb211c80319c",
+		SpanID:  "b7ad6b7169203331",
+	}
+
+	h := traceHeaders(nil, tc, "00f067aa0ba902b7")
+
+	exp := "00-0af7651916cd43dd8448eb211c80319c-00f067aa0ba902b7-00"
+	if res := h.Get(TraceParentHeader); res != exp {
+		t.Errorf("Expected traceparent: %v, got: %v", exp, res)
+	}
+
+	orig := http.Header{}
+	orig.Set(TraceParentHeader, "custom")
+
+	if res := traceHeaders(orig, tc, "1").Get(TraceParentHeader); res !=
+		"custom" {
+		t.Errorf("Expected existing traceparent: custom, got: %v", res)
+	}
+
+	if id := newTraceID(8); !validTraceID(id, 16) {
+		t.Errorf("Expected valid span ID, got: %v", id)
+	}
+}